@@ -1,6 +1,7 @@
 package adapters
 
 import (
+	"context"
 	"recipe-stats/models"
 )
 
@@ -16,3 +17,16 @@ type AdapterMember interface {
 	ToRecipe() []models.Recipe
 	ToDelivery() []models.Delivery
 }
+
+// RecordsAdapter is implemented by the adapters that decode an input file
+// record by record into GeneralRecipe, so the loaders work with any of them.
+type RecordsAdapter interface {
+	SetParsePolicy(policy ParsePolicy)
+	ParsePolicy() ParsePolicy
+	SetWorkers(workers int)
+	Workers() int
+	UnmarshalRecords(filePath string, reject func(*RecordError)) (*[]GeneralRecipe, error)
+	UnmarshalRecordsContext(ctx context.Context, filePath string, reject func(*RecordError), progress func(Progress)) (*[]GeneralRecipe, error)
+	DecodeRecords(ctx context.Context, file []byte, reject func(*RecordError), progress func(Progress)) (*[]GeneralRecipe, error)
+	Validate(filePath string, report func(*RecordError)) (int, error)
+}
@@ -0,0 +1,348 @@
+package adapters
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// DeliveryError describes why a delivery string could not be parsed. Class is
+// one of the ErrorClass constants.
+type DeliveryError struct {
+	Class   string
+	Message string
+}
+
+func (e *DeliveryError) Error() string {
+	return e.Message
+}
+
+// Leniency rules that can be combined into a ParsePolicy, each one accepting a
+// quirk of some upstream partner on top of the "Wednesday 8AM - 2PM" format.
+const (
+	// RuleLowercaseMeridiem accepts am/pm in any case, such as "8am".
+	RuleLowercaseMeridiem = "lowercase_meridiem"
+	// RuleMeridiemSpace accepts a space before am/pm, such as "12 PM".
+	RuleMeridiemSpace = "meridiem_space"
+	// RuleNoonMidnight accepts "noon" and "midnight" in place of 12PM and 12AM.
+	RuleNoonMidnight = "noon_midnight"
+	// RuleDashVariants accepts en-dashes and em-dashes instead of hyphens, with
+	// or without spaces around them, such as "8AM–2PM".
+	RuleDashVariants = "dash_variants"
+	// RuleWeekdayVariants accepts weekdays in any case and their usual
+	// abbreviations, such as "wed" or "Thurs".
+	RuleWeekdayVariants = "weekday_variants"
+	// RuleExtraWhitespace accepts repeated, leading and trailing spaces.
+	RuleExtraWhitespace = "extra_whitespace"
+)
+
+// Names of the built-in policies.
+const (
+	PolicyStrict  = "strict"
+	PolicyLenient = "lenient"
+)
+
+// leniencyRules are all the rules available, the ones a lenient policy uses.
+var leniencyRules = []string{
+	RuleLowercaseMeridiem,
+	RuleMeridiemSpace,
+	RuleNoonMidnight,
+	RuleDashVariants,
+	RuleWeekdayVariants,
+	RuleExtraWhitespace,
+}
+
+// fullWeekdays are the only weekday names accepted by the strict policy.
+var fullWeekdays = map[string]bool{
+	"Monday":    true,
+	"Tuesday":   true,
+	"Wednesday": true,
+	"Thursday":  true,
+	"Friday":    true,
+	"Saturday":  true,
+	"Sunday":    true,
+}
+
+// weekdayVariants maps the lower case weekdays and their abbreviations to the
+// full weekday names, used by RuleWeekdayVariants.
+var weekdayVariants = map[string]string{
+	"monday": "Monday", "mon": "Monday",
+	"tuesday": "Tuesday", "tue": "Tuesday", "tues": "Tuesday",
+	"wednesday": "Wednesday", "wed": "Wednesday",
+	"thursday": "Thursday", "thu": "Thursday", "thur": "Thursday", "thurs": "Thursday",
+	"friday": "Friday", "fri": "Friday",
+	"saturday": "Saturday", "sat": "Saturday",
+	"sunday": "Sunday", "sun": "Sunday",
+}
+
+// ParsePolicy decides which variations of the delivery format are accepted.
+// The zero value is the strict policy, that only accepts the exact
+// "Wednesday 8AM - 2PM" format.
+type ParsePolicy struct {
+	name  string
+	rules map[string]bool
+}
+
+// StrictParsePolicy only accepts the exact "Wednesday 8AM - 2PM" format.
+func StrictParsePolicy() ParsePolicy {
+	return ParsePolicy{name: PolicyStrict}
+}
+
+// LenientParsePolicy accepts every variation known by the leniency rules.
+func LenientParsePolicy() ParsePolicy {
+	policy, _ := newRulesPolicy(PolicyLenient, leniencyRules)
+
+	return policy
+}
+
+// NewParsePolicy builds a policy from its configuration, which is either the
+// name of a built-in policy (strict or lenient) or a list of leniency rules.
+// An empty configuration means the strict policy.
+func NewParsePolicy(names []string) (ParsePolicy, error) {
+	if len(names) == 0 {
+		return StrictParsePolicy(), nil
+	}
+
+	if len(names) == 1 {
+		switch names[0] {
+		case PolicyStrict:
+			return StrictParsePolicy(), nil
+		case PolicyLenient:
+			return LenientParsePolicy(), nil
+		}
+	}
+
+	return newRulesPolicy("", names)
+}
+
+func newRulesPolicy(name string, names []string) (ParsePolicy, error) {
+	policy := ParsePolicy{name: name, rules: map[string]bool{}}
+
+	for _, rule := range names {
+		known := false
+		for _, leniencyRule := range leniencyRules {
+			known = known || rule == leniencyRule
+		}
+		if !known {
+			return ParsePolicy{}, fmt.Errorf("unknown parsing rule %q, use %s, %s or any of: %s", rule, PolicyStrict, PolicyLenient, strings.Join(leniencyRules, ", "))
+		}
+		policy.rules[rule] = true
+	}
+
+	return policy, nil
+}
+
+// Rules returns the leniency rules of the policy, sorted by name.
+func (p ParsePolicy) Rules() []string {
+	rules := []string{}
+	for rule := range p.rules {
+		rules = append(rules, rule)
+	}
+	sort.Strings(rules)
+
+	return rules
+}
+
+// String describes the policy, such as "strict" or "rules: dash_variants".
+func (p ParsePolicy) String() string {
+	if p.name != "" {
+		return p.name
+	}
+	if len(p.rules) == 0 {
+		return PolicyStrict
+	}
+
+	return "rules: " + strings.Join(p.Rules(), ", ")
+}
+
+// ParseDelivery parses a delivery string such as "Wednesday 8AM - 2PM" into its
+// full weekday name and 24h from and to hours, accepting the variations
+// allowed by the policy. The string is walked through just once and never
+// indexed without checking its length, since Regex demonstrated to be very
+// slow.
+func (p ParsePolicy) ParseDelivery(value string) (string, int, int, error) {
+	normalized := value
+	if p.rules[RuleExtraWhitespace] && hasExtraWhitespace(value) {
+		normalized = strings.Join(strings.Fields(value), " ")
+	}
+
+	separator := strings.IndexByte(normalized, ' ')
+	if separator < 0 {
+		return "", 0, 0, invalidDelivery(value)
+	}
+
+	weekday, found := p.weekday(normalized[:separator])
+	if !found {
+		return "", 0, 0, &DeliveryError{Class: ErrorClassUnknownWeekday, Message: fmt.Sprintf("unknown weekday in delivery %q", value)}
+	}
+
+	fromValue, toValue, found := p.window(normalized[separator+1:])
+	if !found {
+		return "", 0, 0, invalidDelivery(value)
+	}
+
+	from, ok := p.hour(fromValue)
+	if !ok {
+		return "", 0, 0, invalidTime(value)
+	}
+	to, ok := p.hour(toValue)
+	if !ok {
+		return "", 0, 0, invalidTime(value)
+	}
+
+	return weekday, from, to, nil
+}
+
+// hasExtraWhitespace tells if value has anything but single spaces between its
+// words, so the common case doesn't pay for normalizing it.
+func hasExtraWhitespace(value string) bool {
+	for i := 0; i < len(value); i++ {
+		switch value[i] {
+		case ' ':
+			if i == 0 || i == len(value)-1 || value[i+1] == ' ' {
+				return true
+			}
+		case '\t', '\n', '\r':
+			return true
+		}
+	}
+
+	return false
+}
+
+func (p ParsePolicy) weekday(value string) (string, bool) {
+	if fullWeekdays[value] {
+		return value, true
+	}
+	if p.rules[RuleWeekdayVariants] {
+		weekday, found := weekdayVariants[strings.ToLower(value)]
+		return weekday, found
+	}
+
+	return "", false
+}
+
+// window splits the time window into its from and to times.
+func (p ParsePolicy) window(value string) (string, string, bool) {
+	if !p.rules[RuleDashVariants] {
+		separator := strings.Index(value, " - ")
+		if separator < 0 {
+			return "", "", false
+		}
+		return value[:separator], value[separator+3:], true
+	}
+
+	for i, char := range value {
+		if char == '-' || char == '–' || char == '—' {
+			return strings.TrimSpace(value[:i]), strings.TrimSpace(value[i+utf8.RuneLen(char):]), true
+		}
+	}
+
+	return "", "", false
+}
+
+// ParseHour parses a single 12h time such as "8AM" into a 24h hour, accepting
+// the variations allowed by the policy.
+func (p ParsePolicy) ParseHour(value string) (int, error) {
+	hour, ok := p.hour(value)
+	if !ok {
+		return 0, &DeliveryError{Class: ErrorClassInvalidTime, Message: fmt.Sprintf("invalid time %q, expected something like 8AM or 12PM", value)}
+	}
+
+	return hour, nil
+}
+
+// hour parses a 12h time such as "8AM" into a 24h hour.
+func (p ParsePolicy) hour(value string) (int, bool) {
+	if p.rules[RuleNoonMidnight] {
+		if strings.EqualFold(value, "noon") {
+			return 12, true
+		}
+		if strings.EqualFold(value, "midnight") {
+			return 0, true
+		}
+	}
+
+	if len(value) < 3 {
+		return 0, false
+	}
+
+	indicator := value[len(value)-2:]
+	if p.rules[RuleLowercaseMeridiem] {
+		indicator = strings.ToUpper(indicator)
+	}
+
+	digits := value[:len(value)-2]
+	if p.rules[RuleMeridiemSpace] && len(digits) > 1 && digits[len(digits)-1] == ' ' {
+		digits = digits[:len(digits)-1]
+	}
+	if len(digits) == 0 || len(digits) > 2 {
+		return 0, false
+	}
+
+	number := 0
+	for i := 0; i < len(digits); i++ {
+		if digits[i] < '0' || digits[i] > '9' {
+			return 0, false
+		}
+		number = number*10 + int(digits[i]-'0')
+	}
+
+	hour, err := to24h(number, indicator)
+
+	return hour, err == nil
+}
+
+// ParseDeliveryStrict parses a delivery string with the strict policy and also
+// rejects windows that end before they start, reporting what is wrong with the
+// string as a *DeliveryError.
+func ParseDeliveryStrict(value string) (string, int, int, error) {
+	return parseDeliveryChecked(value, StrictParsePolicy())
+}
+
+// parseDeliveryChecked parses a delivery string with policy and rejects
+// windows that end before they start.
+func parseDeliveryChecked(value string, policy ParsePolicy) (string, int, int, error) {
+	weekday, from, to, err := policy.ParseDelivery(value)
+	if err != nil {
+		return "", 0, 0, err
+	}
+
+	if to < from {
+		return "", 0, 0, &DeliveryError{Class: ErrorClassInvertedWindow, Message: fmt.Sprintf("delivery window of %q ends before it starts", value)}
+	}
+
+	return weekday, from, to, nil
+}
+
+// to24h transforms a 12h hour number and its AM/PM indicator into a 24h hour
+// number, so the result can always be used as a time range index.
+func to24h(number int, indicator string) (int, error) {
+	if number < 1 || number > 12 {
+		return 0, fmt.Errorf("hour %d out of range", number)
+	}
+
+	switch indicator {
+	case "AM":
+		if number == 12 { // 12 AM
+			return 0, nil
+		}
+		return number, nil
+	case "PM":
+		if number == 12 { // it is noon
+			return 12, nil
+		}
+		return number + 12, nil
+	}
+
+	return 0, fmt.Errorf("unknown indicator %q", indicator)
+}
+
+func invalidDelivery(value string) *DeliveryError {
+	return &DeliveryError{Class: ErrorClassInvalidDelivery, Message: fmt.Sprintf("delivery %q is not in the \"Weekday 8AM - 2PM\" format", value)}
+}
+
+func invalidTime(value string) *DeliveryError {
+	return &DeliveryError{Class: ErrorClassInvalidTime, Message: fmt.Sprintf("invalid time in delivery %q, expected something like 8AM or 12PM", value)}
+}
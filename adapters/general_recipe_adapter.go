@@ -1,9 +1,13 @@
 package adapters
 
 import (
+	"context"
+	stdjson "encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"io/ioutil"
 	"recipe-stats/models"
-	"strconv"
 
 	jsoniter "github.com/json-iterator/go"
 )
@@ -11,6 +15,8 @@ import (
 // GeneralRecipeAdapter is the base structure for this adapter
 type GeneralRecipeAdapter struct {
 	rawRecipes *[]GeneralRecipe
+	policy     ParsePolicy
+	workers    int
 }
 
 // GeneralRecipe is the struct that maps to the input JSON
@@ -22,8 +28,13 @@ type GeneralRecipe struct {
 
 // GeneralDelivery is the struct that maps to the delivery data from the input JSON
 type GeneralDelivery struct {
-	From int
-	To   int
+	Weekday string
+	From    int
+	To      int
+
+	// value is the delivery string, parsed into the fields above by the
+	// adapter according to its ParsePolicy
+	value string
 }
 
 // jsoniter is an optimized library to encode/decode JSON
@@ -34,6 +45,32 @@ func NewGeneralRecipeAdapter() GeneralRecipeAdapter {
 	return GeneralRecipeAdapter{}
 }
 
+// SetParsePolicy changes the policy used to parse the delivery strings, which
+// is strict by default
+func (a *GeneralRecipeAdapter) SetParsePolicy(policy ParsePolicy) {
+	a.policy = policy
+}
+
+// ParsePolicy returns the policy used to parse the delivery strings
+func (a *GeneralRecipeAdapter) ParsePolicy() ParsePolicy {
+	return a.policy
+}
+
+// SetWorkers changes how many workers decode the records at the same time,
+// which is 1 by default. The results are the same with any number of them.
+func (a *GeneralRecipeAdapter) SetWorkers(workers int) {
+	a.workers = workers
+}
+
+// Workers returns how many workers decode the records at the same time
+func (a *GeneralRecipeAdapter) Workers() int {
+	if a.workers < 1 {
+		return 1
+	}
+
+	return a.workers
+}
+
 // unwrap does the Unmarshal of the file data into a collection of GeneralRecipe
 func (a *GeneralRecipeAdapter) unwrap(file []byte) (*[]GeneralRecipe, error) {
 	rawRecipes := new([]GeneralRecipe)
@@ -43,6 +80,12 @@ func (a *GeneralRecipeAdapter) unwrap(file []byte) (*[]GeneralRecipe, error) {
 		return nil, err
 	}
 
+	for i := range *rawRecipes {
+		if err := (*rawRecipes)[i].Delivery.parse(a.policy); err != nil {
+			return nil, err
+		}
+	}
+
 	return rawRecipes, nil
 }
 
@@ -64,6 +107,87 @@ func (a *GeneralRecipeAdapter) Unmarshal(filePath string) (*[]GeneralRecipe, err
 	return unwrappedRecipes, nil
 }
 
+// UnmarshalRecords works like Unmarshal, but decodes each record on its own so
+// a record that can't be parsed doesn't fail the whole file. Those records are
+// handed to reject instead, and left out of the result.
+func (a *GeneralRecipeAdapter) UnmarshalRecords(filePath string, reject func(*RecordError)) (*[]GeneralRecipe, error) {
+	return a.UnmarshalRecordsContext(context.Background(), filePath, reject, nil)
+}
+
+// UnmarshalRecordsContext works like UnmarshalRecords, reporting how far it
+// went to progress, which may be nil, and giving up with the error of ctx as
+// soon as it is canceled.
+func (a *GeneralRecipeAdapter) UnmarshalRecordsContext(ctx context.Context, filePath string, reject func(*RecordError), progress func(Progress)) (*[]GeneralRecipe, error) {
+	file, err := readFile(ctx, filePath, progress)
+	if err != nil {
+		return nil, err
+	}
+
+	return a.DecodeRecords(ctx, file, reject, progress)
+}
+
+// DecodeRecords works like UnmarshalRecordsContext over the content of a file
+// already in memory.
+func (a *GeneralRecipeAdapter) DecodeRecords(ctx context.Context, file []byte, reject func(*RecordError), progress func(Progress)) (*[]GeneralRecipe, error) {
+	rawRecipes, err := decodeRecords(ctx, file, a.workers, reject, progress, func() (recordDecoder, func()) {
+		// a single iterator is reused for every record of a worker, since
+		// borrowing one per record doubles the decoding time
+		iter := json.BorrowIterator(nil)
+
+		return func(index int, offset int64, raw []byte) (GeneralRecipe, *RecordError) {
+			rawRecipe := GeneralRecipe{}
+			iter.ResetBytes(raw)
+			iter.ReadVal(&rawRecipe)
+			if iter.Error != nil && iter.Error != io.EOF {
+				recordError := newRecordError(index, offset, raw, iter.Error)
+				iter.Error = nil
+				return rawRecipe, recordError
+			}
+			if err := rawRecipe.Delivery.parse(a.policy); err != nil {
+				return rawRecipe, newRecordError(index, offset, raw, err)
+			}
+
+			return rawRecipe, nil
+		}, func() { json.ReturnIterator(iter) }
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	a.rawRecipes = rawRecipes
+
+	return rawRecipes, nil
+}
+
+// newRecordError builds the RecordError of a rejected record, keeping the
+// class of the delivery problem if that was the reason. Since jsoniter flattens
+// the errors into strings, a record that failed decoding is decoded again with
+// the standard library, which keeps them as they are and has friendlier
+// messages. It only happens for rejected records, so it is cheap.
+func newRecordError(index int, offset int64, raw []byte, err error) *RecordError {
+	var deliveryError *DeliveryError
+	if !errors.As(err, &deliveryError) {
+		if stdErr := stdjson.Unmarshal(raw, &GeneralRecipe{}); stdErr != nil {
+			err = stdErr
+		}
+	}
+
+	recordError := &RecordError{
+		Index:  index,
+		Offset: offset,
+		Class:  ErrorClassMalformedRecord,
+		Reason: err.Error(),
+		Raw:    raw,
+	}
+
+	if errors.As(err, &deliveryError) {
+		recordError.Class = deliveryError.Class
+		recordError.Reason = deliveryError.Message
+	}
+
+	return recordError
+}
+
 // ToRecipe provides the transforming logic from GeneralRecipe to models.Recipe
 func (r *GeneralRecipe) ToRecipe() models.Recipe {
 	return models.Recipe{
@@ -80,27 +204,36 @@ func (r *GeneralRecipe) ToDelivery() models.Delivery {
 	}
 }
 
-// UnmarshalJSON is the custom Unmarshaler that runs whe decoding GeneralDelivery
+// UnmarshalJSON is the custom Unmarshaler that runs whe decoding GeneralDelivery.
+// It only keeps the delivery string, the adapter parses it afterwards with its
+// policy.
 func (d *GeneralDelivery) UnmarshalJSON(data []byte) error {
 	var v interface{}
 	if err := json.Unmarshal(data, &v); err != nil {
 		return err
 	}
 
-	d.From, d.To = d.GetDeliveryTimes(v.(string))
+	value, ok := v.(string)
+	if !ok {
+		return &DeliveryError{Class: ErrorClassInvalidDelivery, Message: fmt.Sprintf("delivery %s is not a string", string(data))}
+	}
+
+	d.value = value
 
 	return nil
 }
 
-// weekdays is a fixed collection of weekdays and their characters lengths
-var weekdays = map[string]int{
-	"Sa": 8,
-	"Su": 6,
-	"Mo": 6,
-	"Tu": 7,
-	"We": 9,
-	"Th": 8,
-	"Fr": 6,
+// parse fills in the weekday and times of the delivery from its string,
+// according to policy.
+func (d *GeneralDelivery) parse(policy ParsePolicy) error {
+	weekday, from, to, err := policy.ParseDelivery(d.value)
+	if err != nil {
+		return err
+	}
+
+	d.Weekday, d.From, d.To = weekday, from, to
+
+	return nil
 }
 
 // GetDeliveryTimes is a transformation method that gets a full delivery string
@@ -108,53 +241,10 @@ var weekdays = map[string]int{
 // hour numbers that represents the respectives from and to times. In the
 // example given, the expected result would be 8 and 14.
 // For "12AM" it transforms to 0, for "12PM" it transforms to 12.
-// Byte manipulation was used in order to achieve performance since Regex
-// demonstrated to be very slow.
-func (d *GeneralDelivery) GetDeliveryTimes(value string) (int, int) {
-	byteR := []byte(value)
-
-	padding := weekdays[string(byteR[0:2])] + 1 // + 1 to eliminate trailing space
-	timePortion := byteR[padding:]
-
-	// start time
-	var startNumber int
-	var startIndicator string
-	// the second position is a number, the hour has 2 digits
-	if _, err := strconv.Atoi(string(timePortion[1])); err == nil {
-		startNumber, _ = strconv.Atoi(string(timePortion[:2]))
-		startIndicator = string(timePortion[3:5])
-	} else { // the hour has 1 digit
-		startNumber, _ = strconv.Atoi(string(timePortion[:1]))
-		startIndicator = string(timePortion[1:3])
-	}
-
-	if startIndicator == "PM" { // it is noon
-		startNumber += 12
-	} else { // it is morning
-		if startNumber == 12 { // 12 AM
-			startNumber = 0
-		}
-	}
-
-	//end time
-	var endNumber int
-	var endIndicator string
-	// the first position is not a number, the hour has 1 digit
-	if _, err := strconv.Atoi(string(timePortion[len(timePortion)-4:][0])); err != nil {
-		endNumber, _ = strconv.Atoi(string(timePortion[len(timePortion)-3 : len(timePortion)-2]))
-		endIndicator = string(timePortion[len(timePortion)-2:])
-	} else { // the hour has 2 digit
-		endNumber, _ = strconv.Atoi(string(timePortion[len(timePortion)-4 : len(timePortion)-2]))
-		endIndicator = string(timePortion[len(timePortion)-2:])
-	}
-
-	if endIndicator == "PM" { // it is noon
-		endNumber += 12
-	} else { // it is morning
-		if endNumber == 12 { // 12 AM
-			endNumber = 0
-		}
-	}
+// It uses the strict policy, so an unexpected string results in a
+// *DeliveryError.
+func (d *GeneralDelivery) GetDeliveryTimes(value string) (int, int, error) {
+	_, from, to, err := StrictParsePolicy().ParseDelivery(value)
 
-	return startNumber, endNumber
+	return from, to, err
 }
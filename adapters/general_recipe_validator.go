@@ -0,0 +1,115 @@
+package adapters
+
+import (
+	stdjson "encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+)
+
+// Error classes used to group the problems found in records.
+const (
+	ErrorClassMalformedRecord = "malformed_record"
+	ErrorClassEmptyRecipe     = "empty_recipe"
+	ErrorClassInvalidPostcode = "invalid_postcode"
+	ErrorClassInvalidDelivery = "invalid_delivery"
+	ErrorClassUnknownWeekday  = "unknown_weekday"
+	ErrorClassInvalidTime     = "invalid_time"
+	ErrorClassInvertedWindow  = "inverted_window"
+)
+
+// RecordError describes a problem found in a single record of the input file.
+// Index is the position of the record within the records array and Offset is
+// where it starts, counted in bytes from the start of the file.
+type RecordError struct {
+	Index  int
+	Offset int64
+	Class  string
+	Reason string
+	Raw    []byte
+}
+
+func (e *RecordError) Error() string {
+	return fmt.Sprintf("record %d at byte %d: %s", e.Index, e.Offset, e.Reason)
+}
+
+// strictGeneralRecipe maps the input JSON without any transformation, so the
+// values can be checked as they were informed.
+type strictGeneralRecipe struct {
+	Recipe   *string `json:"recipe"`
+	Postcode *string `json:"postcode"`
+	Delivery *string `json:"delivery"`
+}
+
+// Validate reads the file at filePath and runs every record through the strict
+// checks, parsing the deliveries with the adapter's policy, and calls report
+// for each problem found. A record may have more than one problem. It returns
+// how many records were checked, or an error if the file can't be read or is
+// not an array of records.
+func (a *GeneralRecipeAdapter) Validate(filePath string, report func(*RecordError)) (int, error) {
+	file, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return 0, err
+	}
+
+	total := 0
+	err = scanRecords(file, func(index int, offset int64, raw []byte) bool {
+		total++
+		for _, recordError := range ValidateRecord(raw, a.policy) {
+			recordError.Index = index
+			recordError.Offset = offset
+			report(recordError)
+		}
+		return true
+	})
+
+	return total, err
+}
+
+// ValidateRecord runs the strict checks over the raw JSON of a single record,
+// parsing its delivery with policy, and returns every problem found. Index and
+// Offset are left for the caller to fill in.
+func ValidateRecord(raw []byte, policy ParsePolicy) []*RecordError {
+	problem := func(class string, format string, args ...interface{}) *RecordError {
+		return &RecordError{Class: class, Reason: fmt.Sprintf(format, args...), Raw: raw}
+	}
+
+	// the standard library is used here for its friendlier error messages
+	record := strictGeneralRecipe{}
+	if err := stdjson.Unmarshal(raw, &record); err != nil {
+		return []*RecordError{problem(ErrorClassMalformedRecord, "malformed record: %s", err.Error())}
+	}
+
+	problems := []*RecordError{}
+
+	if record.Recipe == nil || strings.TrimSpace(*record.Recipe) == "" {
+		problems = append(problems, problem(ErrorClassEmptyRecipe, "empty recipe name"))
+	}
+
+	if record.Postcode == nil {
+		problems = append(problems, problem(ErrorClassInvalidPostcode, "missing postcode"))
+	} else if !isValidPostcode(*record.Postcode) {
+		problems = append(problems, problem(ErrorClassInvalidPostcode, "postcode %q is not 5 digits", *record.Postcode))
+	}
+
+	if record.Delivery == nil {
+		problems = append(problems, problem(ErrorClassInvalidDelivery, "missing delivery"))
+	} else if _, _, _, err := parseDeliveryChecked(*record.Delivery, policy); err != nil {
+		problems = append(problems, problem(err.(*DeliveryError).Class, "%s", err.Error()))
+	}
+
+	return problems
+}
+
+func isValidPostcode(postcode string) bool {
+	if len(postcode) != 5 {
+		return false
+	}
+	for _, digit := range postcode {
+		if digit < '0' || digit > '9' {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,373 @@
+package adapters
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"strings"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+// MappedRecipeAdapter is a generic adapter for input JSON files whose fields
+// don't match GeneralRecipe, such as {"dish": ..., "zip": ..., "slot": {...}}.
+// Its Schema tells where each field is.
+type MappedRecipeAdapter struct {
+	rawRecipes *[]GeneralRecipe
+	schema     Schema
+	paths      mappedPaths
+	policy     ParsePolicy
+	workers    int
+}
+
+// mappedPaths are the paths of the schema split into their keys
+type mappedPaths struct {
+	recipe   []string
+	postcode []string
+	delivery []string
+	weekday  []string
+	from     []string
+	to       []string
+}
+
+// mappedValues are the values found at the paths of a record, nil if missing
+type mappedValues struct {
+	recipe   interface{}
+	postcode interface{}
+	delivery interface{}
+	weekday  interface{}
+	from     interface{}
+	to       interface{}
+}
+
+// NewMappedRecipeAdapter provides a usable instance of MappedRecipeAdapter for
+// files in schema
+func NewMappedRecipeAdapter(schema Schema) (MappedRecipeAdapter, error) {
+	if err := schema.Validate(); err != nil {
+		return MappedRecipeAdapter{}, fmt.Errorf("invalid schema: %w", err)
+	}
+
+	paths := mappedPaths{
+		recipe:   strings.Split(schema.Recipe, "."),
+		postcode: strings.Split(schema.Postcode, "."),
+	}
+	if schema.Slot != nil {
+		paths.weekday = strings.Split(schema.Slot.Weekday, ".")
+		paths.from = strings.Split(schema.Slot.From, ".")
+		paths.to = strings.Split(schema.Slot.To, ".")
+	} else {
+		paths.delivery = strings.Split(schema.Delivery, ".")
+	}
+
+	return MappedRecipeAdapter{schema: schema, paths: paths}, nil
+}
+
+// SetParsePolicy changes the policy used to parse the deliveries, which is
+// strict by default
+func (a *MappedRecipeAdapter) SetParsePolicy(policy ParsePolicy) {
+	a.policy = policy
+}
+
+// ParsePolicy returns the policy used to parse the deliveries
+func (a *MappedRecipeAdapter) ParsePolicy() ParsePolicy {
+	return a.policy
+}
+
+// SetWorkers changes how many workers map the records at the same time, which
+// is 1 by default. The results are the same with any number of them.
+func (a *MappedRecipeAdapter) SetWorkers(workers int) {
+	a.workers = workers
+}
+
+// Workers returns how many workers map the records at the same time
+func (a *MappedRecipeAdapter) Workers() int {
+	if a.workers < 1 {
+		return 1
+	}
+
+	return a.workers
+}
+
+// UnmarshalRecords reads the file at filePath and maps each record into a
+// GeneralRecipe. The records that can't be mapped are handed to reject
+// instead, and left out of the result.
+func (a *MappedRecipeAdapter) UnmarshalRecords(filePath string, reject func(*RecordError)) (*[]GeneralRecipe, error) {
+	return a.UnmarshalRecordsContext(context.Background(), filePath, reject, nil)
+}
+
+// UnmarshalRecordsContext works like UnmarshalRecords, reporting how far it
+// went to progress, which may be nil, and giving up with the error of ctx as
+// soon as it is canceled.
+func (a *MappedRecipeAdapter) UnmarshalRecordsContext(ctx context.Context, filePath string, reject func(*RecordError), progress func(Progress)) (*[]GeneralRecipe, error) {
+	file, err := readFile(ctx, filePath, progress)
+	if err != nil {
+		return nil, err
+	}
+
+	return a.DecodeRecords(ctx, file, reject, progress)
+}
+
+// DecodeRecords works like UnmarshalRecordsContext over the content of a file
+// already in memory.
+func (a *MappedRecipeAdapter) DecodeRecords(ctx context.Context, file []byte, reject func(*RecordError), progress func(Progress)) (*[]GeneralRecipe, error) {
+	rawRecipes, err := decodeRecords(ctx, file, a.workers, reject, progress, func() (recordDecoder, func()) {
+		iter := json.BorrowIterator(nil)
+
+		return func(index int, offset int64, raw []byte) (GeneralRecipe, *RecordError) {
+			values, err := a.values(iter, raw)
+			if err == nil {
+				var rawRecipe GeneralRecipe
+				rawRecipe, err = a.toGeneralRecipe(values)
+				if err == nil {
+					return rawRecipe, nil
+				}
+			}
+
+			return GeneralRecipe{}, mappedRecordError(index, offset, raw, err)
+		}, func() { json.ReturnIterator(iter) }
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	a.rawRecipes = rawRecipes
+
+	return rawRecipes, nil
+}
+
+// Validate reads the file at filePath and runs every record through the same
+// strict checks of GeneralRecipeAdapter.Validate, calling report for each
+// problem found. It returns how many records were checked, or an error if the
+// file can't be read or is not an array of records.
+func (a *MappedRecipeAdapter) Validate(filePath string, report func(*RecordError)) (int, error) {
+	file, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return 0, err
+	}
+
+	iter := json.BorrowIterator(nil)
+	defer json.ReturnIterator(iter)
+
+	total := 0
+	err = scanRecords(file, func(index int, offset int64, raw []byte) bool {
+		total++
+		for _, recordError := range a.validateRecord(iter, raw) {
+			recordError.Index = index
+			recordError.Offset = offset
+			report(recordError)
+		}
+		return true
+	})
+
+	return total, err
+}
+
+func (a *MappedRecipeAdapter) validateRecord(iter *jsoniter.Iterator, raw []byte) []*RecordError {
+	problem := func(class string, format string, args ...interface{}) *RecordError {
+		return &RecordError{Class: class, Reason: fmt.Sprintf(format, args...), Raw: raw}
+	}
+
+	values, err := a.values(iter, raw)
+	if err != nil {
+		return []*RecordError{problem(ErrorClassMalformedRecord, "malformed record: %s", err.Error())}
+	}
+
+	problems := []*RecordError{}
+
+	if recipe, ok := values.recipe.(string); !ok || strings.TrimSpace(recipe) == "" {
+		problems = append(problems, problem(ErrorClassEmptyRecipe, "empty recipe name at %q", a.schema.Recipe))
+	}
+
+	if postcode, ok := postcodeText(values.postcode); !ok {
+		problems = append(problems, problem(ErrorClassInvalidPostcode, "missing postcode at %q", a.schema.Postcode))
+	} else if !isValidPostcode(postcode) {
+		problems = append(problems, problem(ErrorClassInvalidPostcode, "postcode %q is not 5 digits", postcode))
+	}
+
+	delivery, err := a.toGeneralDelivery(values)
+	if err == nil && delivery.To < delivery.From {
+		err = &DeliveryError{Class: ErrorClassInvertedWindow, Message: fmt.Sprintf("delivery window of %s %d - %d ends before it starts", delivery.Weekday, delivery.From, delivery.To)}
+	}
+	if err != nil {
+		problems = append(problems, mappedRecordError(0, 0, raw, err))
+	}
+
+	return problems
+}
+
+// values decodes raw and picks the values at the paths of the schema
+func (a *MappedRecipeAdapter) values(iter *jsoniter.Iterator, raw []byte) (mappedValues, error) {
+	var record interface{}
+	iter.ResetBytes(raw)
+	iter.ReadVal(&record)
+	if iter.Error != nil && iter.Error != io.EOF {
+		err := iter.Error
+		iter.Error = nil
+		return mappedValues{}, err
+	}
+	iter.Error = nil
+
+	if _, ok := record.(map[string]interface{}); !ok {
+		return mappedValues{}, fmt.Errorf("record is not an object")
+	}
+
+	return mappedValues{
+		recipe:   lookup(record, a.paths.recipe),
+		postcode: lookup(record, a.paths.postcode),
+		delivery: lookup(record, a.paths.delivery),
+		weekday:  lookup(record, a.paths.weekday),
+		from:     lookup(record, a.paths.from),
+		to:       lookup(record, a.paths.to),
+	}, nil
+}
+
+// lookup walks the nested objects of record through path, returning nil if
+// any of the keys is missing
+func lookup(record interface{}, path []string) interface{} {
+	if len(path) == 0 {
+		return nil
+	}
+
+	value := record
+	for _, key := range path {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = object[key]
+	}
+
+	return value
+}
+
+// toGeneralRecipe transforms the values of a record into a GeneralRecipe. As
+// in GeneralRecipeAdapter, missing recipes and postcodes are left empty, but
+// the delivery must be valid.
+func (a *MappedRecipeAdapter) toGeneralRecipe(values mappedValues) (GeneralRecipe, error) {
+	recipe, err := optionalString(values.recipe, a.schema.Recipe)
+	if err != nil {
+		return GeneralRecipe{}, err
+	}
+	postcode, err := optionalPostcode(values.postcode, a.schema.Postcode)
+	if err != nil {
+		return GeneralRecipe{}, err
+	}
+	delivery, err := a.toGeneralDelivery(values)
+	if err != nil {
+		return GeneralRecipe{}, err
+	}
+
+	return GeneralRecipe{Recipe: recipe, Postcode: postcode, Delivery: delivery}, nil
+}
+
+func optionalString(value interface{}, path string) (string, error) {
+	if value == nil {
+		return "", nil
+	}
+	text, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("the value at %q is not a string", path)
+	}
+
+	return text, nil
+}
+
+// optionalPostcode is optionalString for postcodes, which may also be numbers
+// such as 10120 in some sources
+func optionalPostcode(value interface{}, path string) (string, error) {
+	if value == nil {
+		return "", nil
+	}
+	postcode, ok := postcodeText(value)
+	if !ok {
+		return "", fmt.Errorf("the value at %q is not a string or a postcode number", path)
+	}
+
+	return postcode, nil
+}
+
+// postcodeText turns a postcode value into its text, formatting whole
+// numbers with the zeros a number loses, so 1234 becomes "01234"
+func postcodeText(value interface{}) (string, bool) {
+	switch postcode := value.(type) {
+	case string:
+		return postcode, true
+	case float64:
+		if postcode >= 0 && postcode <= 99999 && postcode == math.Trunc(postcode) {
+			return fmt.Sprintf("%05d", int(postcode)), true
+		}
+	}
+
+	return "", false
+}
+
+// toGeneralDelivery parses the delivery of a record, either from its string or
+// from its slot, with the adapter's policy
+func (a *MappedRecipeAdapter) toGeneralDelivery(values mappedValues) (GeneralDelivery, error) {
+	if a.schema.Slot == nil {
+		value, ok := values.delivery.(string)
+		if !ok {
+			return GeneralDelivery{}, &DeliveryError{Class: ErrorClassInvalidDelivery, Message: fmt.Sprintf("the delivery at %q is missing or is not a string", a.schema.Delivery)}
+		}
+
+		delivery := GeneralDelivery{value: value}
+		err := delivery.parse(a.policy)
+
+		return delivery, err
+	}
+
+	value, _ := values.weekday.(string)
+	weekday, found := a.policy.weekday(value)
+	if !found {
+		return GeneralDelivery{}, &DeliveryError{Class: ErrorClassUnknownWeekday, Message: fmt.Sprintf("unknown weekday %v at %q", values.weekday, a.schema.Slot.Weekday)}
+	}
+
+	from, err := a.slotHour(values.from, a.schema.Slot.From)
+	if err != nil {
+		return GeneralDelivery{}, err
+	}
+	to, err := a.slotHour(values.to, a.schema.Slot.To)
+	if err != nil {
+		return GeneralDelivery{}, err
+	}
+
+	return GeneralDelivery{Weekday: weekday, From: from, To: to}, nil
+}
+
+// slotHour parses a slot time, either a 12h string such as "8AM" or a 24h
+// hour number such as 14
+func (a *MappedRecipeAdapter) slotHour(value interface{}, path string) (int, error) {
+	switch time := value.(type) {
+	case string:
+		if hour, ok := a.policy.hour(time); ok {
+			return hour, nil
+		}
+	case float64:
+		if time >= 0 && time <= 23 && time == math.Trunc(time) {
+			return int(time), nil
+		}
+	}
+
+	return 0, &DeliveryError{Class: ErrorClassInvalidTime, Message: fmt.Sprintf("invalid time %v at %q, expected something like 8AM, 12PM or 14", value, path)}
+}
+
+// mappedRecordError builds the RecordError of a record that couldn't be
+// mapped, keeping the class of the delivery problem if that was the reason
+func mappedRecordError(index int, offset int64, raw []byte, err error) *RecordError {
+	recordError := &RecordError{
+		Index:  index,
+		Offset: offset,
+		Class:  ErrorClassMalformedRecord,
+		Reason: err.Error(),
+		Raw:    raw,
+	}
+
+	if deliveryError, ok := err.(*DeliveryError); ok {
+		recordError.Class = deliveryError.Class
+		recordError.Reason = deliveryError.Message
+	}
+
+	return recordError
+}
@@ -0,0 +1,161 @@
+package adapters
+
+import (
+	"context"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// progressEvery is how many records are decoded between progress reports and
+// cancellation checks, so neither shows up in the decoding time
+const progressEvery = 4096
+
+// readChunkSize is how much of the file is read between cancellation checks
+const readChunkSize = 4 << 20
+
+// Progress is how far the decoding of an input file went, counted in bytes of
+// the file. While Reading, the file is still being read into memory and Read
+// counts the bytes read so far, before any record is decoded.
+type Progress struct {
+	Read    int64
+	Total   int64
+	Records int
+	Elapsed time.Duration
+	Reading bool
+}
+
+// Percent returns how much of the file was decoded, from 0 to 100.
+func (p Progress) Percent() float64 {
+	if p.Total <= 0 {
+		return 0
+	}
+
+	return float64(p.Read) * 100 / float64(p.Total)
+}
+
+// RecordsPerSecond returns how fast the records are being decoded.
+func (p Progress) RecordsPerSecond() float64 {
+	if p.Elapsed <= 0 {
+		return 0
+	}
+
+	return float64(p.Records) / p.Elapsed.Seconds()
+}
+
+// ETA returns how long decoding the rest of the file should take at the
+// current pace, or 0 if it can't be told yet.
+func (p Progress) ETA() time.Duration {
+	if p.Read <= 0 || p.Total <= p.Read {
+		return 0
+	}
+
+	perByte := float64(p.Elapsed) / float64(p.Read)
+	return time.Duration(perByte * float64(p.Total-p.Read))
+}
+
+// progressTracker reports the progress of decoding records to progress, which
+// may be nil, and checks ctx for cancellation along the way
+type progressTracker struct {
+	ctx      context.Context
+	progress func(Progress)
+	total    int64
+	started  time.Time
+	records  int
+
+	// mu guards read and records when the records are decoded by many workers
+	mu   *sync.Mutex
+	read int64
+}
+
+func newProgressTracker(ctx context.Context, total int64, progress func(Progress)) *progressTracker {
+	return &progressTracker{ctx: ctx, progress: progress, total: total, started: time.Now(), mu: new(sync.Mutex)}
+}
+
+// record counts a record decoded up to offset. It returns false when the
+// decoding should stop because ctx was canceled.
+func (t *progressTracker) record(offset int64) bool {
+	t.records++
+	if t.records%progressEvery != 0 {
+		return true
+	}
+
+	t.report(offset)
+	return t.ctx.Err() == nil
+}
+
+// add counts records decoded by one of many workers, spanning bytes of the
+// file. It returns false when the decoding should stop because ctx was
+// canceled.
+func (t *progressTracker) add(records int, bytes int64) bool {
+	t.mu.Lock()
+	t.records += records
+	t.read += bytes
+	t.report(t.read)
+	t.mu.Unlock()
+
+	return t.ctx.Err() == nil
+}
+
+func (t *progressTracker) report(offset int64) {
+	if t.progress != nil {
+		t.progress(Progress{Read: offset, Total: t.total, Records: t.records, Elapsed: time.Since(t.started)})
+	}
+}
+
+// done reports the whole file as decoded, unless ctx was canceled, in which
+// case its error is returned
+func (t *progressTracker) done() error {
+	if err := t.ctx.Err(); err != nil {
+		return err
+	}
+
+	t.report(t.total)
+	return nil
+}
+
+// readFile reads the whole file at filePath like ioutil.ReadFile, but in
+// chunks, so a large file can be abandoned through ctx. The bytes read are
+// reported to progress, which may be nil, after every chunk.
+func readFile(ctx context.Context, filePath string, progress func(Progress)) ([]byte, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	started := time.Now()
+	data := make([]byte, 0, info.Size()+1)
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		chunk := data[len(data):cap(data)]
+		if len(chunk) > readChunkSize {
+			chunk = chunk[:readChunkSize]
+		}
+		if len(chunk) == 0 {
+			data = append(data, 0)[:len(data)]
+			continue
+		}
+
+		n, err := file.Read(chunk)
+		data = data[:len(data)+n]
+		if progress != nil && n > 0 {
+			progress(Progress{Read: int64(len(data)), Total: info.Size(), Elapsed: time.Since(started), Reading: true})
+		}
+		if err == io.EOF {
+			return data, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package adapters
+
+import (
+	"fmt"
+)
+
+// MalformedFileError is returned when the input is not a JSON array of
+// records. Offset is the position of the problem, counted in bytes.
+type MalformedFileError struct {
+	Offset  int64
+	Message string
+}
+
+func (e *MalformedFileError) Error() string {
+	return fmt.Sprintf("malformed input file at byte %d: %s", e.Offset, e.Message)
+}
+
+// scanRecords walks the top level JSON array in data without decoding it,
+// calling visit with the index, the byte offset and the raw bytes of every
+// element. Elements are only delimited here, so a broken element is still
+// handed to visit as long as its brackets and quotes are balanced. Scanning
+// stops early if visit returns false.
+func scanRecords(data []byte, visit func(index int, offset int64, raw []byte) bool) error {
+	i, err := arrayStart(data)
+	if err != nil {
+		return err
+	}
+
+	if i < len(data) && data[i] == ']' {
+		return checkTrailing(data, i+1)
+	}
+
+	for index := 0; ; index++ {
+		end, err := elementEnd(data, i)
+		if err != nil {
+			return err
+		}
+
+		if !visit(index, int64(i), data[i:end]) {
+			return nil
+		}
+
+		next, closed, err := nextElement(data, end)
+		if closed || err != nil {
+			return err
+		}
+		i = next
+	}
+}
+
+// arrayStart finds where the first element of the top level array in data
+// starts, or its closing ] if it is empty.
+func arrayStart(data []byte) (int, error) {
+	i := skipWhitespace(data, 0)
+	if i >= len(data) || data[i] != '[' {
+		return 0, &MalformedFileError{Offset: int64(i), Message: "expected the records array to start with ["}
+	}
+
+	return skipWhitespace(data, i+1), nil
+}
+
+// nextElement finds where the element after the one ending at end starts.
+// closed tells the array ended instead, in which case the error is about what
+// comes after it.
+func nextElement(data []byte, end int) (int, bool, error) {
+	i := skipWhitespace(data, end)
+	if i >= len(data) {
+		return 0, false, &MalformedFileError{Offset: int64(i), Message: "unexpected end of file, expected , or ]"}
+	}
+
+	switch data[i] {
+	case ',':
+		return skipWhitespace(data, i+1), false, nil
+	case ']':
+		return 0, true, checkTrailing(data, i+1)
+	default:
+		return 0, false, &MalformedFileError{Offset: int64(i), Message: fmt.Sprintf("unexpected character %q, expected , or ]", data[i])}
+	}
+}
+
+// elementEnd finds where the element starting at start ends, keeping track of
+// nested objects and arrays and of quoted strings.
+func elementEnd(data []byte, start int) (int, error) {
+	depth := 0
+	inString := false
+
+	for i := start; i < len(data); i++ {
+		char := data[i]
+
+		if inString {
+			switch char {
+			case '\\':
+				i++
+			case '"':
+				inString = false
+				if depth == 0 {
+					return i + 1, nil
+				}
+			}
+			continue
+		}
+
+		switch char {
+		case '"':
+			inString = true
+		case '{', '[':
+			depth++
+		case '}', ']':
+			if depth == 0 {
+				if i == start {
+					return 0, &MalformedFileError{Offset: int64(i), Message: fmt.Sprintf("unexpected character %q, expected a record", char)}
+				}
+				return i, nil
+			}
+			depth--
+			if depth == 0 {
+				return i + 1, nil
+			}
+		case ',', ' ', '\t', '\n', '\r':
+			if depth == 0 {
+				if i == start {
+					return 0, &MalformedFileError{Offset: int64(i), Message: fmt.Sprintf("unexpected character %q, expected a record", char)}
+				}
+				return i, nil
+			}
+		}
+	}
+
+	return 0, &MalformedFileError{Offset: int64(len(data)), Message: "unexpected end of file within a record"}
+}
+
+func skipWhitespace(data []byte, i int) int {
+	for i < len(data) && isWhitespace(data[i]) {
+		i++
+	}
+
+	return i
+}
+
+func isWhitespace(char byte) bool {
+	return char == ' ' || char == '\t' || char == '\n' || char == '\r'
+}
+
+func checkTrailing(data []byte, i int) error {
+	if i = skipWhitespace(data, i); i < len(data) {
+		return &MalformedFileError{Offset: int64(i), Message: "unexpected content after the records array"}
+	}
+
+	return nil
+}
@@ -0,0 +1,232 @@
+package adapters
+
+import (
+	"context"
+	"sync"
+)
+
+// minShardSize is the smallest byte range worth handing to a worker, so small
+// files are decoded sequentially
+const minShardSize = 64 << 10
+
+// recordShard is a byte range of the records array, starting at a record and
+// ending where the next shard starts
+type recordShard struct {
+	start int64
+	end   int64
+}
+
+// recordDecoder decodes the raw record at index, found at offset, or tells why
+// it was rejected
+type recordDecoder func(index int, offset int64, raw []byte) (GeneralRecipe, *RecordError)
+
+// decodeRecords decodes every record of the array in file with a decoder from
+// newDecoder, handing the rejected ones to reject. With more than one worker
+// the array is split into byte ranges at what look like record boundaries,
+// each decoded by its own worker with its own decoder, and the results are put
+// back together in the order of the file, so the recipes, the rejects and
+// their order are the same as decoding it sequentially. If a shard doesn't end
+// where the next one starts, a boundary was guessed wrong, such as within a
+// string, and the file is decoded sequentially instead. newDecoder also
+// returns the function releasing the decoder.
+func decodeRecords(ctx context.Context, file []byte, workers int, reject func(*RecordError), progress func(Progress), newDecoder func() (recordDecoder, func())) (*[]GeneralRecipe, error) {
+	tracker := newProgressTracker(ctx, int64(len(file)), progress)
+
+	shards := 1
+	if workers > 1 {
+		shards = workers
+		if maxShards := len(file) / minShardSize; maxShards < shards {
+			shards = maxShards
+		}
+	}
+	if shards <= 1 {
+		return decodeSequentially(file, tracker, reject, newDecoder)
+	}
+
+	ranges, err := shardRecords(file, shards)
+	if err != nil {
+		return nil, err
+	}
+	if len(ranges) <= 1 {
+		return decodeSequentially(file, tracker, reject, newDecoder)
+	}
+
+	results := make([]shardResult, len(ranges))
+	wg := sync.WaitGroup{}
+	for i := range ranges {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = decodeShard(file, ranges[i], tracker, newDecoder)
+		}(i)
+	}
+	wg.Wait()
+
+	if err := tracker.done(); err != nil {
+		return nil, err
+	}
+
+	// every shard is checked to end where the next one starts before its
+	// error is trusted, so the first error is the one scanning sequentially
+	// runs into
+	count := 0
+	for i, result := range results {
+		if result.err != nil {
+			return nil, result.err
+		}
+		if i < len(ranges)-1 && result.next != ranges[i+1].start {
+			return decodeSequentially(file, newProgressTracker(ctx, int64(len(file)), progress), reject, newDecoder)
+		}
+		count += len(result.recipes)
+	}
+
+	rawRecipes := make([]GeneralRecipe, 0, count)
+	index := 0
+	for _, result := range results {
+		for _, recordError := range result.rejects {
+			recordError.Index += index
+			reject(recordError)
+		}
+		rawRecipes = append(rawRecipes, result.recipes...)
+		index += result.records
+	}
+
+	return &rawRecipes, nil
+}
+
+// decodeSequentially decodes every record of file as it is scanned
+func decodeSequentially(file []byte, tracker *progressTracker, reject func(*RecordError), newDecoder func() (recordDecoder, func())) (*[]GeneralRecipe, error) {
+	decode, release := newDecoder()
+	defer release()
+
+	rawRecipes := []GeneralRecipe{}
+	err := scanRecords(file, func(index int, offset int64, raw []byte) bool {
+		rawRecipe, recordError := decode(index, offset, raw)
+		if recordError != nil {
+			reject(recordError)
+		} else {
+			rawRecipes = append(rawRecipes, rawRecipe)
+		}
+
+		return tracker.record(offset)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := tracker.done(); err != nil {
+		return nil, err
+	}
+
+	return &rawRecipes, nil
+}
+
+// shardRecords splits the records array in data into up to shards byte ranges
+// of about the same size without scanning it, seeking to where every range
+// should start and moving on to the next record from there
+func shardRecords(data []byte, shards int) ([]recordShard, error) {
+	first, err := arrayStart(data)
+	if err != nil {
+		return nil, err
+	}
+
+	ranges := []recordShard{{start: int64(first), end: int64(len(data))}}
+	for i := 1; i < shards; i++ {
+		start := recordStartAfter(data, len(data)*i/shards)
+		if start < 0 {
+			break
+		}
+		if int64(start) <= ranges[len(ranges)-1].start {
+			continue
+		}
+
+		ranges[len(ranges)-1].end = int64(start)
+		ranges = append(ranges, recordShard{start: int64(start), end: int64(len(data))})
+	}
+
+	return ranges, nil
+}
+
+// recordStartAfter finds the first record starting after offset, the { of a
+// }, { between two records, or -1 if there is none. Seeking into the middle of
+// the file, it can't tell whether that is within a string, which decodeShard
+// finds out.
+func recordStartAfter(data []byte, offset int) int {
+	for i := offset; i < len(data); i++ {
+		if data[i] != ',' {
+			continue
+		}
+
+		previous := i - 1
+		for previous > offset && isWhitespace(data[previous]) {
+			previous--
+		}
+		next := skipWhitespace(data, i+1)
+		if previous >= offset && data[previous] == '}' && next < len(data) && data[next] == '{' {
+			return next
+		}
+	}
+
+	return -1
+}
+
+// shardResult is what a worker decoded from its shard. next is where the
+// record after the shard starts, which is the start of the next shard unless a
+// boundary was guessed wrong.
+type shardResult struct {
+	recipes []GeneralRecipe
+	rejects []*RecordError
+	records int
+	next    int64
+	err     error
+}
+
+// decodeShard decodes the records of shard, scanning them the same way as
+// scanRecords, until reaching the end of the shard or of the array. The
+// indexes of the rejects are counted from the start of the shard, and they are
+// kept to be handed over in the order of the file.
+func decodeShard(data []byte, shard recordShard, tracker *progressTracker, newDecoder func() (recordDecoder, func())) shardResult {
+	decode, release := newDecoder()
+	defer release()
+
+	result := shardResult{}
+	reported := shard.start
+	records := 0
+
+	if int(shard.start) < len(data) && data[shard.start] == ']' {
+		result.err = checkTrailing(data, int(shard.start)+1)
+		return result
+	}
+
+	i := int(shard.start)
+	for index := 0; ; index++ {
+		end, err := elementEnd(data, i)
+		if err != nil {
+			result.err = err
+			return result
+		}
+
+		rawRecipe, recordError := decode(index, int64(i), data[i:end])
+		if recordError != nil {
+			result.rejects = append(result.rejects, recordError)
+		} else {
+			result.recipes = append(result.recipes, rawRecipe)
+		}
+		result.records++
+
+		records++
+		if records%progressEvery == 0 {
+			if !tracker.add(progressEvery, int64(i)-reported) {
+				return result
+			}
+			records, reported = 0, int64(i)
+		}
+
+		next, closed, err := nextElement(data, end)
+		if closed || err != nil || int64(next) >= shard.end {
+			tracker.add(records, shard.end-reported)
+			result.next, result.err = int64(next), err
+			return result
+		}
+		i = next
+	}
+}
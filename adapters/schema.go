@@ -0,0 +1,104 @@
+package adapters
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Schema maps the fields of an input JSON file into the ones of GeneralRecipe.
+// Every field is a path to a value, with the keys of nested objects separated
+// by dots, such as "slot.day". The delivery is either a string in the
+// "Wednesday 8AM - 2PM" format, at the Delivery path, or a structured Slot.
+type Schema struct {
+	Recipe   string      `mapstructure:"recipe"`
+	Postcode string      `mapstructure:"postcode"`
+	Delivery string      `mapstructure:"delivery"`
+	Slot     *SlotSchema `mapstructure:"slot"`
+}
+
+// SlotSchema maps a delivery informed as separate fields, such as
+// {"day": "Wednesday", "start": "8AM", "end": "2PM"}. The times are either 12h
+// strings, parsed with the adapter's policy, or 24h hour numbers.
+type SlotSchema struct {
+	Weekday string `mapstructure:"weekday"`
+	From    string `mapstructure:"from"`
+	To      string `mapstructure:"to"`
+}
+
+// DefaultSchema is the schema of the fixtures file provided on the
+// requirements, handled by GeneralRecipeAdapter.
+func DefaultSchema() Schema {
+	return Schema{Recipe: "recipe", Postcode: "postcode", Delivery: "delivery"}
+}
+
+// WithDefaults fills the fields left empty with the ones of DefaultSchema. The
+// delivery is only defaulted when there is no slot.
+func (s Schema) WithDefaults() Schema {
+	defaults := DefaultSchema()
+	if s.Recipe == "" {
+		s.Recipe = defaults.Recipe
+	}
+	if s.Postcode == "" {
+		s.Postcode = defaults.Postcode
+	}
+	if s.Delivery == "" && s.Slot == nil {
+		s.Delivery = defaults.Delivery
+	}
+
+	return s
+}
+
+// IsDefault tells if the schema is the DefaultSchema.
+func (s Schema) IsDefault() bool {
+	return s.Slot == nil && s == DefaultSchema()
+}
+
+// Validate checks that every field is mapped and the delivery is mapped only
+// once.
+func (s Schema) Validate() error {
+	paths := map[string]string{"recipe": s.Recipe, "postcode": s.Postcode}
+
+	if s.Slot != nil {
+		if s.Delivery != "" {
+			return fmt.Errorf("map either the delivery or its slot, not both")
+		}
+		paths["slot.weekday"] = s.Slot.Weekday
+		paths["slot.from"] = s.Slot.From
+		paths["slot.to"] = s.Slot.To
+	} else {
+		paths["delivery"] = s.Delivery
+	}
+
+	for field, path := range paths {
+		if path == "" {
+			return fmt.Errorf("the %s field is not mapped", field)
+		}
+		for _, key := range strings.Split(path, ".") {
+			if key == "" {
+				return fmt.Errorf("the %s field has an invalid path %q", field, path)
+			}
+		}
+	}
+
+	return nil
+}
+
+// NewRecordsAdapter provides the adapter for files in schema, parsing the
+// deliveries with policy. The default schema is handled by the faster
+// GeneralRecipeAdapter, any other one by MappedRecipeAdapter.
+func NewRecordsAdapter(schema Schema, policy ParsePolicy) (RecordsAdapter, error) {
+	schema = schema.WithDefaults()
+	if schema.IsDefault() {
+		adapter := NewGeneralRecipeAdapter()
+		adapter.SetParsePolicy(policy)
+		return &adapter, nil
+	}
+
+	adapter, err := NewMappedRecipeAdapter(schema)
+	if err != nil {
+		return nil, err
+	}
+	adapter.SetParsePolicy(policy)
+
+	return &adapter, nil
+}
@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"recipe-stats/loaders"
+
+	"github.com/spf13/cobra"
+)
+
+// exportCmd groups the commands that write the normalised dataset into other
+// formats.
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Exports the normalised dataset into other formats.",
+}
+
+// exportSQLiteCmd writes recipes, postcodes and deliveries into a SQLite
+// database, parsed the same way they are for the stats.
+var exportSQLiteCmd = &cobra.Command{
+	Use:   "sqlite",
+	Short: "Exports the input file into a SQLite database.",
+	Long: `Exports the input file into a SQLite database with the tables recipes, postcodes and deliveries, along with the delivery_details view that joins them.
+
+Deliveries have their weekday, from_hour and to_hour (24h format) already parsed, so the numbers match the ones reported by recipe-stats.
+
+Example: recipe-stats export sqlite -f data.json -o data.sqlite
+`,
+	Args:          cobra.NoArgs,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		filePath, _ := cmd.Flags().GetString("file")
+		outputPath, _ := cmd.Flags().GetString("output")
+
+		recipesAdapter, err := getRecordsAdapter(cmd)
+		if err != nil {
+			return err
+		}
+
+		telemetry, err := getTelemetry(cmd)
+		if err != nil {
+			return err
+		}
+
+		telemetry.profile(profileExport, func() {
+			err = loaders.ExportToSQLiteContext(context.Background(), filePath, outputPath, recipesAdapter, telemetry.Observer)
+		})
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(os.Stderr, "SQLite database written to %s\n", outputPath)
+		telemetry.summary()
+		return nil
+	},
+}
+
+func init() {
+	exportSQLiteCmd.Flags().StringP("output", "o", "", "The path of the SQLite database file to write. Example: data.sqlite")
+	_ = exportSQLiteCmd.MarkFlagRequired("output")
+
+	exportCmd.AddCommand(exportSQLiteCmd)
+	rootCmd.AddCommand(exportCmd)
+}
@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"recipe-stats/generator"
+
+	"github.com/spf13/cobra"
+)
+
+// generateCmd writes a synthetic input file, to measure the loading and the
+// calculations at any size.
+var generateCmd = &cobra.Command{
+	Use:   "generate",
+	Short: "Generates a synthetic input file of any size.",
+	Long: `Generates a synthetic input file in the default format, with as many records, distinct recipes and postcodes as asked for. The same flags, --seed included, always generate the same file.
+
+Postcodes are spread either uniformly or following a zipf distribution, where a few of them have most of the deliveries. Delivery windows either start and end at any hour, or start in the morning and end 2 to 8 hours later with the business distribution.
+
+Example: recipe-stats generate -o data.json --records 1000000 --postcodes 5000 --postcode-distribution zipf
+`,
+	Args:          cobra.NoArgs,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		outputPath, _ := cmd.Flags().GetString("output")
+
+		config := generator.DefaultConfig()
+		config.Seed, _ = cmd.Flags().GetInt64("seed")
+		config.Records, _ = cmd.Flags().GetInt("records")
+		config.Recipes, _ = cmd.Flags().GetInt("recipes")
+		config.Postcodes, _ = cmd.Flags().GetInt("postcodes")
+		config.PostcodeDistribution, _ = cmd.Flags().GetString("postcode-distribution")
+		config.WindowDistribution, _ = cmd.Flags().GetString("window-distribution")
+		if err := config.Validate(); err != nil {
+			return err
+		}
+
+		if outputPath == "-" {
+			out := bufio.NewWriter(os.Stdout)
+			if err := generator.GenerateContext(context.Background(), out, config); err != nil {
+				return err
+			}
+			return out.Flush()
+		}
+
+		file, err := os.Create(outputPath)
+		if err != nil {
+			return err
+		}
+
+		if err := generator.GenerateContext(context.Background(), file, config); err != nil {
+			file.Close()
+			return err
+		}
+		if err := file.Close(); err != nil {
+			return err
+		}
+
+		fmt.Fprintf(os.Stderr, "%d records written to %s\n", config.Records, outputPath)
+		return nil
+	},
+}
+
+func init() {
+	defaults := generator.DefaultConfig()
+
+	generateCmd.Flags().StringP("output", "o", "", "The path of the file to write, or - for the standard output. Example: data.json")
+	_ = generateCmd.MarkFlagRequired("output")
+	generateCmd.Flags().Int64("seed", defaults.Seed, "The seed of the random numbers, the same one always generating the same file")
+	generateCmd.Flags().Int("records", defaults.Records, "How many records to generate")
+	generateCmd.Flags().Int("recipes", defaults.Recipes, fmt.Sprintf("How many distinct recipe names to use, up to %d", generator.MaxRecipes))
+	generateCmd.Flags().Int("postcodes", defaults.Postcodes, fmt.Sprintf("How many distinct postcodes to use, up to %d", generator.MaxPostcodes))
+	generateCmd.Flags().String("postcode-distribution", defaults.PostcodeDistribution, "How the deliveries are spread over the postcodes: uniform or zipf")
+	generateCmd.Flags().String("window-distribution", defaults.WindowDistribution, "How the delivery windows are picked: uniform or business")
+
+	rootCmd.AddCommand(generateCmd)
+}
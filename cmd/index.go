@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"recipe-stats/loaders"
+
+	"github.com/spf13/cobra"
+)
+
+// indexCmd groups the commands that handle snapshots of the loaded dataset.
+var indexCmd = &cobra.Command{
+	Use:   "index",
+	Short: "Manages binary snapshots of a loaded dataset for instant startup.",
+}
+
+// indexBuildCmd parses the input file once and writes the loaded keepers as a
+// snapshot, along with how many records were rejected. The snapshot can be
+// used later on as the --file of the flag mode, interactive, shell, serve and
+// run.
+var indexBuildCmd = &cobra.Command{
+	Use:   "build",
+	Short: "Builds a snapshot from an input file.",
+	Long: `Builds a binary snapshot from the input file, so it can be loaded later on without parsing the JSON again.
+
+Example: recipe-stats index build -f data.json -o data.rsidx
+         recipe-stats -f data.rsidx -c
+`,
+	Args:          cobra.NoArgs,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		filePath, _ := cmd.Flags().GetString("file")
+		outputPath, _ := cmd.Flags().GetString("output")
+		rejectFilePath, _ := cmd.Flags().GetString("reject-file")
+
+		recipesAdapter, err := getRecordsAdapter(cmd)
+		if err != nil {
+			return err
+		}
+
+		telemetry, err := getTelemetry(cmd)
+		if err != nil {
+			return err
+		}
+
+		rejects, err := loaders.NewRejects(rejectFilePath)
+		if err != nil {
+			return err
+		}
+
+		telemetry.profile(profileIndex, func() {
+			err = loaders.BuildSnapshotContext(context.Background(), filePath, outputPath, recipesAdapter, rejects, telemetry.Observer)
+		})
+		if closeErr := rejects.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return err
+		}
+
+		if rejects.Count() > 0 {
+			fmt.Fprintf(os.Stderr, "%d records were rejected\n", rejects.Count())
+		}
+		fmt.Fprintf(os.Stderr, "Snapshot written to %s\n", outputPath)
+		telemetry.summary()
+		return nil
+	},
+}
+
+func init() {
+	indexBuildCmd.Flags().StringP("output", "o", "", "The path of the snapshot file to write. Example: data.rsidx")
+	_ = indexBuildCmd.MarkFlagRequired("output")
+
+	indexCmd.AddCommand(indexBuildCmd)
+	rootCmd.AddCommand(indexCmd)
+}
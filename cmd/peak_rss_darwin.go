@@ -0,0 +1,13 @@
+package cmd
+
+import "syscall"
+
+// peakRSS returns the most memory the process ever had resident, in bytes
+func peakRSS() int64 {
+	usage := syscall.Rusage{}
+	if err := syscall.Getrusage(syscall.RUSAGE_SELF, &usage); err != nil {
+		return 0
+	}
+
+	return usage.Maxrss
+}
@@ -0,0 +1,9 @@
+//go:build !linux && !darwin
+// +build !linux,!darwin
+
+package cmd
+
+// peakRSS returns 0 where the peak of resident memory can't be told
+func peakRSS() int64 {
+	return 0
+}
@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+	"runtime/trace"
+	"strings"
+)
+
+// Phases profiled separately by the --cpuprofile, --memprofile and --trace
+// flags.
+const (
+	profileLoad      = "load"
+	profileCalculate = "calculate"
+	profileQuery     = "query"
+	profileValidate  = "validate"
+	profileExport    = "export"
+	profileIndex     = "index"
+)
+
+// profiler captures the profiles asked for by the --cpuprofile, --memprofile
+// and --trace flags, every phase into its own files
+type profiler struct {
+	cpuPath   string
+	memPath   string
+	tracePath string
+}
+
+// profile runs the phase named phase, capturing the profiles around it into
+// files named after it, such as cpu.load.prof for --cpuprofile cpu.prof. The
+// memory profile counts every allocation since the program started, so the
+// one of a phase is compared to the one of the previous phase with the -base
+// flag of go tool pprof. A profile that can't be captured is reported to
+// stderr without stopping the phase.
+func (p profiler) profile(phase string, run func()) {
+	var cpuFile, traceFile *os.File
+	var err error
+
+	if p.cpuPath != "" {
+		cpuFile, err = os.Create(phasePath(p.cpuPath, phase))
+		if err == nil {
+			err = pprof.StartCPUProfile(cpuFile)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "It was impossible to start the CPU profile. The error was: %s\n", err.Error())
+		}
+	}
+	if p.tracePath != "" {
+		traceFile, err = os.Create(phasePath(p.tracePath, phase))
+		if err == nil {
+			err = trace.Start(traceFile)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "It was impossible to start the trace. The error was: %s\n", err.Error())
+		}
+	}
+
+	run()
+
+	if cpuFile != nil {
+		pprof.StopCPUProfile()
+		cpuFile.Close()
+	}
+	if traceFile != nil {
+		trace.Stop()
+		traceFile.Close()
+	}
+	if p.memPath != "" {
+		if err := writeMemProfile(phasePath(p.memPath, phase)); err != nil {
+			fmt.Fprintf(os.Stderr, "It was impossible to write the memory profile. The error was: %s\n", err.Error())
+		}
+	}
+}
+
+func writeMemProfile(filePath string) error {
+	file, err := os.Create(filePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// the profile is only up to date as of the last garbage collection
+	runtime.GC()
+	return pprof.Lookup("allocs").WriteTo(file, 0)
+}
+
+// phasePath inserts phase before the extension of filePath, or appends it if
+// there is none
+func phasePath(filePath string, phase string) string {
+	extension := filepath.Ext(filePath)
+
+	return strings.TrimSuffix(filePath, extension) + "." + phase + extension
+}
@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"recipe-stats/loaders"
+	"recipe-stats/queries"
+	"recipe-stats/reporters"
+
+	"github.com/spf13/cobra"
+)
+
+// queryCmd runs an ad-hoc SQL-like query over every delivery of the input
+// file.
+var queryCmd = &cobra.Command{
+	Use:   "query <query>",
+	Short: "Runs an SQL-like query over the deliveries of the input file.",
+	Long: `Runs an SQL-like query over the deliveries of the input file. The only table available is deliveries, with the fields:
+
+- recipe, postcode and weekday (text)
+- from and to (number, in 24h format)
+
+Queries support WHERE (=, !=, <>, <, <=, >, >=, LIKE, IN, AND, OR, NOT), GROUP BY, the count, sum, avg, min and max aggregates, ORDER BY (by column position, name or alias) and LIMIT.
+
+Example: recipe-stats query "SELECT recipe, count(*) FROM deliveries WHERE postcode LIKE '101%' AND from >= 9 GROUP BY recipe ORDER BY 2 DESC LIMIT 10"
+`,
+	Args:          cobra.ExactArgs(1),
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		filePath, _ := cmd.Flags().GetString("file")
+		format, _ := cmd.Flags().GetString("format")
+
+		recipesAdapter, err := getRecordsAdapter(cmd)
+		if err != nil {
+			return err
+		}
+
+		telemetry, err := getTelemetry(cmd)
+		if err != nil {
+			return err
+		}
+
+		if format != "json" && format != "table" {
+			return fmt.Errorf("unknown format %q, use json or table", format)
+		}
+
+		// parsing first so a wrong query fails before loading a large file
+		query, err := queries.Parse(args[0])
+		if err != nil {
+			return err
+		}
+
+		var result *queries.Result
+		telemetry.profile(profileQuery, func() {
+			result, err = loaders.QueryFromGeneralRecipeContext(context.Background(), filePath, query, recipesAdapter, telemetry.Observer)
+		})
+		if err != nil {
+			return err
+		}
+
+		output := reporters.QueryReporter{Columns: result.Columns, Rows: result.Rows}
+		var formattedOutput string
+		if format == "table" {
+			formattedOutput, err = output.MarshalTable()
+		} else {
+			formattedOutput, err = output.Marshal()
+		}
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintln(os.Stdout, formattedOutput)
+		telemetry.summary()
+		return nil
+	},
+}
+
+func init() {
+	queryCmd.Flags().String("format", "json", "The output format, either json or table")
+
+	rootCmd.AddCommand(queryCmd)
+}
@@ -3,6 +3,10 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"path/filepath"
+	"recipe-stats/adapters"
+	"recipe-stats/history"
+	"runtime"
 
 	"github.com/spf13/cobra"
 
@@ -30,26 +34,110 @@ Example: recipe-stats -s=Cheese,Grilled -f=path_to_file -c
 `,
 	Run: func(cmd *cobra.Command, args []string) {
 		filePath, _ := cmd.PersistentFlags().GetString("file")
+		appendFilePaths, _ := cmd.PersistentFlags().GetStringSlice("append")
+		rejectFilePath, _ := cmd.PersistentFlags().GetString("reject-file")
 		recipeCount, _ := cmd.PersistentFlags().GetBool("count")
 		namesToSearch, _ := cmd.PersistentFlags().GetStringSlice("search")
 		postcodeToSearch, _ := cmd.PersistentFlags().GetString("postcode")
 		from, _ := cmd.PersistentFlags().GetString("from")
 		to, _ := cmd.PersistentFlags().GetString("to")
-		verbose, _ := cmd.PersistentFlags().GetBool("verbose")
 		interactive, _ := cmd.PersistentFlags().GetBool("interactive")
 
+		recipesAdapter, err := getRecordsAdapter(cmd)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+		telemetry, err := getTelemetry(cmd)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
 		if interactive {
-			interactiveFlow(filePath)
+			interactiveFlow(filePath, recipesAdapter, getHistoryStore())
 		} else {
-			runFromCli(filePath, recipeCount, namesToSearch, postcodeToSearch, from, to, verbose)
+			runFromCli(filePath, appendFilePaths, rejectFilePath, recipesAdapter, recipeCount, namesToSearch, postcodeToSearch, from, to, telemetry)
 		}
 	},
 }
 
+// exitError is returned by commands that need to exit with a specific code,
+// such as validate.
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string {
+	return e.err.Error()
+}
+
+// getParsePolicy builds the policy used to parse the deliveries from the
+// --parse-policy flag, which defaults to the parse_policy of the config file.
+func getParsePolicy(cmd *cobra.Command) (adapters.ParsePolicy, error) {
+	names, _ := cmd.Flags().GetStringSlice("parse-policy")
+
+	policy, err := adapters.NewParsePolicy(names)
+	if err != nil {
+		return policy, fmt.Errorf("invalid parse policy: %w", err)
+	}
+
+	return policy, nil
+}
+
+// getRecordsAdapter builds the adapter used to decode the input files from the
+// schema of the config file, parsing the deliveries with the policy from
+// getParsePolicy and decoding them with as many workers as --workers.
+func getRecordsAdapter(cmd *cobra.Command) (adapters.RecordsAdapter, error) {
+	policy, err := getParsePolicy(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	schema := adapters.Schema{}
+	if err := viper.UnmarshalKey("schema", &schema); err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+
+	recordsAdapter, err := adapters.NewRecordsAdapter(schema, policy)
+	if err != nil {
+		return nil, err
+	}
+
+	workers, _ := cmd.Flags().GetInt("workers")
+	if workers < 1 {
+		return nil, fmt.Errorf("invalid number of workers %d, expected 1 or more", workers)
+	}
+	recordsAdapter.SetWorkers(workers)
+
+	return recordsAdapter, nil
+}
+
+// getHistoryStore provides the store of the interactive queries, kept at the
+// history_file of the config file or in the home directory by default.
+func getHistoryStore() history.Store {
+	filePath := viper.GetString("history_file")
+	if filePath == "" {
+		home, err := homedir.Dir()
+		if err != nil {
+			home = "."
+		}
+		filePath = filepath.Join(home, ".recipe-stats_history.json")
+	}
+
+	return history.NewStore(filePath)
+}
+
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
+		if exitErr, ok := err.(*exitError); ok {
+			fmt.Fprintln(os.Stderr, exitErr)
+			os.Exit(exitErr.code)
+		}
 		fmt.Println(err)
 		os.Exit(1)
 	}
@@ -60,6 +148,11 @@ func init() {
 
 	rootCmd.PersistentFlags().StringP("file", "f", viper.GetString("file_path"), "The full path of a different input file to analyze")
 	_ = viper.BindPFlag("file_path", rootCmd.PersistentFlags().Lookup("file"))
+	rootCmd.PersistentFlags().StringSliceP("append", "a", nil, "Comma separated list of files with new records to add on top of --file")
+	rootCmd.PersistentFlags().String("reject-file", "", "The path of a file to write the records that can't be parsed to, as NDJSON")
+	rootCmd.PersistentFlags().StringSlice("parse-policy", viper.GetStringSlice("parse_policy"), "How to parse the deliveries: strict, lenient or a comma separated list of leniency rules")
+	_ = viper.BindPFlag("parse_policy", rootCmd.PersistentFlags().Lookup("parse-policy"))
+	rootCmd.PersistentFlags().Int("workers", runtime.NumCPU(), "How many workers decode the input files at the same time, each one a range of their records")
 	rootCmd.PersistentFlags().BoolP("count", "c", false, "Counts the number of unique recipes")
 	rootCmd.PersistentFlags().StringSliceP("search", "s", nil, "Comma separated list of recipe names to find")
 	rootCmd.PersistentFlags().StringP("postcode", "p", "", "Postcode number to lookup. Using that flag will require you to inform the --from and --to flags")
@@ -67,6 +160,11 @@ func init() {
 	rootCmd.PersistentFlags().String("to", "", "The ending time for postcode deliveries search. Example: 2PM")
 	rootCmd.PersistentFlags().BoolP("interactive", "i", false, "Runs the program in interactive mode. Any other flag will be ignored.")
 	rootCmd.PersistentFlags().BoolP("verbose", "v", false, "Prints profiling and performance messages")
+	rootCmd.PersistentFlags().String("log-format", "text", "The format of the messages printed by --verbose, either text or json")
+	rootCmd.PersistentFlags().String("log-level", "debug", "The lowest level of the messages printed: debug, info, warn or error. Setting it prints them even without --verbose")
+	rootCmd.PersistentFlags().String("cpuprofile", "", "Writes a CPU profile of every phase, such as loading and calculating, named after the phase. Example: cpu.prof writes cpu.load.prof")
+	rootCmd.PersistentFlags().String("memprofile", "", "Writes a memory profile after every phase, named after the phase. Example: mem.prof writes mem.load.prof")
+	rootCmd.PersistentFlags().String("trace", "", "Writes an execution trace of every phase, named after the phase. Example: trace.out writes trace.load.out")
 
 	rootCmd.Flags().SortFlags = false
 	rootCmd.PersistentFlags().SortFlags = false
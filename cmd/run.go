@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"recipe-stats/history"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// runCmd runs a query saved in interactive mode, without asking anything.
+var runCmd = &cobra.Command{
+	Use:   "run <name>",
+	Short: "Runs a query saved in interactive mode.",
+	Long: `Runs a query saved in interactive mode with "Save this query as", printing the same output of the flag mode.
+
+Example: recipe-stats run weekly-pasta
+`,
+	Args:          cobra.ExactArgs(1),
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		rejectFilePath, _ := cmd.Flags().GetString("reject-file")
+
+		store := getHistoryStore()
+		query, err := store.Saved(args[0])
+		if errors.Is(err, history.ErrQueryNotFound) {
+			names, _ := store.SavedNames()
+			if len(names) == 0 {
+				return fmt.Errorf("there is no saved query named %q, queries can be saved in interactive mode", args[0])
+			}
+			return fmt.Errorf("there is no saved query named %q, use one of: %s", args[0], strings.Join(names, ", "))
+		}
+		if err != nil {
+			return err
+		}
+
+		recipesAdapter, err := getRecordsAdapter(cmd)
+		if err != nil {
+			return err
+		}
+
+		telemetry, err := getTelemetry(cmd)
+		if err != nil {
+			return err
+		}
+
+		runFromCli(query.FilePath, nil, rejectFilePath, recipesAdapter, query.RecipeCount, query.Names, query.Postcode, query.From, query.To, telemetry)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(runCmd)
+}
@@ -1,12 +1,15 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
-	"recipe-stats/keepers"
+	"recipe-stats/adapters"
+	"recipe-stats/history"
 	"recipe-stats/loaders"
+	"recipe-stats/metrics"
+	"recipe-stats/recipestats"
 	"recipe-stats/reporters"
-	"time"
 )
 
 //  Runner contains all the methods focused on triggering the data load from
@@ -16,88 +19,106 @@ import (
 //  the required JSON format, Runner is the place where the result is printed.
 
 // runFromCli is the entrypoint for the CLI execution. It is called when the flag
-// `--interactive` is not set
-func runFromCli(filePath string, recipeCount bool, namesToSearch []string, postcodeToSearch string, from string, to string, verbose bool) {
-	totalStart := time.Now()
+// `--interactive` is not set. Files in appendFilePaths are applied, in order,
+// on top of the dataset loaded from filePath, every file decoded with
+// recipesAdapter. Records that can't be parsed are written to rejectFilePath,
+// if informed. Every step is reported to telemetry, ending with its summary.
+func runFromCli(filePath string, appendFilePaths []string, rejectFilePath string, recipesAdapter adapters.RecordsAdapter, recipeCount bool, namesToSearch []string, postcodeToSearch string, from string, to string, telemetry telemetry) {
+	ctx := context.Background()
+
+	rejects, err := loaders.NewRejects(rejectFilePath)
+	if err != nil {
+		telemetry.Logger.Error("It was impossible to create the reject file", "error", err)
+		jsonOutput := reporters.JSONReporter{}
+		formattedOutput, _ := jsonOutput.Marshal()
+		fmt.Fprintln(os.Stdout, formattedOutput)
+		return
+	}
 
-	recipeKeeper, recipeNameSlicesKeeper, deliveryKeeper, err := loadKeepers(filePath, verbose)
+	var dataset *recipestats.Dataset
+	telemetry.profile(profileLoad, func() {
+		dataset, err = openDataset(ctx, filePath, recipesAdapter, rejects, nil, telemetry.Observer)
+		if err == nil {
+			for _, appendFilePath := range appendFilePaths {
+				err = dataset.Append(ctx, appendFilePath)
+				if err != nil {
+					break
+				}
+			}
+		}
+	})
+	if closeErr := rejects.Close(); closeErr != nil {
+		telemetry.Logger.Error("It was impossible to write the reject file", "error", closeErr)
+	}
 	if err != nil {
+		telemetry.Logger.Error("It was impossible to load the dataset", "error", err)
 		jsonOutput := reporters.JSONReporter{}
 		formattedOutput, _ := jsonOutput.Marshal()
 		fmt.Fprintln(os.Stdout, formattedOutput)
 		return
 	}
 
-	calculate(recipeKeeper, recipeNameSlicesKeeper, deliveryKeeper, recipeCount, namesToSearch, postcodeToSearch, from, to, verbose)
+	request := recipestats.Request{RecipeCount: recipeCount, Names: namesToSearch, Postcode: postcodeToSearch, From: from, To: to}
+	telemetry.profile(profileCalculate, func() {
+		calculate(ctx, dataset, request, telemetry.Observer)
+	})
 
-	if verbose {
-		fmt.Fprintf(os.Stderr, "Total execution took %s\n", time.Since(totalStart))
-	}
+	telemetry.summary()
 }
 
-// runFromInteractive is the entrypoint for the interactive
-func runFromInteractive(recipeKeeper *keepers.RecipeKeeper, recipeNameSlicesKeeper *keepers.RecipeNameSlicesKeeper, deliveryKeeper *keepers.DeliveryKeeper, recipeCount bool, namesToSearch []string, postcodeToSearch string, from string, to string) {
-	calculate(recipeKeeper, recipeNameSlicesKeeper, deliveryKeeper, recipeCount, namesToSearch, postcodeToSearch, from, to, false)
+// runFromInteractive is the entrypoint for the interactive execution, running
+// query over the dataset loaded by the interactive flow.
+func runFromInteractive(dataset *recipestats.Dataset, query history.Query) {
+	request := recipestats.Request{RecipeCount: query.RecipeCount, Names: query.Names, Postcode: query.Postcode, From: query.From, To: query.To}
+	calculate(context.Background(), dataset, request, loaders.Observer{})
 }
 
-func loadKeepers(filePath string, verbose bool) (*keepers.RecipeKeeper, *keepers.RecipeNameSlicesKeeper, *keepers.DeliveryKeeper, error) {
-	recipeKeeper, recipeNameSlicesKeeper, deliveryKeeper, err := loaders.LoadFromGeneralRecipe(filePath, verbose)
-	if err != nil {
-		return nil, nil, nil, err
-	}
-
-	return recipeKeeper, recipeNameSlicesKeeper, deliveryKeeper, nil
+// openDataset loads the dataset from either a snapshot or an input JSON file,
+// depending on the content of the file. An input JSON file is decoded with
+// recipesAdapter, reporting how far it went to progress, which may be nil, and
+// the records that can't be parsed are handed to rejects. Every step is
+// reported to observer.
+func openDataset(ctx context.Context, filePath string, recipesAdapter adapters.RecordsAdapter, rejects *loaders.Rejects, progress func(adapters.Progress), observer loaders.Observer) (*recipestats.Dataset, error) {
+	return recipestats.Open(ctx, filePath,
+		recipestats.WithRecordsAdapter(recipesAdapter),
+		recipestats.WithRejects(rejects),
+		recipestats.WithProgress(progress),
+		recipestats.WithLogger(observer.Logger),
+		recipestats.WithMetrics(observer.Metrics),
+	)
 }
 
-// It supports the verbose option
-func calculate(recipeKeeper *keepers.RecipeKeeper, recipeNameSlicesKeeper *keepers.RecipeNameSlicesKeeper, deliveryKeeper *keepers.DeliveryKeeper, recipeCount bool, namesToSearch []string, postcodeToSearch string, from string, to string, verbose bool) {
-	start := time.Now()
-	jsonOutput := reporters.JSONReporter{}
-
-	if verbose {
-		fmt.Fprintln(os.Stderr, "Calculating...")
-	}
+// calculate gets the stats of request from the dataset and prints them as
+// JSON, reporting on it to observer
+func calculate(ctx context.Context, dataset *recipestats.Dataset, request recipestats.Request, observer loaders.Observer) {
+	finished := observer.Phase(metrics.PhaseCalculate, "Calculating")
 
-	if recipeCount {
-		jsonOutput.UniqueRecipeCount = recipeKeeper.Count()
-	}
+	// The time window is counted on its own, so an invalid one only leaves
+	// its count out of the output
+	window := request
+	request.Postcode, request.From, request.To = "", "", ""
 
-	recipesFound := recipeNameSlicesKeeper.GetSome(namesToSearch)
-	recipesFoundNames := []string{}
-	recipesFoundCounts := []reporters.CountPerRecipe{}
-	for _, recipe := range recipesFound {
-		recipesFoundNames = append(recipesFoundNames, recipe.Recipe)
-		recipesFoundCounts = append(recipesFoundCounts, reporters.CountPerRecipe{Recipe: recipe.Recipe, Count: recipe.Count})
+	jsonOutput := reporters.JSONReporter{}
+	stats, err := dataset.Stats(ctx, request)
+	if err == nil && window.Postcode != "" && window.From != "" && window.To != "" {
+		var count int
+		count, err = dataset.CountDeliveries(ctx, window.Postcode, window.From, window.To)
+		stats.Window = &recipestats.WindowCount{Postcode: window.Postcode, From: window.From, To: window.To, Count: count}
 	}
-	jsonOutput.MatchByName = recipesFoundNames
-	jsonOutput.CountPerRecipe = recipesFoundCounts
-
-	jsonOutput.BusiestPostCode = &reporters.BusiestPostCode{
-		Postcode:      deliveryKeeper.BusiestPostcode.Code,
-		DeliveryCount: deliveryKeeper.BusiestPostcode.Count,
+	if stats != nil {
+		jsonOutput = reporters.NewJSONReporter(stats)
 	}
-
-	countByPostcode := deliveryKeeper.CountByInterval(postcodeToSearch, from, to)
-	if countByPostcode > 0 {
-		jsonOutput.CountPerPostcodeAndTime = &reporters.CountPerPostcodeAndTime{
-			From:          from,
-			To:            to,
-			Postcode:      postcodeToSearch,
-			DeliveryCount: countByPostcode,
-		}
+	if err != nil {
+		observer.Logger.Error("It was impossible to calculate the stats", "error", err)
 	}
 
 	formattedOutput, err := jsonOutput.Marshal()
 
 	if err != nil {
-		if verbose {
-			fmt.Fprintf(os.Stderr, "It was impossible to format the output. The error was:%s\n", err.Error())
-		}
+		observer.Logger.Error("It was impossible to format the output", "error", err)
 	}
 
 	fmt.Fprintln(os.Stdout, formattedOutput)
 
-	if verbose {
-		fmt.Fprintf(os.Stderr, "Calculating took %s\n", time.Since(start))
-	}
+	finished()
 }
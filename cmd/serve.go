@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"recipe-stats/metrics"
+	"recipe-stats/recipestats"
+	"recipe-stats/server"
+
+	"github.com/spf13/cobra"
+)
+
+// serveCmd loads the input file once and answers the stats over HTTP, along
+// with the metrics of the loads and the queries for Prometheus.
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serves the stats of the loaded dataset over HTTP, along with Prometheus metrics.",
+	Long: `Loads the input file or snapshot once and serves its stats over HTTP until the process is stopped.
+
+Endpoints:
+  GET  /stats     the stats of the flag mode, in the same JSON output, asked for with the
+                  count, search, postcode, from and to query parameters
+  POST /reload    loads the file again, keeping the previous dataset if that fails
+  GET  /metrics   the load phases, records, keeper sizes, last reload and query latencies
+                  in the Prometheus text format
+
+Example: recipe-stats serve -f data.json --listen :8080
+`,
+	Args:          cobra.NoArgs,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		filePath, _ := cmd.Flags().GetString("file")
+		listen, _ := cmd.Flags().GetString("listen")
+
+		recipesAdapter, err := getRecordsAdapter(cmd)
+		if err != nil {
+			return err
+		}
+
+		telemetry, err := getTelemetry(cmd)
+		if err != nil {
+			return err
+		}
+
+		prometheus := metrics.NewPrometheus()
+		observer := telemetry.Observer
+		observer.Metrics = prometheus
+		load := func(ctx context.Context) (*recipestats.Dataset, error) {
+			return openDataset(ctx, filePath, recipesAdapter, nil, nil, observer)
+		}
+
+		statsServer := server.New(load, prometheus)
+		if err := statsServer.Reload(context.Background()); err != nil {
+			return err
+		}
+
+		fmt.Fprintf(os.Stderr, "Serving the stats of %s on %s\n", filePath, listen)
+		return http.ListenAndServe(listen, statsServer)
+	},
+}
+
+func init() {
+	serveCmd.Flags().String("listen", ":8080", "The address to serve the stats and the metrics on")
+	rootCmd.AddCommand(serveCmd)
+}
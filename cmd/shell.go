@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"recipe-stats/recipestats"
+	"recipe-stats/shell"
+
+	"github.com/AlecAivazis/survey/v2/terminal"
+	"github.com/spf13/cobra"
+)
+
+// shellCmd loads the input file once and keeps reading commands against it.
+var shellCmd = &cobra.Command{
+	Use:   "shell",
+	Short: "Runs a shell to query the loaded dataset, with tab-completion.",
+	Long: `Loads the input file once and runs a shell where commands are executed against it. Press Tab to complete commands, recipe name words and postcodes.
+
+Commands:
+  count                       counts the unique recipes
+  search <word> [<word>...]   finds the recipes with any of the words in their names
+  postcode <code> <from> <to> counts the deliveries to a postcode within a time window
+  top [<n>]                   lists the n busiest postcodes, 10 by default
+  help                        lists the commands
+  exit                        leaves the shell, as does Ctrl+D
+
+Example: recipe-stats shell -f data.json
+`,
+	Args:          cobra.NoArgs,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		filePath, _ := cmd.Flags().GetString("file")
+
+		recipesAdapter, err := getRecordsAdapter(cmd)
+		if err != nil {
+			return err
+		}
+
+		telemetry, err := getTelemetry(cmd)
+		if err != nil {
+			return err
+		}
+
+		var dataset *recipestats.Dataset
+		telemetry.profile(profileLoad, func() {
+			dataset, err = openDataset(context.Background(), filePath, recipesAdapter, nil, nil, telemetry.Observer)
+		})
+		if err != nil {
+			return err
+		}
+		if dataset.RejectedRecords() > 0 {
+			fmt.Fprintf(os.Stderr, "%d records were rejected\n", dataset.RejectedRecords())
+		}
+
+		telemetry.summary()
+
+		fmt.Println("Type help to list the commands, Tab completes them.")
+		recipeKeeper, recipeNameSlicesKeeper, deliveryKeeper := dataset.Keepers()
+		session := shell.NewSession(recipeKeeper, recipeNameSlicesKeeper, deliveryKeeper, os.Stdout)
+		return shell.Run(session, terminal.Stdio{In: os.Stdin, Out: os.Stdout, Err: os.Stderr})
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(shellCmd)
+}
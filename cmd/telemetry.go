@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"recipe-stats/loaders"
+	"recipe-stats/logging"
+	"recipe-stats/metrics"
+	"runtime"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// telemetry is where a command reports what it is doing: the observer handed
+// to the loaders, along with the collector of its metrics for the final
+// summary and the profiler of its phases.
+type telemetry struct {
+	loaders.Observer
+	profiler
+	collector *metrics.Collector
+	start     time.Time
+}
+
+// getTelemetry builds the telemetry of a command from the --verbose,
+// --log-format and --log-level flags, along with the profiling ones. Nothing
+// is logged unless --verbose is set or --log-level is changed.
+func getTelemetry(cmd *cobra.Command) (telemetry, error) {
+	verbose, _ := cmd.Flags().GetBool("verbose")
+	format, _ := cmd.Flags().GetString("log-format")
+	levelName, _ := cmd.Flags().GetString("log-level")
+	cpuPath, _ := cmd.Flags().GetString("cpuprofile")
+	memPath, _ := cmd.Flags().GetString("memprofile")
+	tracePath, _ := cmd.Flags().GetString("trace")
+
+	level, err := logging.ParseLevel(levelName)
+	if err != nil {
+		return telemetry{}, err
+	}
+
+	var handler logging.Handler
+	switch format {
+	case "text":
+		handler = logging.NewTextHandler(os.Stderr, level)
+	case "json":
+		handler = logging.NewJSONHandler(os.Stderr, level)
+	default:
+		return telemetry{}, fmt.Errorf("unknown log format %q, use text or json", format)
+	}
+
+	collector := metrics.NewCollector()
+	result := telemetry{
+		Observer:  loaders.Observer{Metrics: collector},
+		profiler:  profiler{cpuPath: cpuPath, memPath: memPath, tracePath: tracePath},
+		collector: collector,
+		start:     time.Now(),
+	}
+	if verbose || cmd.Flags().Changed("log-level") {
+		result.Logger = logging.New(handler)
+	}
+
+	return result, nil
+}
+
+// summary logs how long every phase took since the telemetry was built, along
+// with the records and bytes read and the records rejected, and how much
+// memory was used.
+func (t telemetry) summary() {
+	total := time.Since(t.start)
+	t.collector.Phase(metrics.PhaseTotal, total)
+
+	collected := t.collector.Summary()
+	args := []interface{}{"records", collected.Records, "bytes", collected.BytesRead, "rejected", collected.Rejects}
+	for _, phase := range collected.Phases {
+		args = append(args, phase.Name, phase.Duration)
+	}
+
+	t.Logger.Info("Timing summary", args...)
+
+	if t.Logger.Enabled(logging.LevelInfo) {
+		memory := runtime.MemStats{}
+		runtime.ReadMemStats(&memory)
+		t.Logger.Info("Memory summary", "peak_rss", peakRSS(), "total_alloc", memory.TotalAlloc, "mallocs", memory.Mallocs, "heap_inuse", memory.HeapInuse, "gc_cycles", memory.NumGC)
+	}
+}
@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"recipe-stats/adapters"
+	"recipe-stats/loaders"
+	"recipe-stats/metrics"
+	"recipe-stats/reporters"
+	"recipe-stats/snapshots"
+
+	"github.com/spf13/cobra"
+)
+
+// Exit codes of the validate command, so ingestion jobs can gate on them.
+const (
+	validateExitInvalidFile    = 1
+	validateExitInvalidRecords = 2
+)
+
+// errSnapshotNotValidatable is returned when validating a snapshot, since the
+// records it was built from are not kept.
+var errSnapshotNotValidatable = errors.New("snapshots can't be validated since they don't keep the records, please validate the input JSON file")
+
+// validateCmd checks every record of the input file and prints a data-quality
+// report.
+var validateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Checks every record of the input file and prints a data-quality report.",
+	Long: `Checks every record of the input file with strict rules and prints a data-quality report, with the count of problems per class and samples of the offending records along with their byte offsets.
+
+The problems checked are: malformed records, empty recipe names, postcodes that aren't 5 digits, unknown weekdays, invalid times and delivery windows that end before they start.
+Records are read with the schema of the config file, and deliveries are parsed with the configured parsing policy, see --parse-policy.
+
+Exit codes:
+  0  every record is valid
+  1  the file can't be read, is a snapshot or is not an array of records
+  2  at least one record is invalid
+
+Example: recipe-stats validate -f data.json --samples 5
+`,
+	Args:          cobra.NoArgs,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		filePath, _ := cmd.Flags().GetString("file")
+		samples, _ := cmd.Flags().GetInt("samples")
+
+		recipesAdapter, err := getRecordsAdapter(cmd)
+		if err != nil {
+			return &exitError{code: validateExitInvalidFile, err: err}
+		}
+
+		telemetry, err := getTelemetry(cmd)
+		if err != nil {
+			return &exitError{code: validateExitInvalidFile, err: err}
+		}
+
+		var report *reporters.ValidationReporter
+		telemetry.profile(profileValidate, func() {
+			report, err = validate(filePath, recipesAdapter, samples, telemetry.Observer)
+		})
+		if err != nil {
+			return &exitError{code: validateExitInvalidFile, err: err}
+		}
+
+		formattedOutput, err := report.Marshal()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(os.Stdout, formattedOutput)
+		telemetry.summary()
+
+		if report.InvalidRecords > 0 {
+			return &exitError{code: validateExitInvalidRecords, err: fmt.Errorf("%d of %d records are invalid", report.InvalidRecords, report.TotalRecords)}
+		}
+
+		return nil
+	},
+}
+
+// validate runs the checks of recipesAdapter over the file at filePath and
+// builds the report, keeping up to samples offending records per error class.
+// It reports on the validation to observer.
+func validate(filePath string, recipesAdapter adapters.RecordsAdapter, samples int, observer loaders.Observer) (*reporters.ValidationReporter, error) {
+	if snapshots.IsSnapshot(filePath) {
+		return nil, errSnapshotNotValidatable
+	}
+
+	finished := observer.Phase(metrics.PhaseValidate, "Validating recipes file")
+	observer.Logger.Debug("Parsing policy", "policy", recipesAdapter.ParsePolicy())
+
+	report := &reporters.ValidationReporter{ErrorsPerClass: map[string]int{}}
+	lastInvalidIndex := -1
+
+	total, err := recipesAdapter.Validate(filePath, func(recordError *adapters.RecordError) {
+		if recordError.Index != lastInvalidIndex {
+			lastInvalidIndex = recordError.Index
+			report.InvalidRecords++
+		}
+
+		report.ErrorsPerClass[recordError.Class]++
+		if report.ErrorsPerClass[recordError.Class] <= samples {
+			report.Samples = append(report.Samples, reporters.ValidationSample{
+				Class:  recordError.Class,
+				Index:  recordError.Index,
+				Offset: recordError.Offset,
+				Reason: recordError.Reason,
+				Record: string(recordError.Raw),
+			})
+		}
+	})
+	if err != nil {
+		observer.Logger.Error("It was impossible to validate the input file", "error", err)
+		return nil, err
+	}
+
+	report.TotalRecords = total
+	report.ValidRecords = total - report.InvalidRecords
+
+	if observer.Metrics != nil {
+		observer.Metrics.Records(total)
+		observer.Metrics.Rejects(report.InvalidRecords)
+	}
+	finished("records", total, "invalid", report.InvalidRecords)
+
+	return report, nil
+}
+
+func init() {
+	validateCmd.Flags().Int("samples", 3, "How many offending records to show per error class")
+
+	rootCmd.AddCommand(validateCmd)
+}
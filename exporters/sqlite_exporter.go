@@ -0,0 +1,163 @@
+package exporters
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"recipe-stats/adapters"
+	"recipe-stats/keepers"
+
+	// registers the "sqlite3" driver for database/sql
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// SQLiteExporter writes the normalised dataset into a SQLite database, so it
+// can be queried with plain SQL by any tool. Recipes and postcodes are stored
+// once each and referenced by the deliveries.
+type SQLiteExporter struct {
+	filePath string
+}
+
+// sqliteSchema is the structure created on every export. delivery_details is
+// a convenience view that joins everything back together.
+const sqliteSchema = `
+CREATE TABLE recipes (
+	id   INTEGER PRIMARY KEY,
+	name TEXT NOT NULL UNIQUE
+);
+
+CREATE TABLE postcodes (
+	id   INTEGER PRIMARY KEY,
+	code TEXT NOT NULL UNIQUE
+);
+
+CREATE TABLE deliveries (
+	id          INTEGER PRIMARY KEY,
+	recipe_id   INTEGER NOT NULL REFERENCES recipes (id),
+	postcode_id INTEGER NOT NULL REFERENCES postcodes (id),
+	weekday     TEXT NOT NULL,
+	from_hour   INTEGER NOT NULL,
+	to_hour     INTEGER NOT NULL
+);
+
+CREATE VIEW delivery_details AS
+	SELECT d.id, r.name AS recipe, p.code AS postcode, d.weekday, d.from_hour, d.to_hour
+	FROM deliveries d
+	JOIN recipes r ON r.id = d.recipe_id
+	JOIN postcodes p ON p.id = d.postcode_id;
+`
+
+// sqliteIndexes are created after the data is inserted, since building them
+// once is much faster than keeping them up to date on every insert.
+const sqliteIndexes = `
+CREATE INDEX deliveries_recipe_id ON deliveries (recipe_id);
+CREATE INDEX deliveries_postcode_window ON deliveries (postcode_id, from_hour, to_hour);
+CREATE INDEX deliveries_weekday ON deliveries (weekday);
+`
+
+// NewSQLiteExporter provides a usable instance of SQLiteExporter that writes
+// into the database file at filePath. Any existing file is replaced.
+func NewSQLiteExporter(filePath string) SQLiteExporter {
+	return SQLiteExporter{filePath: filePath}
+}
+
+// Export writes every recipe record into the database within a single
+// transaction.
+func (e *SQLiteExporter) Export(recipes []adapters.GeneralRecipe) error {
+	return e.ExportContext(context.Background(), recipes)
+}
+
+// ExportContext works like Export, rolling the transaction back and giving up
+// with the error of ctx as soon as it is canceled.
+func (e *SQLiteExporter) ExportContext(ctx context.Context, recipes []adapters.GeneralRecipe) error {
+	if err := os.Remove(e.filePath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	db, err := sql.Open("sqlite3", e.filePath)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if _, err := db.ExecContext(ctx, sqliteSchema); err != nil {
+		return err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := insertRecipes(ctx, tx, recipes); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	_, err = db.ExecContext(ctx, sqliteIndexes)
+	return err
+}
+
+// insertRecipes inserts the records, assigning ids to recipes and postcodes
+// the first time they are seen.
+func insertRecipes(ctx context.Context, tx *sql.Tx, recipes []adapters.GeneralRecipe) error {
+	insertRecipe, err := tx.Prepare("INSERT INTO recipes (id, name) VALUES (?, ?)")
+	if err != nil {
+		return err
+	}
+	defer insertRecipe.Close()
+
+	insertPostcode, err := tx.Prepare("INSERT INTO postcodes (id, code) VALUES (?, ?)")
+	if err != nil {
+		return err
+	}
+	defer insertPostcode.Close()
+
+	insertDelivery, err := tx.Prepare("INSERT INTO deliveries (recipe_id, postcode_id, weekday, from_hour, to_hour) VALUES (?, ?, ?, ?, ?)")
+	if err != nil {
+		return err
+	}
+	defer insertDelivery.Close()
+
+	recipeIDs := map[string]int{}
+	postcodeIDs := map[string]int{}
+
+	for i := 0; i < len(recipes); i++ {
+		if i%keepers.ContextBatch == 0 {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+		}
+		recipe := recipes[i].ToRecipe()
+		delivery := recipes[i].ToDelivery()
+
+		recipeID, found := recipeIDs[recipe.Recipe]
+		if !found {
+			recipeID = len(recipeIDs) + 1
+			recipeIDs[recipe.Recipe] = recipeID
+			if _, err := insertRecipe.Exec(recipeID, recipe.Recipe); err != nil {
+				return err
+			}
+		}
+
+		postcodeID, found := postcodeIDs[delivery.Postcode]
+		if !found {
+			postcodeID = len(postcodeIDs) + 1
+			postcodeIDs[delivery.Postcode] = postcodeID
+			if _, err := insertPostcode.Exec(postcodeID, delivery.Postcode); err != nil {
+				return err
+			}
+		}
+
+		_, err := insertDelivery.Exec(recipeID, postcodeID, recipes[i].Delivery.Weekday, delivery.From, delivery.To)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,183 @@
+// Package generator writes synthetic input files in the default format, so the
+// loading and the calculations can be measured at any size. The same Config,
+// seed included, always produces the same file.
+package generator
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"strconv"
+)
+
+// The distributions of the postcodes.
+const (
+	// PostcodesUniform gives every postcode about the same deliveries
+	PostcodesUniform = "uniform"
+	// PostcodesZipf gives a few postcodes most of the deliveries, the way
+	// dense areas do
+	PostcodesZipf = "zipf"
+)
+
+// The distributions of the delivery windows.
+const (
+	// WindowsUniform starts and ends the windows at any hour of the day
+	WindowsUniform = "uniform"
+	// WindowsBusiness starts the windows in the morning and ends them 2 to 8
+	// hours later
+	WindowsBusiness = "business"
+)
+
+// firstPostcode is the lowest postcode generated, so every one has 5 digits
+const firstPostcode = 10000
+
+// MaxPostcodes is how many distinct 5 digit postcodes can be generated.
+const MaxPostcodes = 100000 - firstPostcode
+
+// Config describes the file to generate.
+type Config struct {
+	// Seed makes the same Config produce the same file
+	Seed int64
+	// Records is how many records the file has
+	Records int
+	// Recipes is how many distinct recipe names are used, at most MaxRecipes
+	Recipes int
+	// Postcodes is how many distinct postcodes are used, at most MaxPostcodes
+	Postcodes int
+	// PostcodeDistribution is either PostcodesUniform or PostcodesZipf
+	PostcodeDistribution string
+	// WindowDistribution is either WindowsUniform or WindowsBusiness
+	WindowDistribution string
+}
+
+// DefaultConfig is a small file with about as many recipes as the sample data.
+func DefaultConfig() Config {
+	return Config{
+		Seed:                 1,
+		Records:              100000,
+		Recipes:              30,
+		Postcodes:            100,
+		PostcodeDistribution: PostcodesUniform,
+		WindowDistribution:   WindowsUniform,
+	}
+}
+
+// Validate tells what is wrong with the config, if anything.
+func (c Config) Validate() error {
+	switch {
+	case c.Records < 0:
+		return fmt.Errorf("the number of records can't be negative, got %d", c.Records)
+	case c.Recipes < 1 || c.Recipes > MaxRecipes:
+		return fmt.Errorf("the number of recipes must be between 1 and %d, got %d", MaxRecipes, c.Recipes)
+	case c.Postcodes < 1 || c.Postcodes > MaxPostcodes:
+		return fmt.Errorf("the number of postcodes must be between 1 and %d, got %d", MaxPostcodes, c.Postcodes)
+	case c.PostcodeDistribution != PostcodesUniform && c.PostcodeDistribution != PostcodesZipf:
+		return fmt.Errorf("unknown postcode distribution %q, use %s or %s", c.PostcodeDistribution, PostcodesUniform, PostcodesZipf)
+	case c.WindowDistribution != WindowsUniform && c.WindowDistribution != WindowsBusiness:
+		return fmt.Errorf("unknown window distribution %q, use %s or %s", c.WindowDistribution, WindowsUniform, WindowsBusiness)
+	}
+
+	return nil
+}
+
+// Generate writes the JSON array of records described by config to w.
+func Generate(w io.Writer, config Config) error {
+	return GenerateContext(context.Background(), w, config)
+}
+
+// GenerateContext works like Generate, giving up with the error of ctx as soon
+// as it is canceled.
+func GenerateContext(ctx context.Context, w io.Writer, config Config) error {
+	if err := config.Validate(); err != nil {
+		return err
+	}
+
+	random := rand.New(rand.NewSource(config.Seed))
+	recipes := recipeNames(random, config.Recipes)
+	postcode := postcodePicker(random, config)
+
+	out := bufio.NewWriter(w)
+	out.WriteString("[")
+	line := make([]byte, 0, 128)
+	for i := 0; i < config.Records; i++ {
+		if i%checkEvery == 0 {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+		}
+
+		from, to := window(random, config.WindowDistribution)
+
+		line = line[:0]
+		if i > 0 {
+			line = append(line, ",\n"...)
+		}
+		line = append(line, `{ "postcode": "`...)
+		line = strconv.AppendInt(line, int64(firstPostcode+postcode()), 10)
+		line = append(line, `", "recipe": "`...)
+		line = append(line, recipes[random.Intn(len(recipes))]...)
+		line = append(line, `", "delivery": "`...)
+		line = append(line, weekdays[random.Intn(len(weekdays))]...)
+		line = append(line, ' ')
+		line = appendHour(line, from)
+		line = append(line, " - "...)
+		line = appendHour(line, to)
+		line = append(line, `" }`...)
+
+		if _, err := out.Write(line); err != nil {
+			return err
+		}
+	}
+	out.WriteString("]\n")
+
+	return out.Flush()
+}
+
+// checkEvery is how many records are written between checks of the context
+const checkEvery = 4096
+
+var weekdays = []string{"Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday", "Sunday"}
+
+// postcodePicker returns the function picking the index of the postcode of
+// every record
+func postcodePicker(random *rand.Rand, config Config) func() int {
+	if config.PostcodeDistribution == PostcodesZipf && config.Postcodes > 1 {
+		zipf := rand.NewZipf(random, 1.1, 1, uint64(config.Postcodes-1))
+		return func() int {
+			return int(zipf.Uint64())
+		}
+	}
+
+	return func() int {
+		return random.Intn(config.Postcodes)
+	}
+}
+
+// window picks the from and to hours of a delivery, from always before to
+func window(random *rand.Rand, distribution string) (int, int) {
+	if distribution == WindowsBusiness {
+		from := 6 + random.Intn(7)
+		return from, from + 2 + random.Intn(7)
+	}
+
+	from := random.Intn(23)
+	return from, from + 1 + random.Intn(23-from)
+}
+
+// appendHour appends hour in the 12h format of the delivery strings, such as
+// 12AM, 8AM or 2PM
+func appendHour(line []byte, hour int) []byte {
+	suffix := "AM"
+	if hour >= 12 {
+		suffix = "PM"
+	}
+
+	hour %= 12
+	if hour == 0 {
+		hour = 12
+	}
+
+	return append(strconv.AppendInt(line, int64(hour), 10), suffix...)
+}
@@ -0,0 +1,37 @@
+package generator
+
+import "math/rand"
+
+// The words recipe names are made of, a flavour, a main ingredient and a dish,
+// such as "Creamy Chicken Tacos", so searching by a word finds many recipes.
+var (
+	flavours = [...]string{
+		"Creamy", "Spicy", "Smoky", "Garlic", "Honey", "Lemon", "Sweet", "Crispy",
+		"Grilled", "Cheesy", "Herby", "Zesty", "Roasted", "Speedy", "Sticky", "Tangy",
+	}
+	ingredients = [...]string{
+		"Chicken", "Steak", "Pork", "Salmon", "Shrimp", "Tofu", "Veggie", "Mushroom",
+		"Beef", "Turkey", "Halloumi", "Chickpea", "Lentil", "Sausage", "Cod", "Lamb",
+	}
+	dishes = [...]string{
+		"Tacos", "Burgers", "Risotto", "Curry", "Fajitas", "Bowls", "Pasta", "Pizzas",
+		"Stir-Fry", "Salad", "Skewers", "Quesadillas", "Ramen", "Chili", "Flatbreads", "Stew",
+	}
+)
+
+// MaxRecipes is how many distinct recipe names can be generated.
+const MaxRecipes = len(flavours) * len(ingredients) * len(dishes)
+
+// recipeNames picks count distinct recipe names
+func recipeNames(random *rand.Rand, count int) []string {
+	names := make([]string, 0, count)
+	for _, combination := range random.Perm(MaxRecipes)[:count] {
+		flavour := combination % len(flavours)
+		ingredient := combination / len(flavours) % len(ingredients)
+		dish := combination / len(flavours) / len(ingredients)
+
+		names = append(names, flavours[flavour]+" "+ingredients[ingredient]+" "+dishes[dish])
+	}
+
+	return names
+}
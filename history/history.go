@@ -0,0 +1,197 @@
+package history
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// MaxEntries is how many past queries are kept in the history, the oldest ones
+// are dropped first.
+const MaxEntries = 50
+
+// ErrQueryNotFound is returned when there is no saved query with a given name.
+var ErrQueryNotFound = errors.New("saved query not found")
+
+// Query holds the answers of an interactive run, so it can be run again.
+type Query struct {
+	FilePath    string   `json:"file_path"`
+	RecipeCount bool     `json:"recipe_count,omitempty"`
+	Names       []string `json:"names,omitempty"`
+	Postcode    string   `json:"postcode,omitempty"`
+	From        string   `json:"from,omitempty"`
+	To          string   `json:"to,omitempty"`
+}
+
+// Entry is a query of the history, along with when it ran.
+type Entry struct {
+	Query
+	RanAt time.Time `json:"ran_at"`
+}
+
+// String describes the query in a single line, such as
+// "data.json: count, search Pasta,Cheese, postcode 10120 9AM-2PM".
+func (q Query) String() string {
+	parts := []string{}
+	if q.RecipeCount {
+		parts = append(parts, "count")
+	}
+	if len(q.Names) > 0 {
+		parts = append(parts, "search "+strings.Join(q.Names, ","))
+	}
+	if q.Postcode != "" {
+		parts = append(parts, fmt.Sprintf("postcode %s %s-%s", q.Postcode, q.From, q.To))
+	}
+	if len(parts) == 0 {
+		parts = append(parts, "busiest postcode")
+	}
+
+	return fmt.Sprintf("%s: %s", filepath.Base(q.FilePath), strings.Join(parts, ", "))
+}
+
+// file is the content of the history file
+type file struct {
+	History []Entry          `json:"history"`
+	Saved   map[string]Query `json:"saved"`
+}
+
+// Store keeps the history of queries and the saved ones in a JSON file. A
+// missing file is the same as an empty history.
+type Store struct {
+	filePath string
+}
+
+// NewStore provides a usable instance of Store, backed by the file at filePath
+func NewStore(filePath string) Store {
+	return Store{filePath: filePath}
+}
+
+// History returns the past queries, the most recent first.
+func (s Store) History() ([]Entry, error) {
+	content, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]Entry, len(content.History))
+	for i, entry := range content.History {
+		entries[len(entries)-1-i] = entry
+	}
+
+	return entries, nil
+}
+
+// Add records query as the most recent entry of the history. A previous entry
+// with the same query is moved up instead of repeated.
+func (s Store) Add(query Query) error {
+	content, err := s.read()
+	if err != nil {
+		return err
+	}
+
+	entries := []Entry{}
+	for _, entry := range content.History {
+		if !reflect.DeepEqual(entry.Query, query) {
+			entries = append(entries, entry)
+		}
+	}
+	entries = append(entries, Entry{Query: query, RanAt: time.Now()})
+	if len(entries) > MaxEntries {
+		entries = entries[len(entries)-MaxEntries:]
+	}
+	content.History = entries
+
+	return s.write(content)
+}
+
+// Save keeps query under name, replacing any query saved with the same name.
+func (s Store) Save(name string, query Query) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return fmt.Errorf("the name of a saved query can't be empty")
+	}
+
+	content, err := s.read()
+	if err != nil {
+		return err
+	}
+	content.Saved[name] = query
+
+	return s.write(content)
+}
+
+// Saved returns the query saved under name, or ErrQueryNotFound.
+func (s Store) Saved(name string) (Query, error) {
+	content, err := s.read()
+	if err != nil {
+		return Query{}, err
+	}
+
+	query, found := content.Saved[name]
+	if !found {
+		return Query{}, ErrQueryNotFound
+	}
+
+	return query, nil
+}
+
+// SavedNames returns the names of the saved queries, sorted.
+func (s Store) SavedNames() ([]string, error) {
+	content, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+
+	names := []string{}
+	for name := range content.Saved {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names, nil
+}
+
+func (s Store) read() (file, error) {
+	content := file{}
+
+	data, err := ioutil.ReadFile(s.filePath)
+	if os.IsNotExist(err) {
+		content.Saved = map[string]Query{}
+		return content, nil
+	}
+	if err != nil {
+		return content, err
+	}
+
+	if err := json.Unmarshal(data, &content); err != nil {
+		return content, fmt.Errorf("invalid history file %s: %w", s.filePath, err)
+	}
+	if content.Saved == nil {
+		content.Saved = map[string]Query{}
+	}
+
+	return content, nil
+}
+
+// write replaces the history file through a temporary file, so it is never
+// left half written
+func (s Store) write(content file) error {
+	data, err := json.MarshalIndent(content, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tempPath := s.filePath + ".tmp"
+	if err := ioutil.WriteFile(tempPath, data, 0600); err != nil {
+		return err
+	}
+
+	return os.Rename(tempPath, s.filePath)
+}
@@ -0,0 +1,571 @@
+package interactive
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"recipe-stats/adapters"
+	"recipe-stats/history"
+	"recipe-stats/recipestats"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+)
+
+// State is a step of the interactive flow.
+type State int
+
+// The states of the interactive flow. Loading only starts the load, which goes
+// on in the background while the options are chosen, and ShowResults waits for
+// it to finish.
+const (
+	StateChooseFile State = iota
+	StateLoading
+	StateChooseOptions
+	StateShowResults
+	StateRunAgain
+	StateDone
+)
+
+func (s State) String() string {
+	switch s {
+	case StateChooseFile:
+		return "choose file"
+	case StateLoading:
+		return "loading"
+	case StateChooseOptions:
+		return "choose options"
+	case StateShowResults:
+		return "show results"
+	case StateRunAgain:
+		return "run again"
+	case StateDone:
+		return "done"
+	}
+
+	return fmt.Sprintf("State(%d)", int(s))
+}
+
+// Asker asks the questions of the flow. SurveyAsker asks them on the terminal,
+// while tests can script the answers. Validators are survey validators, nil
+// when any answer is accepted.
+type Asker interface {
+	Select(message string, options []string) (int, error)
+	MultiSelect(message string, options []string) ([]int, error)
+	Input(message string, validator func(answer interface{}) error) (string, error)
+	Confirm(message string, defaultAnswer bool) (bool, error)
+}
+
+// Loader loads the dataset from the file at filePath, reporting how far it went
+// to progress and giving up as soon as ctx is canceled.
+type Loader func(ctx context.Context, filePath string, progress func(adapters.Progress)) (*recipestats.Dataset, error)
+
+// Runner shows the results of query over dataset.
+type Runner func(dataset *recipestats.Dataset, query history.Query)
+
+// Config holds what the flow depends on.
+type Config struct {
+	Asker           Asker
+	Load            Loader
+	Run             Runner
+	BuiltInFilePath string
+	// History keeps the queries run, it is optional
+	History *history.Store
+	// Errors is where the problems found along the flow are shown
+	Errors io.Writer
+	// Progress is where the progress bar is drawn while waiting for a load,
+	// it is optional
+	Progress io.Writer
+}
+
+// Options of the questions, in the order they are shown.
+const (
+	OptionCustomFile  = "Inform custom file path"
+	OptionBuiltInFile = "Use built-in file"
+
+	OptionCount    = "Count unique recipes"
+	OptionSearch   = "Search by recipe name"
+	OptionPostcode = "Search by postcode and time window"
+
+	OptionNewQuery      = "Start a new query"
+	OptionPreviousQuery = "Re-run a previous query"
+	OptionSavedQuery    = "Run a saved query"
+)
+
+// Messages of the questions.
+const (
+	MessageStart         = "What do you want to do?"
+	MessagePreviousQuery = "Which query do you want to run again?"
+	MessageSavedQuery    = "Which saved query do you want to run?"
+	MessageFileOption    = "Which file do you want to use?"
+	MessageCustomFile    = "Inform the custom file path to use:"
+	MessageOptions       = "Select which options you want to use in order to check recipes stats:"
+	MessageRecipeNames   = "Inform a comma separated list of recipes name to search:"
+	MessagePostcode      = "Inform the desired postcode to search:"
+	MessageFrom          = "From what time to start searching? (Example: 9AM)"
+	MessageTo            = "Up to what time to end searching? (Example: 2PM)"
+	MessageSaveQuery     = "Save this query as (leave it empty to skip):"
+	MessageTryAgain      = "Do you want to try again?"
+	MessageRunAgain      = "Do you want to run again?"
+	MessageReuseDataset  = "Use the same dataset?"
+	MessageLoadCanceled  = "Do you want to choose another file?"
+)
+
+// progressInterval is how often the progress bar is redrawn
+const progressInterval = 100 * time.Millisecond
+
+var (
+	fileOptions = []string{OptionCustomFile, OptionBuiltInFile}
+	options     = []string{OptionCount, OptionSearch, OptionPostcode}
+)
+
+// loading is a load running in the background
+type loading struct {
+	done    chan struct{}
+	cancel  context.CancelFunc
+	dataset *recipestats.Dataset
+	err     error
+
+	mu       sync.Mutex
+	progress adapters.Progress
+}
+
+func (l *loading) report(progress adapters.Progress) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.progress = progress
+}
+
+func (l *loading) lastProgress() adapters.Progress {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.progress
+}
+
+// Flow is the interactive flow as a state machine, going from choosing a file
+// to showing the results as many times as asked, without recursion.
+type Flow struct {
+	config Config
+	state  State
+	ctx    context.Context
+
+	// mu guards loading, which CancelLoading reaches from other goroutines
+	mu      sync.Mutex
+	loading *loading
+
+	filePath string
+	replay   *history.Query
+	query    history.Query
+}
+
+// NewFlow provides a usable instance of Flow, in StateChooseFile
+func NewFlow(config Config) *Flow {
+	if config.Errors == nil {
+		config.Errors = ioutil.Discard
+	}
+
+	return &Flow{config: config, state: StateChooseFile, ctx: context.Background()}
+}
+
+// State returns the state the flow is in.
+func (f *Flow) State() State {
+	return f.state
+}
+
+// Run steps through the flow until it is done. It stops early with the error
+// of a question that couldn't be asked, such as when the user interrupts it.
+func (f *Flow) Run() error {
+	return f.RunContext(context.Background())
+}
+
+// RunContext works like Run, stopping with the error of ctx as soon as it is
+// canceled. Whatever is still loading when it returns is canceled.
+func (f *Flow) RunContext(ctx context.Context) error {
+	f.ctx = ctx
+	defer f.CancelLoading()
+
+	for f.state != StateDone {
+		if err := f.Step(); err != nil {
+			f.state = StateDone
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			f.state = StateDone
+			return err
+		}
+	}
+
+	return nil
+}
+
+// CancelLoading cancels the load running in the background, if any, such as
+// when Ctrl+C is pressed while waiting for it. The flow then offers choosing
+// another file. It is safe to call from other goroutines.
+func (f *Flow) CancelLoading() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.loading != nil {
+		f.loading.cancel()
+	}
+}
+
+// Step runs the current state and moves the flow to the next one.
+func (f *Flow) Step() error {
+	var next State
+	var err error
+
+	switch f.state {
+	case StateChooseFile:
+		next, err = f.chooseFile()
+	case StateLoading:
+		next = f.startLoading()
+	case StateChooseOptions:
+		next, err = f.chooseOptions()
+	case StateShowResults:
+		next, err = f.showResults()
+	case StateRunAgain:
+		next, err = f.runAgain()
+	default:
+		next = StateDone
+	}
+	if err != nil {
+		return err
+	}
+
+	f.state = next
+	return nil
+}
+
+func (f *Flow) chooseFile() (State, error) {
+	f.replay = nil
+
+	replay, err := f.askPreviousQuery()
+	if err != nil {
+		return StateDone, err
+	}
+	if replay != nil {
+		if err := ValidateFilePath(replay.FilePath); err != nil {
+			fmt.Fprintf(f.config.Errors, "The query can't run again, %s\n", err.Error())
+		} else {
+			f.replay = replay
+			f.filePath = replay.FilePath
+			return StateLoading, nil
+		}
+	}
+
+	chosen, err := f.config.Asker.Select(MessageFileOption, fileOptions)
+	if err != nil {
+		return StateDone, err
+	}
+
+	if fileOptions[chosen] == OptionBuiltInFile {
+		if err := ValidateFilePath(f.config.BuiltInFilePath); err == nil {
+			f.filePath = f.config.BuiltInFilePath
+			return StateLoading, nil
+		}
+		fmt.Fprintf(f.config.Errors, "The built-in file can't be used, %s\n", ValidateFilePath(f.config.BuiltInFilePath).Error())
+	}
+
+	filePath, err := f.config.Asker.Input(MessageCustomFile, ValidateFilePath)
+	if err != nil {
+		return StateDone, err
+	}
+	f.filePath = strings.TrimSpace(filePath)
+
+	return StateLoading, nil
+}
+
+// askPreviousQuery offers running one of the queries in the history or one of
+// the saved queries, if there are any. It returns nil for a new query.
+func (f *Flow) askPreviousQuery() (*history.Query, error) {
+	if f.config.History == nil {
+		return nil, nil
+	}
+
+	entries, err := f.config.History.History()
+	if err != nil {
+		fmt.Fprintf(f.config.Errors, "It was impossible to read the history file. The error was: %s\n", err.Error())
+		return nil, nil
+	}
+	savedNames, _ := f.config.History.SavedNames()
+	if len(entries) == 0 && len(savedNames) == 0 {
+		return nil, nil
+	}
+
+	startOptions := []string{OptionNewQuery}
+	if len(entries) > 0 {
+		startOptions = append(startOptions, OptionPreviousQuery)
+	}
+	if len(savedNames) > 0 {
+		startOptions = append(startOptions, OptionSavedQuery)
+	}
+
+	chosen, err := f.config.Asker.Select(MessageStart, startOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	switch startOptions[chosen] {
+	case OptionPreviousQuery:
+		descriptions := []string{}
+		for _, entry := range entries {
+			descriptions = append(descriptions, fmt.Sprintf("%s (%s)", entry.Query, entry.RanAt.Format("2006-01-02 15:04")))
+		}
+
+		chosen, err := f.config.Asker.Select(MessagePreviousQuery, descriptions)
+		if err != nil {
+			return nil, err
+		}
+		return &entries[chosen].Query, nil
+	case OptionSavedQuery:
+		chosen, err := f.config.Asker.Select(MessageSavedQuery, savedNames)
+		if err != nil {
+			return nil, err
+		}
+		query, err := f.config.History.Saved(savedNames[chosen])
+		if err != nil {
+			fmt.Fprintf(f.config.Errors, "It was impossible to read the saved query. The error was: %s\n", err.Error())
+			return nil, nil
+		}
+		return &query, nil
+	}
+
+	return nil, nil
+}
+
+// startLoading loads the dataset in the background, so it is ready by the time
+// the options are chosen
+func (f *Flow) startLoading() State {
+	// a previous load that is still running is of no use anymore
+	f.CancelLoading()
+
+	// force GC to free up memory to load large chunks again
+	f.mu.Lock()
+	f.loading = nil
+	f.mu.Unlock()
+	debug.FreeOSMemory()
+
+	ctx, cancel := context.WithCancel(f.ctx)
+	current := &loading{done: make(chan struct{}), cancel: cancel}
+	filePath := f.filePath
+	go func() {
+		defer close(current.done)
+		current.dataset, current.err = f.config.Load(ctx, filePath, current.report)
+	}()
+
+	f.mu.Lock()
+	f.loading = current
+	f.mu.Unlock()
+
+	return StateChooseOptions
+}
+
+// wait waits for the dataset being loaded
+func (f *Flow) wait() (*recipestats.Dataset, error) {
+	<-f.loading.done
+
+	return f.loading.dataset, f.loading.err
+}
+
+// waitWithProgress waits for the dataset being loaded, drawing a progress bar
+// meanwhile if it is not loaded yet
+func (f *Flow) waitWithProgress() (*recipestats.Dataset, error) {
+	current := f.loading
+	if f.config.Progress == nil {
+		return f.wait()
+	}
+
+	select {
+	case <-current.done:
+		return f.wait()
+	default:
+	}
+
+	ticker := time.NewTicker(progressInterval)
+	defer ticker.Stop()
+
+	for {
+		fmt.Fprintf(f.config.Progress, "\r\033[K%s", RenderProgress(current.lastProgress(), ProgressBarWidth))
+
+		select {
+		case <-current.done:
+			fmt.Fprint(f.config.Progress, "\r\033[K")
+			return f.wait()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (f *Flow) chooseOptions() (State, error) {
+	if f.replay != nil {
+		f.query = *f.replay
+		f.replay = nil
+		return StateShowResults, nil
+	}
+
+	chosen := []int{}
+	for len(chosen) == 0 {
+		var err error
+		chosen, err = f.config.Asker.MultiSelect(MessageOptions, options)
+		if err != nil {
+			return StateDone, err
+		}
+	}
+
+	query := history.Query{FilePath: f.filePath}
+	for _, option := range chosen {
+		switch options[option] {
+		case OptionCount:
+			query.RecipeCount = true
+		case OptionSearch:
+			names, err := f.config.Asker.Input(MessageRecipeNames, required)
+			if err != nil {
+				return StateDone, err
+			}
+			query.Names = splitNames(names)
+		case OptionPostcode:
+			// postcodes are only known once loading is done, so until then
+			// the answer is accepted as it is and checked in showResults
+			var postcodes []string
+			loadedPostcodes := func() []string {
+				select {
+				case <-f.loading.done:
+				default:
+					return nil
+				}
+				if dataset, err := f.wait(); postcodes == nil && err == nil && dataset != nil {
+					postcodes = dataset.Postcodes()
+				}
+				return postcodes
+			}
+
+			postcode, err := f.config.Asker.Input(MessagePostcode, ValidatePostcode(loadedPostcodes))
+			if err != nil {
+				return StateDone, err
+			}
+			from, err := f.config.Asker.Input(MessageFrom, ValidateTime)
+			if err != nil {
+				return StateDone, err
+			}
+			to, err := f.config.Asker.Input(MessageTo, ValidateWindowEnd(func() string { return from }))
+			if err != nil {
+				return StateDone, err
+			}
+
+			query.Postcode = strings.TrimSpace(postcode)
+			query.From, query.To = NormalizeTime(from), NormalizeTime(to)
+		}
+	}
+	f.query = query
+
+	return StateShowResults, nil
+}
+
+func (f *Flow) showResults() (State, error) {
+	dataset, err := f.waitWithProgress()
+	if ctxErr := f.ctx.Err(); ctxErr != nil {
+		return StateDone, ctxErr
+	}
+	if err == context.Canceled {
+		fmt.Fprintln(f.config.Errors, "Loading was canceled.")
+
+		chooseAnother, err := f.config.Asker.Confirm(MessageLoadCanceled, true)
+		if err != nil || !chooseAnother {
+			return StateDone, err
+		}
+		return StateChooseFile, nil
+	}
+	if err != nil {
+		fmt.Fprintf(f.config.Errors, "Something went wrong, please try again. Error:%s\n", err.Error())
+
+		tryAgain, err := f.config.Asker.Confirm(MessageTryAgain, false)
+		if err != nil || !tryAgain {
+			return StateDone, err
+		}
+		return StateChooseFile, nil
+	}
+
+	if f.query.Postcode != "" {
+		if err := ValidatePostcode(dataset.Postcodes)(f.query.Postcode); err != nil {
+			fmt.Fprintf(f.config.Errors, "The postcode can't be searched. The error was: %s\n", err.Error())
+			return StateChooseOptions, nil
+		}
+	}
+
+	f.config.Run(dataset, f.query)
+
+	return StateRunAgain, f.rememberQuery()
+}
+
+// rememberQuery adds the query to the history and offers saving it under a
+// name, so it can be run later on with `recipe-stats run <name>`.
+func (f *Flow) rememberQuery() error {
+	if f.config.History == nil {
+		return nil
+	}
+
+	query := f.query
+	if absolutePath, err := filepath.Abs(query.FilePath); err == nil {
+		query.FilePath = absolutePath
+	}
+
+	if err := f.config.History.Add(query); err != nil {
+		fmt.Fprintf(f.config.Errors, "It was impossible to write the history file. The error was: %s\n", err.Error())
+		return nil
+	}
+
+	name, err := f.config.Asker.Input(MessageSaveQuery, nil)
+	if err != nil || strings.TrimSpace(name) == "" {
+		return err
+	}
+	if err := f.config.History.Save(name, query); err != nil {
+		fmt.Fprintf(f.config.Errors, "It was impossible to save the query. The error was: %s\n", err.Error())
+	}
+
+	return nil
+}
+
+func (f *Flow) runAgain() (State, error) {
+	runAgain, err := f.config.Asker.Confirm(MessageRunAgain, false)
+	if err != nil || !runAgain {
+		return StateDone, err
+	}
+
+	reuseDataset, err := f.config.Asker.Confirm(MessageReuseDataset, true)
+	if err != nil {
+		return StateDone, err
+	}
+	if reuseDataset {
+		return StateChooseOptions, nil
+	}
+
+	return StateChooseFile, nil
+}
+
+// splitNames splits the comma separated recipe names of an answer, leaving out
+// the spaces around them and the empty ones
+func splitNames(answer string) []string {
+	names := []string{}
+	for _, name := range strings.Split(answer, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
+// required is a survey validator that doesn't accept empty answers
+func required(answer interface{}) error {
+	if strings.TrimSpace(fmt.Sprint(answer)) == "" {
+		return fmt.Errorf("value is required")
+	}
+
+	return nil
+}
@@ -0,0 +1,45 @@
+package interactive
+
+import (
+	"fmt"
+	"recipe-stats/adapters"
+	"strings"
+	"time"
+)
+
+// ProgressBarWidth is how many characters the bar itself takes.
+const ProgressBarWidth = 30
+
+// RenderProgress draws progress as a single line, such as
+//
+//	[=============>                ]  45% 120000 records/s ETA 3s
+//
+// While the file is being read, "reading" is shown instead of the records per
+// second. When the size of the input is unknown, as for snapshots, only the
+// records decoded so far are shown.
+func RenderProgress(progress adapters.Progress, width int) string {
+	if progress.Total <= 0 {
+		return fmt.Sprintf("Loading... %d records", progress.Records)
+	}
+
+	percent := progress.Percent()
+	filled := int(percent * float64(width) / 100)
+	if filled > width {
+		filled = width
+	}
+
+	bar := strings.Repeat("=", filled)
+	if filled < width {
+		bar += ">" + strings.Repeat(" ", width-filled-1)
+	}
+
+	line := fmt.Sprintf("[%s] %3.0f%% %.0f records/s", bar, percent, progress.RecordsPerSecond())
+	if progress.Reading {
+		line = fmt.Sprintf("[%s] %3.0f%% reading", bar, percent)
+	}
+	if eta := progress.ETA(); eta > 0 {
+		line += fmt.Sprintf(" ETA %s", eta.Round(time.Second))
+	}
+
+	return line
+}
@@ -0,0 +1,44 @@
+package interactive
+
+import (
+	"github.com/AlecAivazis/survey/v2"
+)
+
+// SurveyAsker asks the questions of the flow on the terminal.
+type SurveyAsker struct{}
+
+// Select asks to choose one of options, returning its index.
+func (SurveyAsker) Select(message string, options []string) (int, error) {
+	var chosen int
+	err := survey.AskOne(&survey.Select{Message: message, Options: options}, &chosen)
+
+	return chosen, err
+}
+
+// MultiSelect asks to choose at least one of options, returning their indexes.
+func (SurveyAsker) MultiSelect(message string, options []string) ([]int, error) {
+	chosen := []int{}
+	err := survey.AskOne(&survey.MultiSelect{Message: message, Options: options}, &chosen, survey.WithValidator(survey.Required))
+
+	return chosen, err
+}
+
+// Input asks for an answer accepted by validator.
+func (SurveyAsker) Input(message string, validator func(answer interface{}) error) (string, error) {
+	var answer string
+	opts := []survey.AskOpt{}
+	if validator != nil {
+		opts = append(opts, survey.WithValidator(validator))
+	}
+	err := survey.AskOne(&survey.Input{Message: message}, &answer, opts...)
+
+	return answer, err
+}
+
+// Confirm asks a yes or no question.
+func (SurveyAsker) Confirm(message string, defaultAnswer bool) (bool, error) {
+	answer := defaultAnswer
+	err := survey.AskOne(&survey.Confirm{Message: message, Default: defaultAnswer}, &answer)
+
+	return answer, err
+}
@@ -0,0 +1,175 @@
+package interactive
+
+import (
+	"fmt"
+	"os"
+	"recipe-stats/adapters"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// MaxSuggestions is how many postcodes are suggested when the one informed has
+// no deliveries.
+const MaxSuggestions = 5
+
+// timePolicy accepts the usual ways of typing a time, such as "9 am", since
+// every answer is normalized by NormalizeTime before being used
+var timePolicy = adapters.LenientParsePolicy()
+
+// ValidateFilePath is a survey validator that checks the answer is the path of
+// a readable file, so loading doesn't start with a wrong path.
+func ValidateFilePath(answer interface{}) error {
+	filePath := strings.TrimSpace(fmt.Sprint(answer))
+	if filePath == "" {
+		return fmt.Errorf("inform the path of the file")
+	}
+
+	file, err := os.Open(filePath)
+	if os.IsNotExist(err) {
+		return fmt.Errorf("the file %s doesn't exist", filePath)
+	}
+	if err != nil {
+		return fmt.Errorf("the file %s can't be read: %s", filePath, err.Error())
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("the file %s can't be read: %s", filePath, err.Error())
+	}
+	if info.IsDir() {
+		return fmt.Errorf("%s is a directory, inform the path of a file", filePath)
+	}
+
+	return nil
+}
+
+// ValidateTime is a survey validator that checks the answer is a 12h time,
+// such as 9AM.
+func ValidateTime(answer interface{}) error {
+	_, err := timePolicy.ParseHour(strings.TrimSpace(fmt.Sprint(answer)))
+	if err != nil {
+		return fmt.Errorf("%q is not a time, inform something like 9AM or 12PM", fmt.Sprint(answer))
+	}
+
+	return nil
+}
+
+// ValidateWindowEnd provides a survey validator that checks the answer is a
+// 12h time not before the one returned by from, the start of the window.
+func ValidateWindowEnd(from func() string) func(answer interface{}) error {
+	return func(answer interface{}) error {
+		if err := ValidateTime(answer); err != nil {
+			return err
+		}
+
+		start, err := timePolicy.ParseHour(strings.TrimSpace(from()))
+		if err != nil {
+			return nil
+		}
+		end, _ := timePolicy.ParseHour(strings.TrimSpace(fmt.Sprint(answer)))
+		if end < start {
+			return fmt.Errorf("the window must end after it starts at %s", NormalizeTime(from()))
+		}
+
+		return nil
+	}
+}
+
+// NormalizeTime turns a time accepted by ValidateTime into the format the
+// keepers work with, such as "9 am" into "9AM".
+func NormalizeTime(value string) string {
+	hour, err := timePolicy.ParseHour(strings.TrimSpace(value))
+	if err != nil {
+		return value
+	}
+
+	switch {
+	case hour == 0:
+		return "12AM"
+	case hour < 12:
+		return fmt.Sprintf("%dAM", hour)
+	case hour == 12:
+		return "12PM"
+	}
+
+	return fmt.Sprintf("%dPM", hour-12)
+}
+
+// ValidatePostcode provides a survey validator that checks the answer is one
+// of the postcodes returned by postcodes, suggesting the closest ones
+// otherwise. If postcodes returns nil, as when the dataset couldn't be loaded,
+// any answer is accepted.
+func ValidatePostcode(postcodes func() []string) func(answer interface{}) error {
+	return func(answer interface{}) error {
+		postcode := strings.TrimSpace(fmt.Sprint(answer))
+		if postcode == "" {
+			return fmt.Errorf("inform the postcode")
+		}
+
+		known := postcodes()
+		if known == nil {
+			return nil
+		}
+
+		index := sort.SearchStrings(known, postcode)
+		if index < len(known) && known[index] == postcode {
+			return nil
+		}
+
+		suggestions := SuggestPostcodes(known, postcode, MaxSuggestions)
+		if len(suggestions) == 0 {
+			return fmt.Errorf("there are no deliveries to %s", postcode)
+		}
+
+		return fmt.Errorf("there are no deliveries to %s, try %s", postcode, strings.Join(suggestions, ", "))
+	}
+}
+
+// SuggestPostcodes returns up to n postcodes of known that are the closest to
+// postcode: the ones sharing the longest prefix first and, among those, the
+// ones numerically closer.
+func SuggestPostcodes(known []string, postcode string, n int) []string {
+	type candidate struct {
+		code     string
+		prefix   int
+		distance int
+	}
+
+	number, numberErr := strconv.Atoi(postcode)
+	candidates := make([]candidate, 0, len(known))
+	for _, code := range known {
+		prefix := 0
+		for prefix < len(code) && prefix < len(postcode) && code[prefix] == postcode[prefix] {
+			prefix++
+		}
+
+		distance := 0
+		if codeNumber, err := strconv.Atoi(code); err == nil && numberErr == nil {
+			distance = codeNumber - number
+			if distance < 0 {
+				distance = -distance
+			}
+		}
+
+		candidates = append(candidates, candidate{code: code, prefix: prefix, distance: distance})
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].prefix != candidates[j].prefix {
+			return candidates[i].prefix > candidates[j].prefix
+		}
+		if candidates[i].distance != candidates[j].distance {
+			return candidates[i].distance < candidates[j].distance
+		}
+		return candidates[i].code < candidates[j].code
+	})
+
+	suggestions := []string{}
+	for i := 0; i < len(candidates) && i < n; i++ {
+		suggestions = append(suggestions, candidates[i].code)
+	}
+
+	return suggestions
+}
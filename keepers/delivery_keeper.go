@@ -1,20 +1,37 @@
 package keepers
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
 	"recipe-stats/models"
-	"strconv"
+	"sort"
+	"sync"
 )
 
-// postcode is the struct that internally holds all the deliveries within a time
-// range for a given postcode. It also keeps in account the count of deliveries
-// and the and the postcode number for the sake of searching later.
+// ErrInvalidTime is returned when a time is not in the 12h format, such as 8AM
+// or 12PM.
+var ErrInvalidTime = errors.New("invalid time")
+
+// postcode is the struct that internally holds how many deliveries were found
+// within every time range for a given postcode. It also keeps in account the
+// count of deliveries and the postcode number for the sake of searching later.
 type postcode struct {
 	Code string
-	// I am using an slice initialized with  all the possible time ranges (12AM to
-	// 12PM) so I don't need to sort later and I can also access time ranges
-	// instantly
-	Deliveries      [24][24][]models.Delivery
+	// I am using an array with all the possible time ranges (12AM to 12PM) so
+	// I don't need to sort later and I can also access time ranges instantly.
+	// Only the counts are kept, since a delivery is nothing but its postcode
+	// and time range, which makes a postcode take 2KB however many deliveries
+	// it has.
+	Counts          [24][24]uint32
 	DeliveriesCount int
+	// last is the position of its latest delivery among every delivery added
+	// to the keeper, which tells which postcode got to a count first
+	last int
+	// deliveries are the deliveries in the order they were added, only kept by
+	// keepers retaining them, all of them sharing Code as their postcode
+	deliveries []models.Delivery
 }
 
 // BusiestPostCode is the struct that serves as the base for the accounting of
@@ -26,95 +43,430 @@ type BusiestPostcode struct {
 
 // DeliveryKeeper is the main struct for the Delivery Keeper and holds reference
 // to all the required objects for the logic of controlling deliveries and the
-// busiest postcode.
+// busiest postcode. It is safe for concurrent use by multiple readers and
+// writers. Its zero value is an empty keeper that can be read, while
+// NewDeliveryKeeper provides one that deliveries can be added to.
 type DeliveryKeeper struct {
-	postcodes       map[string]*postcode
-	BusiestPostcode BusiestPostcode
+	mu        sync.RWMutex
+	postcodes map[string]*postcode
+	added     int
+	retain    bool
+	// busiest is the busiest postcode, read through GetBusiestPostcode
+	busiest BusiestPostcode
 }
 
-// NewDeliveryKeeper provides a usable instance of DeliveryKeeper.
+// NewDeliveryKeeper provides a usable instance of DeliveryKeeper, which only
+// counts the deliveries within every time range of every postcode.
 func NewDeliveryKeeper() DeliveryKeeper {
 	return DeliveryKeeper{
-		postcodes:       map[string]*postcode{},
-		BusiestPostcode: BusiestPostcode{},
+		postcodes: map[string]*postcode{},
+	}
+}
+
+// NewRetainingDeliveryKeeper provides a usable instance of DeliveryKeeper that
+// also keeps every delivery in the order they were added, for the callers of
+// Deliveries that need that order. It takes 32 more bytes per delivery.
+func NewRetainingDeliveryKeeper() DeliveryKeeper {
+	return DeliveryKeeper{
+		postcodes: map[string]*postcode{},
+		retain:    true,
 	}
 }
 
 // Add puts a new delivery on the list of deliveries taking its time range and
 // postcode into account. It also updates the busiest postcode if applicable.
 func (dk *DeliveryKeeper) Add(delivery models.Delivery) {
+	dk.mu.Lock()
+	defer dk.mu.Unlock()
+
 	// Mapping postcodes
 	foundPostcode, found := dk.postcodes[delivery.Postcode]
 	if found {
 		foundPostcode.DeliveriesCount++
 	} else {
 		foundPostcode = &postcode{
-			Code:            delivery.Postcode,
+			Code:            cloneString(delivery.Postcode),
 			DeliveriesCount: 1,
 		}
-		dk.postcodes[delivery.Postcode] = foundPostcode
+		dk.postcodes[foundPostcode.Code] = foundPostcode
 	}
 
-	// Mapping deliveries within time range
-	foundPostcode.Deliveries[delivery.From][delivery.To] =
-		append(foundPostcode.Deliveries[delivery.From][delivery.To],
-			delivery)
+	// Counting deliveries within time range
+	foundPostcode.Counts[delivery.From][delivery.To]++
+	if dk.retain {
+		delivery.Postcode = foundPostcode.Code
+		foundPostcode.deliveries = append(foundPostcode.deliveries, delivery)
+	}
+	dk.added++
+	foundPostcode.last = dk.added
 
-	if dk.BusiestPostcode.Count < foundPostcode.DeliveriesCount {
-		dk.BusiestPostcode.Code = foundPostcode.Code
-		dk.BusiestPostcode.Count = foundPostcode.DeliveriesCount
+	if dk.busiest.Count < foundPostcode.DeliveriesCount {
+		dk.busiest.Code = foundPostcode.Code
+		dk.busiest.Count = foundPostcode.DeliveriesCount
 	}
 }
 
+// cloneString copies code into memory of its own. The postcodes of the
+// records are slices of the whole input buffer, so keeping one as it is would
+// pin that buffer in memory for as long as the keeper lives.
+func cloneString(code string) string {
+	return string([]byte(code))
+}
+
+// Merge adds every delivery of other to the keeper, as if they were added
+// after its own, and recomputes the busiest postcode from the merged counts.
+// On ties it is the postcode that got to the count first, the same one Add
+// would have kept. When the keeper retains the deliveries and other doesn't,
+// the ones of other are retained by time range. other is left as it is, and
+// may be the keeper itself.
+func (dk *DeliveryKeeper) Merge(other *DeliveryKeeper) {
+	// other is copied first, so it doesn't need to be locked along with the
+	// keeper
+	other.mu.RLock()
+	merged := make([]postcode, 0, len(other.postcodes))
+	for _, otherPostcode := range other.postcodes {
+		copied := *otherPostcode
+		copied.deliveries = nil
+		if dk.retain {
+			copied.deliveries = otherPostcode.orderedDeliveries()
+		}
+		merged = append(merged, copied)
+	}
+	otherAdded := other.added
+	other.mu.RUnlock()
+
+	dk.mu.Lock()
+	defer dk.mu.Unlock()
+
+	for i := range merged {
+		mergedPostcode := &merged[i]
+		mergedPostcode.last += dk.added
+
+		foundPostcode, found := dk.postcodes[mergedPostcode.Code]
+		if !found {
+			foundPostcode = &postcode{Code: cloneString(mergedPostcode.Code)}
+			dk.postcodes[foundPostcode.Code] = foundPostcode
+		}
+
+		for from := range mergedPostcode.Counts {
+			for to, count := range mergedPostcode.Counts[from] {
+				foundPostcode.Counts[from][to] += count
+			}
+		}
+		for _, delivery := range mergedPostcode.deliveries {
+			delivery.Postcode = foundPostcode.Code
+			foundPostcode.deliveries = append(foundPostcode.deliveries, delivery)
+		}
+		foundPostcode.DeliveriesCount += mergedPostcode.DeliveriesCount
+		foundPostcode.last = mergedPostcode.last
+	}
+	dk.added += otherAdded
+
+	dk.busiest = dk.busiestPostcode()
+}
+
+// busiestPostcode finds the postcode with the most deliveries. Since a
+// postcode gets to its count with its latest delivery, the one that got there
+// first on ties is the one whose latest delivery came first. The caller must
+// hold the lock.
+func (dk *DeliveryKeeper) busiestPostcode() BusiestPostcode {
+	var busiest *postcode
+	for _, foundPostcode := range dk.postcodes {
+		if busiest == nil || foundPostcode.DeliveriesCount > busiest.DeliveriesCount {
+			busiest = foundPostcode
+			continue
+		}
+		if foundPostcode.DeliveriesCount < busiest.DeliveriesCount {
+			continue
+		}
+		if foundPostcode.last < busiest.last || (foundPostcode.last == busiest.last && foundPostcode.Code < busiest.Code) {
+			busiest = foundPostcode
+		}
+	}
+
+	if busiest == nil || busiest.DeliveriesCount == 0 {
+		return BusiestPostcode{}
+	}
+
+	return BusiestPostcode{Code: busiest.Code, Count: busiest.DeliveriesCount}
+}
+
+// GetBusiestPostcode returns a copy of the current busiest postcode. It is
+// safe to call while deliveries are being added.
+func (dk *DeliveryKeeper) GetBusiestPostcode() BusiestPostcode {
+	dk.mu.RLock()
+	defer dk.mu.RUnlock()
+
+	return dk.busiest
+}
+
+// RetainsDeliveries tells whether the keeper keeps every delivery in the order
+// they were added, as created by NewRetainingDeliveryKeeper.
+func (dk *DeliveryKeeper) RetainsDeliveries() bool {
+	return dk.retain
+}
+
+// Deliveries returns the deliveries of a postcode, in the order they were
+// added when the keeper retains them, and sorted by time range otherwise.
+func (dk *DeliveryKeeper) Deliveries(code string) []models.Delivery {
+	dk.mu.RLock()
+	defer dk.mu.RUnlock()
+
+	foundPostcode, found := dk.postcodes[code]
+	if !found {
+		return nil
+	}
+
+	return foundPostcode.orderedDeliveries()
+}
+
+// orderedDeliveries returns the deliveries retained, or builds them out of the
+// counts, sorted by time range, when they weren't
+func (p *postcode) orderedDeliveries() []models.Delivery {
+	if p.deliveries != nil {
+		return append([]models.Delivery(nil), p.deliveries...)
+	}
+
+	deliveries := make([]models.Delivery, 0, p.DeliveriesCount)
+	for from := range p.Counts {
+		for to, count := range p.Counts[from] {
+			for i := uint32(0); i < count; i++ {
+				deliveries = append(deliveries, models.Delivery{From: from, To: to, Postcode: p.Code})
+			}
+		}
+	}
+
+	return deliveries
+}
+
+// Postcodes returns the codes of every postcode with deliveries, sorted.
+func (dk *DeliveryKeeper) Postcodes() []string {
+	dk.mu.RLock()
+	defer dk.mu.RUnlock()
+
+	codes := make([]string, 0, len(dk.postcodes))
+	for code := range dk.postcodes {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+
+	return codes
+}
+
+// TopPostcodes returns up to n postcodes with the most deliveries, the busiest
+// first. Postcodes with the same count are sorted by their codes.
+func (dk *DeliveryKeeper) TopPostcodes(n int) []BusiestPostcode {
+	dk.mu.RLock()
+	top := make([]BusiestPostcode, 0, len(dk.postcodes))
+	for _, foundPostcode := range dk.postcodes {
+		top = append(top, BusiestPostcode{Code: foundPostcode.Code, Count: foundPostcode.DeliveriesCount})
+	}
+	dk.mu.RUnlock()
+
+	sort.Slice(top, func(i, j int) bool {
+		if top[i].Count != top[j].Count {
+			return top[i].Count > top[j].Count
+		}
+		return top[i].Code < top[j].Code
+	})
+
+	if n >= 0 && n < len(top) {
+		top = top[:n]
+	}
+
+	return top
+}
+
+// MarshalBinary encodes the busiest postcode and, for each postcode, how many
+// deliveries were found within every time range. Postcodes are sorted so the
+// same data always produces the same bytes.
+func (dk *DeliveryKeeper) MarshalBinary() ([]byte, error) {
+	dk.mu.RLock()
+	defer dk.mu.RUnlock()
+
+	codes := make([]string, 0, len(dk.postcodes))
+	for code := range dk.postcodes {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+
+	w := binaryWriter{}
+	w.string(dk.busiest.Code)
+	w.uvarint(dk.busiest.Count)
+	w.uvarint(len(codes))
+	for _, code := range codes {
+		foundPostcode := dk.postcodes[code]
+		w.string(code)
+
+		ranges := 0
+		for from := range foundPostcode.Counts {
+			for _, count := range foundPostcode.Counts[from] {
+				if count > 0 {
+					ranges++
+				}
+			}
+		}
+
+		w.uvarint(ranges)
+		for from := range foundPostcode.Counts {
+			for to, count := range foundPostcode.Counts[from] {
+				if count > 0 {
+					w.uvarint(from)
+					w.uvarint(to)
+					w.uvarint(int(count))
+				}
+			}
+		}
+	}
+
+	return w.buf, nil
+}
+
+// UnmarshalBinary replaces the content of the keeper with the deliveries
+// decoded from data, as produced by MarshalBinary.
+func (dk *DeliveryKeeper) UnmarshalBinary(data []byte) error {
+	r := binaryReader{buf: data}
+
+	busiestPostcode := BusiestPostcode{Code: r.string(), Count: r.uvarint()}
+	size := r.uvarint()
+	postcodes := make(map[string]*postcode)
+	added := 0
+	for i := 0; i < size && r.err == nil; i++ {
+		foundPostcode := &postcode{Code: r.string()}
+		ranges := r.uvarint()
+		for j := 0; j < ranges && r.err == nil; j++ {
+			from, to, count := r.uvarint(), r.uvarint(), r.uvarint()
+			if from >= len(foundPostcode.Counts) || to >= len(foundPostcode.Counts[from]) || count > math.MaxUint32 {
+				return ErrCorruptedData
+			}
+
+			foundPostcode.Counts[from][to] = uint32(count)
+			foundPostcode.DeliveriesCount += count
+		}
+		added += foundPostcode.DeliveriesCount
+		postcodes[foundPostcode.Code] = foundPostcode
+	}
+
+	if err := r.finish(); err != nil {
+		return err
+	}
+
+	// the order the deliveries were added in is not kept, so the busiest
+	// postcode is taken as the first to get to its count, keeping it on ties
+	// when other keepers are merged in, and the deliveries retained are sorted
+	// by time range
+	for _, foundPostcode := range postcodes {
+		if foundPostcode.Code != busiestPostcode.Code {
+			foundPostcode.last = added
+		}
+		if dk.retain {
+			foundPostcode.deliveries = foundPostcode.orderedDeliveries()
+		}
+	}
+
+	dk.mu.Lock()
+	defer dk.mu.Unlock()
+	dk.postcodes = postcodes
+	dk.added = added
+	dk.busiest = busiestPostcode
+
+	return nil
+}
+
 // CountByInterval takes a postcode and an start and end times in 12h format,
 // finds and counts all the deliveries for that postcode within the time range.
-// If nothing was found or the parameters are empty, it returns 0.
+// If nothing was found, the parameters are empty or not valid times, or the
+// time range ends before it starts, it returns 0.
 func (dk *DeliveryKeeper) CountByInterval(postcode string, start string, end string) int {
+	count, _ := dk.CountByIntervalContext(context.Background(), postcode, start, end)
+	return count
+}
+
+// CountByIntervalContext works like CountByInterval, giving up with the error
+// of ctx as soon as it is canceled. Times that aren't valid result in
+// ErrInvalidTime.
+func (dk *DeliveryKeeper) CountByIntervalContext(ctx context.Context, postcode string, start string, end string) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
 	if postcode == "" || start == "" || end == "" {
-		return 0
+		return 0, nil
+	}
+	rangeBottom, err := TimeToIndex(start)
+	if err != nil {
+		return 0, err
+	}
+	rangeTop, err := TimeToIndex(end)
+	if err != nil {
+		return 0, err
+	}
+	// no delivery is within a time range ending before it starts
+	if rangeTop < rangeBottom {
+		return 0, nil
 	}
-	rangeBottom := TimeToIndex(start)
-	rangeTop := TimeToIndex(end) + 1
+	rangeTop++
+
+	dk.mu.RLock()
+	defer dk.mu.RUnlock()
 
 	foundPostcode, found := dk.postcodes[postcode]
 	if !found {
-		return 0
+		return 0, nil
 	}
 
 	// start filtering by start time
-	fromStartTimeDeliveries := foundPostcode.Deliveries[rangeBottom:rangeTop]
+	fromStartTimeCounts := foundPostcode.Counts[rangeBottom:rangeTop]
 
 	var count int
-	for _, endTimes := range fromStartTimeDeliveries {
+	for _, endTimes := range fromStartTimeCounts {
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
 		for _, deliveries := range endTimes[rangeBottom:rangeTop] {
-			if len(deliveries) > 0 {
-				count += len(deliveries)
-			}
+			count += int(deliveries)
 		}
 	}
 
-	return count
+	return count, nil
 }
 
 // TimeToIndex is a transform function that transforms a 12h time string in a
 // 24h time number. Example: 5PM turns into 17, 12AM turns into 0.
-// Byte manipulation was chosen in favor of performance.
-func TimeToIndex(time string) int {
-	byteDr := []byte(time)
-	suffix := string(byteDr[len(byteDr)-2:])
-	prefix := string(byteDr[0 : len(byteDr)-2])
-	value, _ := strconv.Atoi(prefix)
-	if suffix == "PM" {
+// Byte manipulation was chosen in favor of performance. Anything but an hour
+// from 1 to 12 followed by AM or PM, such as "M" or "13PM", results in
+// ErrInvalidTime.
+func TimeToIndex(time string) (int, error) {
+	if len(time) < 3 || len(time) > 4 {
+		return 0, invalidTime(time)
+	}
+
+	value := 0
+	for _, digit := range []byte(time[:len(time)-2]) {
+		if digit < '0' || digit > '9' {
+			return 0, invalidTime(time)
+		}
+		value = value*10 + int(digit-'0')
+	}
+	if value < 1 || value > 12 {
+		return 0, invalidTime(time)
+	}
+
+	switch time[len(time)-2:] {
+	case "PM":
 		if value == 12 {
-			return value // 12PM
-		} else {
-			return value + 12
+			return value, nil // 12PM
 		}
-	} else {
+		return value + 12, nil
+	case "AM":
 		if value == 12 {
-			return 0 // 12AM
-		} else {
-			return value
+			return 0, nil // 12AM
 		}
+		return value, nil
 	}
+
+	return 0, invalidTime(time)
+}
+
+func invalidTime(time string) error {
+	return fmt.Errorf("%w %q, expected something like 8AM or 12PM", ErrInvalidTime, time)
 }
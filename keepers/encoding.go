@@ -0,0 +1,78 @@
+package keepers
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// ErrCorruptedData is returned when decoding keepers from a binary
+// representation that is truncated or inconsistent.
+var ErrCorruptedData = errors.New("corrupted keeper data")
+
+// binaryWriter is a tiny helper to build the binary representation of the
+// keepers using varints and length prefixed strings.
+type binaryWriter struct {
+	buf []byte
+}
+
+func (w *binaryWriter) uvarint(value int) {
+	var scratch [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(scratch[:], uint64(value))
+	w.buf = append(w.buf, scratch[:n]...)
+}
+
+func (w *binaryWriter) string(value string) {
+	w.uvarint(len(value))
+	w.buf = append(w.buf, value...)
+}
+
+// binaryReader is the counterpart of binaryWriter. The first error found is
+// kept so the decoding code can check it just once at the end.
+type binaryReader struct {
+	buf []byte
+	err error
+}
+
+func (r *binaryReader) uvarint() int {
+	if r.err != nil {
+		return 0
+	}
+
+	value, n := binary.Uvarint(r.buf)
+	if n <= 0 || value > uint64(int(^uint(0)>>1)) {
+		r.err = ErrCorruptedData
+		return 0
+	}
+	r.buf = r.buf[n:]
+
+	return int(value)
+}
+
+func (r *binaryReader) string() string {
+	length := r.uvarint()
+	if r.err != nil {
+		return ""
+	}
+	if length > len(r.buf) {
+		r.err = ErrCorruptedData
+		return ""
+	}
+
+	value := string(r.buf[:length])
+	r.buf = r.buf[length:]
+
+	return value
+}
+
+// finish reports the first error found, or ErrCorruptedData if there are
+// unread bytes left.
+func (r *binaryReader) finish() error {
+	if r.err != nil {
+		return r.err
+	}
+	if len(r.buf) > 0 {
+		return ErrCorruptedData
+	}
+
+	return nil
+}
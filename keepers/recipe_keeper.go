@@ -1,25 +1,41 @@
 package keepers
 
 import (
+	"errors"
 	"recipe-stats/models"
+	"sort"
+	"sync"
 )
 
+// ErrRecipeNotFound is returned when removing a recipe that was never added.
+var ErrRecipeNotFound = errors.New("recipe not found")
+
+// ContextBatch is how many items are handled between checks of a context for
+// cancellation, so the checks don't show up in the loading time.
+const ContextBatch = 1024
+
 // RecipeKeeper is the main struct for the Recipe Keeper, holding the necessary
-// data structures to calculate distinct recipes count
+// data structures to calculate distinct recipes count. It is safe for
+// concurrent use by multiple readers and writers. Its zero value is an empty
+// keeper that can be read, while NewRecipeKeeper provides one that recipes can
+// be added to.
 type RecipeKeeper struct {
+	mu      sync.RWMutex
 	recipes map[string]models.Recipe
 }
 
 // NewRecipeKeeper provides a usable instance of RecipeKeeper.
 func NewRecipeKeeper() RecipeKeeper {
-	rk := RecipeKeeper{}
-	rk.recipes = make(map[string]models.Recipe)
-
-	return rk
+	return RecipeKeeper{
+		recipes: make(map[string]models.Recipe),
+	}
 }
 
 // Add puts a new recipe on the list of recipes if it does not exists yet.
 func (rk *RecipeKeeper) Add(recipe models.Recipe) error {
+	rk.mu.Lock()
+	defer rk.mu.Unlock()
+
 	if existingRecipe, exists := rk.recipes[recipe.Recipe]; exists {
 		existingRecipe.Count += 1
 		rk.recipes[recipe.Recipe] = existingRecipe
@@ -31,8 +47,51 @@ func (rk *RecipeKeeper) Add(recipe models.Recipe) error {
 	return nil
 }
 
+// Remove takes a single occurrence of a recipe out of the list of recipes,
+// dropping it when its count reaches zero. It returns ErrRecipeNotFound if the
+// recipe is unknown.
+func (rk *RecipeKeeper) Remove(recipe models.Recipe) error {
+	rk.mu.Lock()
+	defer rk.mu.Unlock()
+
+	existingRecipe, exists := rk.recipes[recipe.Recipe]
+	if !exists {
+		return ErrRecipeNotFound
+	}
+
+	existingRecipe.Count--
+	if existingRecipe.Count > 0 {
+		rk.recipes[recipe.Recipe] = existingRecipe
+	} else {
+		delete(rk.recipes, recipe.Recipe)
+	}
+
+	return nil
+}
+
+// Merge adds every recipe of other to the keeper, summing the counts of the
+// recipes found in both. other is left as it is, and may be the keeper itself.
+func (rk *RecipeKeeper) Merge(other *RecipeKeeper) {
+	recipes := other.GetMap()
+
+	rk.mu.Lock()
+	defer rk.mu.Unlock()
+
+	for name, recipe := range recipes {
+		if existingRecipe, exists := rk.recipes[name]; exists {
+			existingRecipe.Count += recipe.Count
+			rk.recipes[name] = existingRecipe
+		} else {
+			rk.recipes[name] = recipe
+		}
+	}
+}
+
 // Count calculates the number of distinct recipes found.
 func (rk *RecipeKeeper) Count() int {
+	rk.mu.RLock()
+	defer rk.mu.RUnlock()
+
 	if rk.recipes == nil {
 		return 0
 	}
@@ -42,5 +101,58 @@ func (rk *RecipeKeeper) Count() int {
 
 // GetMap is a utility to return a copy of the recipes map
 func (rk *RecipeKeeper) GetMap() map[string]models.Recipe {
-	return rk.recipes
+	rk.mu.RLock()
+	defer rk.mu.RUnlock()
+
+	recipes := make(map[string]models.Recipe, len(rk.recipes))
+	for name, recipe := range rk.recipes {
+		recipes[name] = recipe
+	}
+
+	return recipes
+}
+
+// MarshalBinary encodes the recipes and their counts. Recipes are sorted by
+// name so the same data always produces the same bytes.
+func (rk *RecipeKeeper) MarshalBinary() ([]byte, error) {
+	rk.mu.RLock()
+	defer rk.mu.RUnlock()
+
+	names := make([]string, 0, len(rk.recipes))
+	for name := range rk.recipes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	w := binaryWriter{}
+	w.uvarint(len(names))
+	for _, name := range names {
+		w.string(name)
+		w.uvarint(rk.recipes[name].Count)
+	}
+
+	return w.buf, nil
+}
+
+// UnmarshalBinary replaces the content of the keeper with the recipes decoded
+// from data, as produced by MarshalBinary.
+func (rk *RecipeKeeper) UnmarshalBinary(data []byte) error {
+	r := binaryReader{buf: data}
+
+	size := r.uvarint()
+	recipes := make(map[string]models.Recipe)
+	for i := 0; i < size && r.err == nil; i++ {
+		name := r.string()
+		recipes[name] = models.Recipe{Recipe: name, Count: r.uvarint()}
+	}
+
+	if err := r.finish(); err != nil {
+		return err
+	}
+
+	rk.mu.Lock()
+	defer rk.mu.Unlock()
+	rk.recipes = recipes
+
+	return nil
 }
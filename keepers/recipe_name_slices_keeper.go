@@ -1,9 +1,11 @@
 package keepers
 
 import (
+	"context"
 	"recipe-stats/models"
 	"sort"
 	"strings"
+	"sync"
 )
 
 // I first thought of using a balanced tree to store everything from the
@@ -28,43 +30,275 @@ import (
 
 // RecipeNameSlicesKeeper is the main struct for the Recipes Name Slice
 // reference to all the required objects for the logic of controlling deliveries and the
-// busiest postcode.
+// busiest postcode. It is safe for concurrent use by multiple readers and
+// writers. Its zero value is an empty keeper that can be read, while
+// NewRecipeNameSlicesKeeper provides one that recipes can be added to.
+// Each distinct recipe is stored only once and every name slice points to it,
+// so counts stay up to date when the same recipe is added again later on.
 type RecipeNameSlicesKeeper struct {
-	recipeNameSlices map[string][]models.Recipe
+	mu               sync.RWMutex
+	recipes          map[string]*models.Recipe
+	recipeNameSlices map[string][]*models.Recipe
 }
 
+// NewRecipeNameSlicesKeeper provides a usable instance of
+// RecipeNameSlicesKeeper.
 func NewRecipeNameSlicesKeeper() RecipeNameSlicesKeeper {
-	rnsk := RecipeNameSlicesKeeper{}
-	rnsk.recipeNameSlices = make(map[string][]models.Recipe)
-
-	return rnsk
+	return RecipeNameSlicesKeeper{
+		recipes:          make(map[string]*models.Recipe),
+		recipeNameSlices: make(map[string][]*models.Recipe),
+	}
 }
 
 // Load receives a map of Recipes (the key is the recipe name) and breaks it
 // into name slices, distributing the words found within the names map along
-// the recipe itself
+// the recipe itself. Recipes already known have their counts summed up.
 func (rnsk *RecipeNameSlicesKeeper) Load(recipes map[string]models.Recipe) {
+	_ = rnsk.LoadContext(context.Background(), recipes)
+}
+
+// LoadContext works like Load, giving up with the error of ctx as soon as it
+// is canceled. The recipes loaded until then are kept.
+func (rnsk *RecipeNameSlicesKeeper) LoadContext(ctx context.Context, recipes map[string]models.Recipe) error {
+	rnsk.mu.Lock()
+	defer rnsk.mu.Unlock()
+
+	loaded := 0
 	for _, recipe := range recipes {
-		recipeNameSlices := strings.Split(recipe.Recipe, " ")
+		if loaded%ContextBatch == 0 {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+		}
+		rnsk.add(recipe.Recipe, recipe.Count)
+		loaded++
+	}
+
+	return nil
+}
+
+// Add puts a single occurrence of a recipe into the name slices, the same way
+// RecipeKeeper.Add does, so both keepers stay consistent when new records are
+// streamed in after the initial Load.
+func (rnsk *RecipeNameSlicesKeeper) Add(recipe models.Recipe) error {
+	rnsk.mu.Lock()
+	defer rnsk.mu.Unlock()
+
+	rnsk.add(recipe.Recipe, 1)
+
+	return nil
+}
+
+// Remove takes a single occurrence of a recipe out of the name slices. When
+// its count reaches zero, the recipe is not found by any name slice anymore.
+// It returns ErrRecipeNotFound if the recipe is unknown.
+func (rnsk *RecipeNameSlicesKeeper) Remove(recipe models.Recipe) error {
+	rnsk.mu.Lock()
+	defer rnsk.mu.Unlock()
+
+	existingRecipe, exists := rnsk.recipes[recipe.Recipe]
+	if !exists {
+		return ErrRecipeNotFound
+	}
+
+	existingRecipe.Count--
+	if existingRecipe.Count > 0 {
+		return nil
+	}
+
+	delete(rnsk.recipes, recipe.Recipe)
+	for _, recipeNameSlice := range strings.Split(recipe.Recipe, " ") {
+		remaining := rnsk.recipeNameSlices[recipeNameSlice][:0]
+		for _, indexedRecipe := range rnsk.recipeNameSlices[recipeNameSlice] {
+			if indexedRecipe != existingRecipe {
+				remaining = append(remaining, indexedRecipe)
+			}
+		}
+
+		if len(remaining) == 0 {
+			delete(rnsk.recipeNameSlices, recipeNameSlice)
+		} else {
+			rnsk.recipeNameSlices[recipeNameSlice] = remaining
+		}
+	}
+
+	return nil
+}
+
+// Merge adds every recipe of other to the name slices, summing the counts of
+// the recipes found in both and indexing the new ones by their name slices.
+// other is left as it is, and may be the keeper itself.
+func (rnsk *RecipeNameSlicesKeeper) Merge(other *RecipeNameSlicesKeeper) {
+	other.mu.RLock()
+	names := make([]string, 0, len(other.recipes))
+	counts := make(map[string]int, len(other.recipes))
+	for name, recipe := range other.recipes {
+		names = append(names, name)
+		counts[name] = recipe.Count
+	}
+	other.mu.RUnlock()
+
+	// the new recipes are indexed in the same order every time
+	sort.Strings(names)
+
+	rnsk.mu.Lock()
+	defer rnsk.mu.Unlock()
+
+	for _, name := range names {
+		rnsk.add(name, counts[name])
+	}
+}
+
+// add sums count to the recipe named name, indexing it by its name slices the
+// first time it is seen. The caller must hold the write lock.
+func (rnsk *RecipeNameSlicesKeeper) add(name string, count int) {
+	if existingRecipe, exists := rnsk.recipes[name]; exists {
+		existingRecipe.Count += count
+		return
+	}
+
+	recipe := &models.Recipe{Recipe: name, Count: count}
+	rnsk.recipes[name] = recipe
 
-		for _, recipeNameSlice := range recipeNameSlices {
-			rnsk.recipeNameSlices[recipeNameSlice] = append(rnsk.recipeNameSlices[recipeNameSlice], recipe)
+	for _, recipeNameSlice := range strings.Split(name, " ") {
+		indexedRecipes := rnsk.recipeNameSlices[recipeNameSlice]
+		// a word repeated within the same name must not index it twice
+		if len(indexedRecipes) > 0 && indexedRecipes[len(indexedRecipes)-1] == recipe {
+			continue
 		}
+		rnsk.recipeNameSlices[recipeNameSlice] = append(indexedRecipes, recipe)
 	}
 }
 
+// MarshalBinary encodes the distinct recipes followed by every name slice and
+// the positions of the recipes it refers to. Everything is sorted so the same
+// data always produces the same bytes.
+func (rnsk *RecipeNameSlicesKeeper) MarshalBinary() ([]byte, error) {
+	rnsk.mu.RLock()
+	defer rnsk.mu.RUnlock()
+
+	names := make([]string, 0, len(rnsk.recipes))
+	for name := range rnsk.recipes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	positions := make(map[*models.Recipe]int, len(names))
+	w := binaryWriter{}
+	w.uvarint(len(names))
+	for i, name := range names {
+		recipe := rnsk.recipes[name]
+		positions[recipe] = i
+		w.string(name)
+		w.uvarint(recipe.Count)
+	}
+
+	recipeNameSlices := make([]string, 0, len(rnsk.recipeNameSlices))
+	for recipeNameSlice := range rnsk.recipeNameSlices {
+		recipeNameSlices = append(recipeNameSlices, recipeNameSlice)
+	}
+	sort.Strings(recipeNameSlices)
+
+	w.uvarint(len(recipeNameSlices))
+	for _, recipeNameSlice := range recipeNameSlices {
+		w.string(recipeNameSlice)
+		w.uvarint(len(rnsk.recipeNameSlices[recipeNameSlice]))
+		for _, recipe := range rnsk.recipeNameSlices[recipeNameSlice] {
+			w.uvarint(positions[recipe])
+		}
+	}
+
+	return w.buf, nil
+}
+
+// UnmarshalBinary replaces the content of the keeper with the name slices
+// decoded from data, as produced by MarshalBinary.
+func (rnsk *RecipeNameSlicesKeeper) UnmarshalBinary(data []byte) error {
+	r := binaryReader{buf: data}
+
+	size := r.uvarint()
+	ordered := []*models.Recipe{}
+	recipes := make(map[string]*models.Recipe)
+	for i := 0; i < size && r.err == nil; i++ {
+		recipe := &models.Recipe{Recipe: r.string(), Count: r.uvarint()}
+		ordered = append(ordered, recipe)
+		recipes[recipe.Recipe] = recipe
+	}
+
+	size = r.uvarint()
+	recipeNameSlices := make(map[string][]*models.Recipe)
+	for i := 0; i < size && r.err == nil; i++ {
+		recipeNameSlice := r.string()
+		indexedCount := r.uvarint()
+		for j := 0; j < indexedCount && r.err == nil; j++ {
+			position := r.uvarint()
+			if position >= len(ordered) {
+				return ErrCorruptedData
+			}
+			recipeNameSlices[recipeNameSlice] = append(recipeNameSlices[recipeNameSlice], ordered[position])
+		}
+	}
+
+	if err := r.finish(); err != nil {
+		return err
+	}
+
+	rnsk.mu.Lock()
+	defer rnsk.mu.Unlock()
+	rnsk.recipes = recipes
+	rnsk.recipeNameSlices = recipeNameSlices
+
+	return nil
+}
+
 // Get finds a single name slice and returns the recipes related to it. Also
 // returns true if find something and false if not.
 func (rnsk *RecipeNameSlicesKeeper) Get(recipeNameSlice string) ([]models.Recipe, bool) {
-	recipes, found := rnsk.recipeNameSlices[recipeNameSlice]
+	rnsk.mu.RLock()
+	defer rnsk.mu.RUnlock()
+
+	indexedRecipes, found := rnsk.recipeNameSlices[recipeNameSlice]
+	if !found {
+		return nil, false
+	}
+
+	// recipes are copied so callers can't see later changes
+	recipes := make([]models.Recipe, 0, len(indexedRecipes))
+	for _, recipe := range indexedRecipes {
+		recipes = append(recipes, *recipe)
+	}
 
-	return recipes, found
+	return recipes, true
+}
+
+// Words returns every name slice known, sorted.
+func (rnsk *RecipeNameSlicesKeeper) Words() []string {
+	rnsk.mu.RLock()
+	defer rnsk.mu.RUnlock()
+
+	words := make([]string, 0, len(rnsk.recipeNameSlices))
+	for word := range rnsk.recipeNameSlices {
+		words = append(words, word)
+	}
+	sort.Strings(words)
+
+	return words
 }
 
 // GetSome finds multiple name slices and returns the recipes related to them.
 func (rnsk *RecipeNameSlicesKeeper) GetSome(recipeNameSlices []string) []models.Recipe {
+	recipes, _ := rnsk.GetSomeContext(context.Background(), recipeNameSlices)
+	return recipes
+}
+
+// GetSomeContext works like GetSome, giving up with the error of ctx as soon
+// as it is canceled.
+func (rnsk *RecipeNameSlicesKeeper) GetSomeContext(ctx context.Context, recipeNameSlices []string) ([]models.Recipe, error) {
 	recipesFound := []models.Recipe{}
 	for _, slice := range recipeNameSlices {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
 		if recipes, found := rnsk.Get(slice); found {
 			recipesFound = append(recipesFound, recipes...)
 		}
@@ -85,7 +319,7 @@ func (rnsk *RecipeNameSlicesKeeper) GetSome(recipeNameSlices []string) []models.
 		}
 	}
 
-	return singleRecipesFound
+	return singleRecipesFound, nil
 }
 
 type ByRecipe []models.Recipe
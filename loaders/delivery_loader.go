@@ -1,32 +1,77 @@
 package loaders
 
 import (
-	"fmt"
-	"os"
+	"context"
 	"recipe-stats/adapters"
 	"recipe-stats/keepers"
-	"sync"
-	"time"
+	"recipe-stats/metrics"
 )
 
-func loadDeliveriesFromGeneralRecipe(recipes []adapters.GeneralRecipe, wg *sync.WaitGroup, verbose bool) *keepers.DeliveryKeeper {
-	defer wg.Done()
+// loadDeliveriesFromGeneralRecipe builds the delivery keeper, splitting
+// recipes among up to workers partial keepers merged in the end
+func loadDeliveriesFromGeneralRecipe(ctx context.Context, recipes []adapters.GeneralRecipe, workers int, observer Observer) (*keepers.DeliveryKeeper, error) {
+	finished := observer.Phase(metrics.PhaseMapDeliveries, "Mapping deliveries")
 
-	start := time.Now()
-	if verbose {
-		fmt.Fprintln(os.Stderr, "Mapping deliveries...")
+	deliveryKeeper, err := mergeDeliveries(ctx, recipes, workers)
+	if err != nil {
+		return nil, err
 	}
 
-	deliveryKeeper := keepers.NewDeliveryKeeper()
+	finished("deliveries", len(recipes))
 
-	for i := 0; i < len(recipes); i++ {
-		delivery := recipes[i].ToDelivery()
-		deliveryKeeper.Add(delivery)
+	return deliveryKeeper, nil
+}
+
+// appendDeliveriesFromGeneralRecipe builds partial keepers out of recipes,
+// split among up to workers of them, and merges them into the loaded one
+func appendDeliveriesFromGeneralRecipe(ctx context.Context, recipes []adapters.GeneralRecipe, deliveryKeeper *keepers.DeliveryKeeper, workers int, observer Observer) error {
+	finished := observer.Phase(metrics.PhaseAppendDeliveries, "Appending deliveries")
+
+	appended, err := mergeDeliveries(ctx, recipes, workers)
+	if err != nil {
+		return err
 	}
+	deliveryKeeper.Merge(appended)
+
+	finished("deliveries", len(recipes))
+
+	return nil
+}
+
+// mergeDeliveries builds a delivery keeper out of up to workers partial ones,
+// each built from consecutive recipes
+func mergeDeliveries(ctx context.Context, recipes []adapters.GeneralRecipe, workers int) (*keepers.DeliveryKeeper, error) {
+	shards := shardRecipes(recipes, workers)
+	partials := make([]keepers.DeliveryKeeper, len(shards))
+	err := runShards(shards, func(i int, shard []adapters.GeneralRecipe) error {
+		partials[i] = keepers.NewDeliveryKeeper()
+		return addDeliveries(ctx, shard, &partials[i])
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// merged in the order of the shards, so the busiest postcode is the same
+	// one adding every delivery to a single keeper would find
+	deliveryKeeper := &partials[0]
+	for i := 1; i < len(partials); i++ {
+		deliveryKeeper.Merge(&partials[i])
+	}
+
+	return deliveryKeeper, nil
+}
+
+// addDeliveries adds the delivery of every record to deliveryKeeper
+func addDeliveries(ctx context.Context, recipes []adapters.GeneralRecipe, deliveryKeeper *keepers.DeliveryKeeper) error {
+	for i := 0; i < len(recipes); i++ {
+		if i%keepers.ContextBatch == 0 {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+		}
 
-	if verbose {
-		fmt.Fprintf(os.Stderr, "Mapping deliveries took %s\n", time.Since(start))
+		deliveryKeeper.Add(recipes[i].ToDelivery())
 	}
 
-	return &deliveryKeeper
+	return nil
 }
@@ -1,56 +1,187 @@
 package loaders
 
 import (
-	"fmt"
-	"os"
+	"context"
 	"recipe-stats/adapters"
 	"recipe-stats/keepers"
+	"recipe-stats/metrics"
 	"sync"
-	"time"
 )
 
+// LoadFromGeneralRecipe loads the keepers from the JSON file at filePath,
+// leaving out the records that can't be parsed with the strict policy.
 func LoadFromGeneralRecipe(filePath string, verbose bool) (*keepers.RecipeKeeper, *keepers.RecipeNameSlicesKeeper, *keepers.DeliveryKeeper, error) {
-	wg := *new(sync.WaitGroup)
+	recipesAdapter := adapters.NewGeneralRecipeAdapter()
+	return LoadFromGeneralRecipeWithRejects(filePath, &recipesAdapter, nil, verbose)
+}
 
-	recipes, err := loadGeneralRecipesFile(filePath, verbose)
+// LoadFromGeneralRecipeWithRejects works like LoadFromGeneralRecipe, decoding
+// the file with recipesAdapter and handing the records that can't be parsed
+// to rejects.
+func LoadFromGeneralRecipeWithRejects(filePath string, recipesAdapter adapters.RecordsAdapter, rejects *Rejects, verbose bool) (*keepers.RecipeKeeper, *keepers.RecipeNameSlicesKeeper, *keepers.DeliveryKeeper, error) {
+	return LoadFromGeneralRecipeContext(context.Background(), filePath, recipesAdapter, rejects, nil, VerboseObserver(verbose))
+}
+
+// DefaultParallelism is how many keepers are built at the same time when
+// loading. The name slices keeper is built from the recipe keeper, one after
+// the other, so there are only 2 builds that can run at the same time: that one
+// and the delivery keeper.
+const DefaultParallelism = 2
+
+// LoadFromGeneralRecipeContext works like LoadFromGeneralRecipeWithRejects,
+// reporting how far reading the file went to progress, which may be nil, and
+// every step to observer, and giving up with the error of ctx as soon as it is
+// canceled.
+func LoadFromGeneralRecipeContext(ctx context.Context, filePath string, recipesAdapter adapters.RecordsAdapter, rejects *Rejects, progress func(adapters.Progress), observer Observer) (*keepers.RecipeKeeper, *keepers.RecipeNameSlicesKeeper, *keepers.DeliveryKeeper, error) {
+	recipes, err := loadGeneralRecipesFile(ctx, filePath, recipesAdapter, rejects, progress, observer)
 	if err != nil {
 		return nil, nil, nil, err
 	}
 
+	return buildKeepers(ctx, *recipes, DefaultParallelism, recipesAdapter.Workers(), observer)
+}
+
+// LoadFromBytesContext works like LoadFromGeneralRecipeContext over the content
+// of an input JSON file already in memory, named name in the rejected records.
+// Up to parallelism keepers are built at the same time, as in buildKeepers.
+func LoadFromBytesContext(ctx context.Context, data []byte, name string, recipesAdapter adapters.RecordsAdapter, rejects *Rejects, progress func(adapters.Progress), parallelism int, observer Observer) (*keepers.RecipeKeeper, *keepers.RecipeNameSlicesKeeper, *keepers.DeliveryKeeper, error) {
+	recipes, err := decodeGeneralRecipes(name, recipesAdapter, rejects, progress, observer, func(reject func(*adapters.RecordError), progress func(adapters.Progress)) (*[]adapters.GeneralRecipe, error) {
+		return recipesAdapter.DecodeRecords(ctx, data, reject, progress)
+	})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return buildKeepers(ctx, *recipes, parallelism, recipesAdapter.Workers(), observer)
+}
+
+// buildKeepers builds every keeper from recipes, each one out of up to workers
+// partial keepers. Up to parallelism of the builds run at the same time, one at
+// a time when it is 1 or less, and any value from DefaultParallelism up runs
+// all of them at once.
+func buildKeepers(ctx context.Context, recipes []adapters.GeneralRecipe, parallelism int, workers int, observer Observer) (*keepers.RecipeKeeper, *keepers.RecipeNameSlicesKeeper, *keepers.DeliveryKeeper, error) {
+	if parallelism < 1 {
+		parallelism = 1
+	}
+
+	// running holds a slot for every build running, so no more than
+	// parallelism of them run at the same time
+	running := make(chan struct{}, parallelism)
+	wg := *new(sync.WaitGroup)
+	run := func(task func()) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			running <- struct{}{}
+			defer func() { <-running }()
+			task()
+		}()
+	}
+
 	recipeKeeper := new(keepers.RecipeKeeper)
 	recipeNameSlicesKeeper := new(keepers.RecipeNameSlicesKeeper)
-	wg.Add(2)
+	var recipesErr error
+	run(func() {
+		recipeKeeper, recipesErr = loadRecipesFromGeneralRecipe(ctx, recipes, workers, observer)
+		if recipesErr == nil {
+			recipeNameSlicesKeeper, recipesErr = loadRecipeNameSlicesFromRecipes(ctx, recipeKeeper.GetMap(), observer)
+		}
+	})
+
+	deliveryKeeper := new(keepers.DeliveryKeeper)
+	var deliveriesErr error
+	run(func() {
+		deliveryKeeper, deliveriesErr = loadDeliveriesFromGeneralRecipe(ctx, recipes, workers, observer)
+	})
+
+	wg.Wait()
+	if recipesErr != nil {
+		return nil, nil, nil, recipesErr
+	}
+	if deliveriesErr != nil {
+		return nil, nil, nil, deliveriesErr
+	}
+
+	return recipeKeeper, recipeNameSlicesKeeper, deliveryKeeper, nil
+}
+
+// AppendFromGeneralRecipe reads the new records from filePath and adds them
+// to already loaded keepers, so daily deltas can be applied without rebuilding
+// the whole dataset. The file is decoded with recipesAdapter, and the records
+// that can't be parsed are handed to rejects.
+func AppendFromGeneralRecipe(filePath string, recipeKeeper *keepers.RecipeKeeper, recipeNameSlicesKeeper *keepers.RecipeNameSlicesKeeper, deliveryKeeper *keepers.DeliveryKeeper, recipesAdapter adapters.RecordsAdapter, rejects *Rejects, verbose bool) error {
+	return AppendFromGeneralRecipeContext(context.Background(), filePath, recipeKeeper, recipeNameSlicesKeeper, deliveryKeeper, recipesAdapter, rejects, VerboseObserver(verbose))
+}
+
+// AppendFromGeneralRecipeContext works like AppendFromGeneralRecipe, reporting
+// to observer and giving up with the error of ctx as soon as it is canceled.
+// The new records are built into keepers of their own, merged into the loaded
+// ones at the end, so canceling it leaves them as they were unless some were
+// already merged, in which case they should be loaded again.
+func AppendFromGeneralRecipeContext(ctx context.Context, filePath string, recipeKeeper *keepers.RecipeKeeper, recipeNameSlicesKeeper *keepers.RecipeNameSlicesKeeper, deliveryKeeper *keepers.DeliveryKeeper, recipesAdapter adapters.RecordsAdapter, rejects *Rejects, observer Observer) error {
+	wg := *new(sync.WaitGroup)
+
+	recipes, err := loadGeneralRecipesFile(ctx, filePath, recipesAdapter, rejects, nil, observer)
+	if err != nil {
+		return err
+	}
+
+	var recipesErr error
+	wg.Add(1)
 	go func() {
-		recipeKeeper, _ = loadRecipesFromGeneralRecipe(*recipes, &wg, verbose)
-		recipeNameSlicesKeeper = loadRecipeNameSlicesFromRecipes(recipeKeeper.GetMap(), &wg, verbose)
+		defer wg.Done()
+		recipesErr = appendRecipesFromGeneralRecipe(ctx, *recipes, recipeKeeper, recipeNameSlicesKeeper, recipesAdapter.Workers(), observer)
 	}()
 
-	deliveryKeeper := new(keepers.DeliveryKeeper)
+	var deliveriesErr error
 	wg.Add(1)
 	go func() {
-		deliveryKeeper = loadDeliveriesFromGeneralRecipe(*recipes, &wg, verbose)
+		defer wg.Done()
+		deliveriesErr = appendDeliveriesFromGeneralRecipe(ctx, *recipes, deliveryKeeper, recipesAdapter.Workers(), observer)
 	}()
 
 	wg.Wait()
-	return recipeKeeper, recipeNameSlicesKeeper, deliveryKeeper, nil
+	if recipesErr != nil {
+		return recipesErr
+	}
+	return deliveriesErr
+}
+
+func loadGeneralRecipesFile(ctx context.Context, filePath string, recipesAdapter adapters.RecordsAdapter, rejects *Rejects, progress func(adapters.Progress), observer Observer) (*[]adapters.GeneralRecipe, error) {
+	return decodeGeneralRecipes(filePath, recipesAdapter, rejects, progress, observer, func(reject func(*adapters.RecordError), progress func(adapters.Progress)) (*[]adapters.GeneralRecipe, error) {
+		return recipesAdapter.UnmarshalRecordsContext(ctx, filePath, reject, progress)
+	})
 }
 
-func loadGeneralRecipesFile(filePath string, verbose bool) (*[]adapters.GeneralRecipe, error) {
-	if verbose {
-		fmt.Fprintln(os.Stderr, "Reading recipes file...")
+// decodeGeneralRecipes runs decode, handing it the function that rejects the
+// records of the input named name and the one reporting progress, and reports
+// on it to observer
+func decodeGeneralRecipes(name string, recipesAdapter adapters.RecordsAdapter, rejects *Rejects, progress func(adapters.Progress), observer Observer, decode func(reject func(*adapters.RecordError), progress func(adapters.Progress)) (*[]adapters.GeneralRecipe, error)) (*[]adapters.GeneralRecipe, error) {
+	finished := observer.Phase(metrics.PhaseReadRecipes, "Reading recipes file")
+	observer.Logger.Debug("Parsing policy", "policy", recipesAdapter.ParsePolicy(), "workers", recipesAdapter.Workers())
+	if rejects == nil {
+		// still counting them for the metrics
+		rejects, _ = NewRejects("")
 	}
-	start := time.Now()
-	recipesAdapter := adapters.NewGeneralRecipeAdapter()
-	recipes, err := recipesAdapter.Unmarshal(filePath)
-	if err != nil {
-		if verbose {
-			fmt.Fprintf(os.Stderr, "It was impossible to parse the input file. The error was: %s\n", err.Error())
+	rejectedBefore := rejects.Count()
+
+	var bytesRead int64
+	recipes, err := decode(rejects.add(name), func(current adapters.Progress) {
+		bytesRead = current.Read
+		if progress != nil {
+			progress(current)
 		}
+	})
+	if err != nil {
+		observer.Logger.Error("It was impossible to parse the input file", "error", err)
 		return nil, err
 	}
-	if verbose {
-		fmt.Fprintf(os.Stderr, "Reading recipes file took %s\n", time.Since(start))
-	}
+
+	rejected := rejects.Count() - rejectedBefore
+	observer.recorder().Records(len(*recipes) + rejected)
+	observer.recorder().BytesRead(bytesRead)
+	observer.recorder().Rejects(rejected)
+	finished("records", len(*recipes)+rejected, "bytes", bytesRead, "rejected", rejected)
 
 	return recipes, nil
 }
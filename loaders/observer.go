@@ -0,0 +1,48 @@
+package loaders
+
+import (
+	"os"
+	"recipe-stats/logging"
+	"recipe-stats/metrics"
+	"time"
+)
+
+// Observer is where the loaders report what they are doing: the steps they
+// go through are logged to Logger and measured into Metrics. The zero Observer
+// reports nothing.
+type Observer struct {
+	Logger  *logging.Logger
+	Metrics metrics.Recorder
+}
+
+// VerboseObserver logs every step to stderr as text when verbose, and nothing
+// otherwise, the way the --verbose flag does.
+func VerboseObserver(verbose bool) Observer {
+	if !verbose {
+		return Observer{}
+	}
+
+	return Observer{Logger: logging.New(logging.NewTextHandler(os.Stderr, logging.LevelDebug))}
+}
+
+func (o Observer) recorder() metrics.Recorder {
+	if o.Metrics == nil {
+		return metrics.Nop{}
+	}
+
+	return o.Metrics
+}
+
+// Phase logs that the step described by message started, and returns the
+// function to call once it finished, which measures it as the phase name and
+// logs args along with how long it took.
+func (o Observer) Phase(name string, message string) func(args ...interface{}) {
+	o.Logger.Debug(message + "...")
+	start := time.Now()
+
+	return func(args ...interface{}) {
+		duration := time.Since(start)
+		o.recorder().Phase(name, duration)
+		o.Logger.Info(message+" finished", append([]interface{}{"phase", name, "duration", duration}, args...)...)
+	}
+}
@@ -0,0 +1,64 @@
+package loaders
+
+import (
+	"context"
+	"errors"
+	"recipe-stats/adapters"
+	"recipe-stats/metrics"
+	"recipe-stats/queries"
+	"recipe-stats/snapshots"
+)
+
+// ErrSnapshotNotQueryable is returned when querying a snapshot, since it only
+// keeps the pre-processed keepers and not every single delivery.
+var ErrSnapshotNotQueryable = errors.New("snapshots can't be queried since they don't keep every delivery, please use the input JSON file")
+
+// QueryFromGeneralRecipe reads the JSON file at filePath with recipesAdapter,
+// the same one used to build the keepers, and runs the query over every
+// delivery.
+func QueryFromGeneralRecipe(filePath string, query *queries.Query, recipesAdapter adapters.RecordsAdapter, verbose bool) (*queries.Result, error) {
+	return QueryFromGeneralRecipeContext(context.Background(), filePath, query, recipesAdapter, VerboseObserver(verbose))
+}
+
+// QueryFromGeneralRecipeContext works like QueryFromGeneralRecipe, reporting to
+// observer and giving up with the error of ctx as soon as it is canceled.
+func QueryFromGeneralRecipeContext(ctx context.Context, filePath string, query *queries.Query, recipesAdapter adapters.RecordsAdapter, observer Observer) (*queries.Result, error) {
+	if snapshots.IsSnapshot(filePath) {
+		return nil, ErrSnapshotNotQueryable
+	}
+
+	recipes, err := loadGeneralRecipesFile(ctx, filePath, recipesAdapter, nil, nil, observer)
+	if err != nil {
+		return nil, err
+	}
+
+	finished := observer.Phase(metrics.PhaseRunQuery, "Running query")
+
+	result, err := query.ExecuteContext(ctx, rowsFromGeneralRecipe(*recipes))
+	if err != nil {
+		return nil, err
+	}
+
+	finished("rows", len(result.Rows))
+
+	return result, nil
+}
+
+func rowsFromGeneralRecipe(recipes []adapters.GeneralRecipe) []queries.Row {
+	rows := make([]queries.Row, len(recipes))
+
+	for i := 0; i < len(recipes); i++ {
+		recipe := recipes[i].ToRecipe()
+		delivery := recipes[i].ToDelivery()
+
+		rows[i] = queries.Row{
+			Recipe:   recipe.Recipe,
+			Postcode: delivery.Postcode,
+			Weekday:  recipes[i].Delivery.Weekday,
+			From:     delivery.From,
+			To:       delivery.To,
+		}
+	}
+
+	return rows
+}
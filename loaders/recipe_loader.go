@@ -1,39 +1,88 @@
 package loaders
 
 import (
-	"fmt"
-	"os"
+	"context"
 	"recipe-stats/adapters"
 	"recipe-stats/keepers"
-	"sync"
-	"time"
+	"recipe-stats/metrics"
 )
 
-func loadRecipesFromGeneralRecipe(recipes []adapters.GeneralRecipe, wg *sync.WaitGroup, verbose bool) (*keepers.RecipeKeeper, error) {
-	defer wg.Done()
+// loadRecipesFromGeneralRecipe builds the recipe keeper, splitting recipes
+// among up to workers partial keepers merged in the end
+func loadRecipesFromGeneralRecipe(ctx context.Context, recipes []adapters.GeneralRecipe, workers int, observer Observer) (*keepers.RecipeKeeper, error) {
+	finished := observer.Phase(metrics.PhaseLoadRecipes, "Loading recipes")
 
-	start := time.Now()
-	if verbose {
-		fmt.Fprintln(os.Stderr, "Loading recipes...")
+	recipeKeeper, err := mergeRecipes(ctx, recipes, workers, observer)
+	if err != nil {
+		return nil, err
 	}
 
-	recipeKeeper := keepers.NewRecipeKeeper()
+	finished("recipes", recipeKeeper.Count())
 
+	return recipeKeeper, nil
+}
+
+// mergeRecipes builds a recipe keeper out of up to workers partial ones, each
+// built from consecutive recipes
+func mergeRecipes(ctx context.Context, recipes []adapters.GeneralRecipe, workers int, observer Observer) (*keepers.RecipeKeeper, error) {
+	shards := shardRecipes(recipes, workers)
+	partials := make([]keepers.RecipeKeeper, len(shards))
+	err := runShards(shards, func(i int, shard []adapters.GeneralRecipe) error {
+		partials[i] = keepers.NewRecipeKeeper()
+		return addRecipes(ctx, shard, &partials[i], observer)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	recipeKeeper := &partials[0]
+	for i := 1; i < len(partials); i++ {
+		recipeKeeper.Merge(&partials[i])
+	}
+
+	return recipeKeeper, nil
+}
+
+// addRecipes adds the recipe of every record to recipeKeeper
+func addRecipes(ctx context.Context, recipes []adapters.GeneralRecipe, recipeKeeper *keepers.RecipeKeeper, observer Observer) error {
 	for i := 0; i < len(recipes); i++ {
+		if i%keepers.ContextBatch == 0 {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+		}
+
 		recipe := recipes[i].ToRecipe()
 		err := recipeKeeper.Add(recipe)
 
 		if err != nil {
-			if verbose {
-				fmt.Fprintf(os.Stderr, "It was impossible to load data into the calculator. The error was: %s\n", err.Error())
-			}
-			return nil, err
+			observer.Logger.Error("It was impossible to load data into the calculator", "error", err)
+			return err
 		}
 	}
 
-	if verbose {
-		fmt.Fprintf(os.Stderr, "Loading recipes took %s\n", time.Since(start))
+	return nil
+}
+
+// appendRecipesFromGeneralRecipe builds partial keepers out of recipes, split
+// among up to workers of them, and merges them into the loaded ones
+func appendRecipesFromGeneralRecipe(ctx context.Context, recipes []adapters.GeneralRecipe, recipeKeeper *keepers.RecipeKeeper, recipeNameSlicesKeeper *keepers.RecipeNameSlicesKeeper, workers int, observer Observer) error {
+	finished := observer.Phase(metrics.PhaseAppendRecipes, "Appending recipes")
+
+	appended, err := mergeRecipes(ctx, recipes, workers, observer)
+	if err != nil {
+		return err
 	}
 
-	return &recipeKeeper, nil
+	appendedNameSlices := keepers.NewRecipeNameSlicesKeeper()
+	if err := appendedNameSlices.LoadContext(ctx, appended.GetMap()); err != nil {
+		return err
+	}
+
+	recipeKeeper.Merge(appended)
+	recipeNameSlicesKeeper.Merge(&appendedNameSlices)
+
+	finished("recipes", recipeKeeper.Count())
+
+	return nil
 }
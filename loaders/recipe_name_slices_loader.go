@@ -1,29 +1,22 @@
 package loaders
 
 import (
-	"fmt"
-	"os"
+	"context"
 	"recipe-stats/keepers"
+	"recipe-stats/metrics"
 	"recipe-stats/models"
-	"sync"
-	"time"
 )
 
-func loadRecipeNameSlicesFromRecipes(recipes map[string]models.Recipe, wg *sync.WaitGroup, verbose bool) *keepers.RecipeNameSlicesKeeper {
-	defer wg.Done()
-
-	start := time.Now()
-	if verbose {
-		fmt.Fprintln(os.Stderr, "Building recipes map...")
-	}
+func loadRecipeNameSlicesFromRecipes(ctx context.Context, recipes map[string]models.Recipe, observer Observer) (*keepers.RecipeNameSlicesKeeper, error) {
+	finished := observer.Phase(metrics.PhaseBuildRecipeNames, "Building recipes map")
 
 	rnsk := keepers.NewRecipeNameSlicesKeeper()
 
-	rnsk.Load(recipes)
-
-	if verbose {
-		fmt.Fprintf(os.Stderr, "Building recipes map took %s\n", time.Since(start))
+	if err := rnsk.LoadContext(ctx, recipes); err != nil {
+		return nil, err
 	}
 
-	return &rnsk
+	finished()
+
+	return &rnsk, nil
 }
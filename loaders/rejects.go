@@ -0,0 +1,124 @@
+package loaders
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"recipe-stats/adapters"
+	"sync"
+)
+
+// Rejects keeps track of the records that could not be loaded, optionally
+// writing each one of them to a quarantine file as NDJSON so they are not
+// lost. A nil *Rejects is valid and simply ignores the rejected records.
+type Rejects struct {
+	mu     *sync.Mutex
+	count  int
+	file   *os.File
+	writer *bufio.Writer
+	err    error
+}
+
+// rejectedRecord is a single line of the quarantine file.
+type rejectedRecord struct {
+	File   string `json:"file"`
+	Index  int    `json:"index"`
+	Offset int64  `json:"offset"`
+	Class  string `json:"class"`
+	Reason string `json:"reason"`
+	Record string `json:"record"`
+}
+
+// NewRejects provides a usable instance of Rejects. If filePath is not empty,
+// the quarantine file is created (or truncated) there.
+func NewRejects(filePath string) (*Rejects, error) {
+	rejects := &Rejects{mu: new(sync.Mutex)}
+	if filePath == "" {
+		return rejects, nil
+	}
+
+	file, err := os.Create(filePath)
+	if err != nil {
+		return nil, err
+	}
+	rejects.file = file
+	rejects.writer = bufio.NewWriter(file)
+
+	return rejects, nil
+}
+
+// Count returns how many records were rejected so far.
+func (r *Rejects) Count() int {
+	if r == nil {
+		return 0
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.count
+}
+
+// addCount counts rejected records that aren't at hand, such as the ones
+// rejected when building a snapshot.
+func (r *Rejects) addCount(count int) {
+	if r == nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.count += count
+}
+
+// Close flushes and closes the quarantine file, returning the first error
+// found while writing to it.
+func (r *Rejects) Close() error {
+	if r == nil || r.file == nil {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.writer.Flush(); err != nil && r.err == nil {
+		r.err = err
+	}
+	if err := r.file.Close(); err != nil && r.err == nil {
+		r.err = err
+	}
+
+	return r.err
+}
+
+// add returns the function handed to the adapter to reject the records of the
+// file at filePath.
+func (r *Rejects) add(filePath string) func(*adapters.RecordError) {
+	return func(recordError *adapters.RecordError) {
+		if r == nil {
+			return
+		}
+
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		r.count++
+		if r.writer == nil || r.err != nil {
+			return
+		}
+
+		line, err := json.Marshal(rejectedRecord{
+			File:   filePath,
+			Index:  recordError.Index,
+			Offset: recordError.Offset,
+			Class:  recordError.Class,
+			Reason: recordError.Reason,
+			Record: string(recordError.Raw),
+		})
+		if err == nil {
+			_, err = r.writer.Write(append(line, '\n'))
+		}
+		r.err = err
+	}
+}
@@ -0,0 +1,57 @@
+package loaders
+
+import (
+	"recipe-stats/adapters"
+	"sync"
+)
+
+// minShardRecipes is the fewest records worth building partial keepers from,
+// so small inputs are built on a single goroutine
+const minShardRecipes = 4096
+
+// shardRecipes splits recipes into up to workers consecutive shards of about
+// the same size, with at least minShardRecipes records each. There is always
+// one shard, even with no recipes at all.
+func shardRecipes(recipes []adapters.GeneralRecipe, workers int) [][]adapters.GeneralRecipe {
+	shards := workers
+	if maxShards := len(recipes) / minShardRecipes; maxShards < shards {
+		shards = maxShards
+	}
+	if shards <= 1 {
+		return [][]adapters.GeneralRecipe{recipes}
+	}
+
+	result := make([][]adapters.GeneralRecipe, 0, shards)
+	for i := 0; i < shards; i++ {
+		result = append(result, recipes[i*len(recipes)/shards:(i+1)*len(recipes)/shards])
+	}
+
+	return result
+}
+
+// runShards runs build over every shard at the same time, returning the
+// error of the first shard that failed
+func runShards(shards [][]adapters.GeneralRecipe, build func(i int, shard []adapters.GeneralRecipe) error) error {
+	if len(shards) == 1 {
+		return build(0, shards[0])
+	}
+
+	errs := make([]error, len(shards))
+	wg := sync.WaitGroup{}
+	for i := range shards {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = build(i, shards[i])
+		}(i)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,89 @@
+package loaders
+
+import (
+	"context"
+	"recipe-stats/adapters"
+	"recipe-stats/keepers"
+	"recipe-stats/metrics"
+	"recipe-stats/snapshots"
+)
+
+// LoadFromSnapshot loads all the keepers at once from a snapshot previously
+// built by BuildSnapshot, skipping the JSON parsing entirely.
+func LoadFromSnapshot(filePath string, verbose bool) (*keepers.RecipeKeeper, *keepers.RecipeNameSlicesKeeper, *keepers.DeliveryKeeper, error) {
+	return LoadFromSnapshotContext(context.Background(), filePath, nil, VerboseObserver(verbose))
+}
+
+// LoadFromSnapshotContext works like LoadFromSnapshot, reporting to observer
+// and returning the error of ctx if it was canceled. Decoding a snapshot is
+// quick and can't be stopped halfway, so ctx is only checked before and after
+// it. The records rejected when the snapshot was built are counted in rejects,
+// though they can't be written to its quarantine file.
+func LoadFromSnapshotContext(ctx context.Context, filePath string, rejects *Rejects, observer Observer) (*keepers.RecipeKeeper, *keepers.RecipeNameSlicesKeeper, *keepers.DeliveryKeeper, error) {
+	return loadSnapshot(ctx, rejects, observer, func() (*keepers.RecipeKeeper, *keepers.RecipeNameSlicesKeeper, *keepers.DeliveryKeeper, int, error) {
+		return snapshots.ReadFile(filePath)
+	})
+}
+
+// LoadFromSnapshotBytesContext works like LoadFromSnapshotContext over the
+// content of a snapshot already in memory.
+func LoadFromSnapshotBytesContext(ctx context.Context, data []byte, rejects *Rejects, observer Observer) (*keepers.RecipeKeeper, *keepers.RecipeNameSlicesKeeper, *keepers.DeliveryKeeper, error) {
+	return loadSnapshot(ctx, rejects, observer, func() (*keepers.RecipeKeeper, *keepers.RecipeNameSlicesKeeper, *keepers.DeliveryKeeper, int, error) {
+		return snapshots.Decode(data)
+	})
+}
+
+func loadSnapshot(ctx context.Context, rejects *Rejects, observer Observer, decode func() (*keepers.RecipeKeeper, *keepers.RecipeNameSlicesKeeper, *keepers.DeliveryKeeper, int, error)) (*keepers.RecipeKeeper, *keepers.RecipeNameSlicesKeeper, *keepers.DeliveryKeeper, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, nil, nil, err
+	}
+
+	finished := observer.Phase(metrics.PhaseReadSnapshot, "Reading snapshot file")
+
+	recipeKeeper, recipeNameSlicesKeeper, deliveryKeeper, rejected, err := decode()
+	if err != nil {
+		observer.Logger.Error("It was impossible to read the snapshot file", "error", err)
+		return nil, nil, nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, nil, nil, err
+	}
+
+	rejects.addCount(rejected)
+	finished("recipes", recipeKeeper.Count(), "rejected", rejected)
+
+	return recipeKeeper, recipeNameSlicesKeeper, deliveryKeeper, nil
+}
+
+// BuildSnapshot loads the keepers from the JSON file at filePath, decoded with
+// recipesAdapter, and writes them as a snapshot into outputPath, along with
+// how many records were rejected.
+func BuildSnapshot(filePath string, outputPath string, recipesAdapter adapters.RecordsAdapter, verbose bool) error {
+	return BuildSnapshotContext(context.Background(), filePath, outputPath, recipesAdapter, nil, VerboseObserver(verbose))
+}
+
+// BuildSnapshotContext works like BuildSnapshot, handing the records that
+// can't be parsed to rejects, reporting to observer and giving up with the
+// error of ctx as soon as it is canceled, before anything is written. The
+// rejected records are counted even if rejects is nil.
+func BuildSnapshotContext(ctx context.Context, filePath string, outputPath string, recipesAdapter adapters.RecordsAdapter, rejects *Rejects, observer Observer) error {
+	if rejects == nil {
+		rejects, _ = NewRejects("")
+	}
+
+	recipeKeeper, recipeNameSlicesKeeper, deliveryKeeper, err := LoadFromGeneralRecipeContext(ctx, filePath, recipesAdapter, rejects, nil, observer)
+	if err != nil {
+		return err
+	}
+
+	finished := observer.Phase(metrics.PhaseWriteSnapshot, "Writing snapshot file")
+
+	err = snapshots.WriteFile(outputPath, recipeKeeper, recipeNameSlicesKeeper, deliveryKeeper, rejects.Count())
+	if err != nil {
+		return err
+	}
+
+	finished()
+
+	return nil
+}
@@ -0,0 +1,46 @@
+package loaders
+
+import (
+	"context"
+	"errors"
+	"recipe-stats/adapters"
+	"recipe-stats/exporters"
+	"recipe-stats/metrics"
+	"recipe-stats/snapshots"
+)
+
+// ErrSnapshotNotExportable is returned when exporting a snapshot, since it only
+// keeps the pre-processed keepers and not every single delivery.
+var ErrSnapshotNotExportable = errors.New("snapshots can't be exported since they don't keep every delivery, please use the input JSON file")
+
+// ExportToSQLite reads the JSON file at filePath with recipesAdapter, the same
+// one used to build the keepers, and writes the records into a SQLite database
+// at outputPath.
+func ExportToSQLite(filePath string, outputPath string, recipesAdapter adapters.RecordsAdapter, verbose bool) error {
+	return ExportToSQLiteContext(context.Background(), filePath, outputPath, recipesAdapter, VerboseObserver(verbose))
+}
+
+// ExportToSQLiteContext works like ExportToSQLite, reporting to observer and
+// giving up with the error of ctx as soon as it is canceled.
+func ExportToSQLiteContext(ctx context.Context, filePath string, outputPath string, recipesAdapter adapters.RecordsAdapter, observer Observer) error {
+	if snapshots.IsSnapshot(filePath) {
+		return ErrSnapshotNotExportable
+	}
+
+	recipes, err := loadGeneralRecipesFile(ctx, filePath, recipesAdapter, nil, nil, observer)
+	if err != nil {
+		return err
+	}
+
+	finished := observer.Phase(metrics.PhaseWriteSQLite, "Writing SQLite database")
+
+	exporter := exporters.NewSQLiteExporter(outputPath)
+	if err := exporter.ExportContext(ctx, *recipes); err != nil {
+		observer.Logger.Error("It was impossible to write the SQLite database", "error", err)
+		return err
+	}
+
+	finished()
+
+	return nil
+}
@@ -0,0 +1,155 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// writer writes whole lines to w, one at a time, since records are logged
+// from many goroutines while loading
+type writer struct {
+	mu    *sync.Mutex
+	w     io.Writer
+	level Level
+}
+
+func (w writer) Enabled(level Level) bool {
+	return level >= w.level
+}
+
+func (w writer) write(line []byte) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	_, err := w.w.Write(line)
+	return err
+}
+
+// TextHandler writes records as logfmt lines, such as
+// time=2021-03-01T10:00:00.000Z level=INFO msg="Reading recipes file finished" duration=1.5s
+type TextHandler struct {
+	writer
+}
+
+// NewTextHandler creates a TextHandler writing the records at level or above
+// to w.
+func NewTextHandler(w io.Writer, level Level) *TextHandler {
+	return &TextHandler{writer{mu: new(sync.Mutex), w: w, level: level}}
+}
+
+// Handle writes record as a line.
+func (h *TextHandler) Handle(record Record) error {
+	line := bytes.Buffer{}
+	line.WriteString("time=")
+	line.WriteString(record.Time.Format(timeFormat))
+	line.WriteString(" level=")
+	line.WriteString(record.Level.String())
+	line.WriteString(" msg=")
+	line.WriteString(quoteText(record.Message))
+
+	for _, attr := range record.Attrs {
+		line.WriteByte(' ')
+		line.WriteString(quoteText(attr.Key))
+		line.WriteByte('=')
+		line.WriteString(quoteText(textValue(attr.Value)))
+	}
+	line.WriteByte('\n')
+
+	return h.write(line.Bytes())
+}
+
+// JSONHandler writes records as JSON objects, one per line, such as
+// {"time":"2021-03-01T10:00:00.000Z","level":"INFO","msg":"Reading recipes file finished","duration":1500000000}
+type JSONHandler struct {
+	writer
+}
+
+// NewJSONHandler creates a JSONHandler writing the records at level or above
+// to w.
+func NewJSONHandler(w io.Writer, level Level) *JSONHandler {
+	return &JSONHandler{writer{mu: new(sync.Mutex), w: w, level: level}}
+}
+
+// Handle writes record as a line. Durations are written in nanoseconds and
+// errors as their messages, as log/slog does.
+func (h *JSONHandler) Handle(record Record) error {
+	line := bytes.Buffer{}
+	line.WriteString(`{"time":`)
+	writeJSON(&line, record.Time.Format(timeFormat))
+	line.WriteString(`,"level":`)
+	writeJSON(&line, record.Level.String())
+	line.WriteString(`,"msg":`)
+	writeJSON(&line, record.Message)
+
+	for _, attr := range record.Attrs {
+		line.WriteByte(',')
+		writeJSON(&line, attr.Key)
+		line.WriteByte(':')
+		writeJSON(&line, jsonValue(attr.Value))
+	}
+	line.WriteString("}\n")
+
+	return h.write(line.Bytes())
+}
+
+// timeFormat is RFC 3339 with milliseconds, as log/slog writes times
+const timeFormat = "2006-01-02T15:04:05.000Z07:00"
+
+func textValue(value interface{}) string {
+	switch value := value.(type) {
+	case string:
+		return value
+	case error:
+		return value.Error()
+	case time.Time:
+		return value.Format(timeFormat)
+	case fmt.Stringer:
+		return value.String()
+	}
+
+	return fmt.Sprint(value)
+}
+
+// quoteText quotes s when it would be ambiguous in a logfmt line
+func quoteText(s string) string {
+	if s == "" {
+		return `""`
+	}
+	for _, r := range s {
+		if unicode.IsSpace(r) || r == '"' || r == '=' || !unicode.IsPrint(r) {
+			return strconv.Quote(s)
+		}
+	}
+
+	return s
+}
+
+func jsonValue(value interface{}) interface{} {
+	switch value := value.(type) {
+	case time.Duration:
+		return int64(value)
+	case error:
+		return value.Error()
+	case time.Time:
+		return value.Format(timeFormat)
+	}
+
+	return value
+}
+
+// writeJSON writes value encoded as JSON, or the error encoding it as a string
+// if it can't be
+func writeJSON(line *bytes.Buffer, value interface{}) {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		encoded, _ = json.Marshal(fmt.Sprintf("!ERROR:%s", err.Error()))
+	}
+
+	line.Write(encoded)
+}
@@ -0,0 +1,170 @@
+// Package logging is a small structured logger in the shape of log/slog, which
+// the Go version this project builds with doesn't have yet: a Logger formats
+// leveled messages along with key-value attributes, and hands them to a
+// Handler that writes them as text or JSON. Moving to log/slog later should
+// only take replacing the imports.
+package logging
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Level is how important a message is. The values are the ones of log/slog.
+type Level int
+
+// The levels a message can be logged at.
+const (
+	LevelDebug Level = -4
+	LevelInfo  Level = 0
+	LevelWarn  Level = 4
+	LevelError Level = 8
+)
+
+func (l Level) String() string {
+	switch l {
+	case LevelDebug:
+		return "DEBUG"
+	case LevelInfo:
+		return "INFO"
+	case LevelWarn:
+		return "WARN"
+	case LevelError:
+		return "ERROR"
+	}
+
+	return fmt.Sprintf("Level(%d)", int(l))
+}
+
+// ParseLevel returns the level named name, such as debug or INFO.
+func ParseLevel(name string) (Level, error) {
+	switch strings.ToLower(name) {
+	case "debug":
+		return LevelDebug, nil
+	case "info":
+		return LevelInfo, nil
+	case "warn", "warning":
+		return LevelWarn, nil
+	case "error":
+		return LevelError, nil
+	}
+
+	return 0, fmt.Errorf("unknown log level %q, expected debug, info, warn or error", name)
+}
+
+// badKey is the key of a value logged without one, as log/slog does
+const badKey = "!BADKEY"
+
+// Attr is a key-value attribute of a message.
+type Attr struct {
+	Key   string
+	Value interface{}
+}
+
+// Record is a message logged along with its attributes.
+type Record struct {
+	Time    time.Time
+	Level   Level
+	Message string
+	Attrs   []Attr
+}
+
+// Handler writes the records of a Logger somewhere.
+type Handler interface {
+	// Enabled tells whether records at level are written at all, so they
+	// don't need to be built otherwise
+	Enabled(level Level) bool
+	Handle(record Record) error
+}
+
+// Logger logs messages to its Handler. A nil Logger discards everything, so
+// it can be left out wherever one is optional.
+type Logger struct {
+	handler Handler
+	attrs   []Attr
+}
+
+// New creates a Logger writing to handler.
+func New(handler Handler) *Logger {
+	return &Logger{handler: handler}
+}
+
+// With returns a Logger that adds the attributes in args, alternating keys and
+// values, to every message.
+func (l *Logger) With(args ...interface{}) *Logger {
+	if l == nil {
+		return nil
+	}
+
+	attrs := make([]Attr, 0, len(l.attrs)+len(args)/2)
+	attrs = append(attrs, l.attrs...)
+
+	return &Logger{handler: l.handler, attrs: append(attrs, toAttrs(args)...)}
+}
+
+// Enabled tells whether messages at level are written.
+func (l *Logger) Enabled(level Level) bool {
+	return l != nil && l.handler != nil && l.handler.Enabled(level)
+}
+
+// Debug logs msg at LevelDebug, along with the attributes in args.
+func (l *Logger) Debug(msg string, args ...interface{}) {
+	l.Log(LevelDebug, msg, args...)
+}
+
+// Info logs msg at LevelInfo, along with the attributes in args.
+func (l *Logger) Info(msg string, args ...interface{}) {
+	l.Log(LevelInfo, msg, args...)
+}
+
+// Warn logs msg at LevelWarn, along with the attributes in args.
+func (l *Logger) Warn(msg string, args ...interface{}) {
+	l.Log(LevelWarn, msg, args...)
+}
+
+// Error logs msg at LevelError, along with the attributes in args.
+func (l *Logger) Error(msg string, args ...interface{}) {
+	l.Log(LevelError, msg, args...)
+}
+
+// Log logs msg at level, along with the attributes in args, alternating keys
+// and values. Errors writing the message are ignored, as there is nowhere left
+// to report them.
+func (l *Logger) Log(level Level, msg string, args ...interface{}) {
+	if !l.Enabled(level) {
+		return
+	}
+
+	attrs := make([]Attr, 0, len(l.attrs)+len(args)/2)
+	attrs = append(attrs, l.attrs...)
+	attrs = append(attrs, toAttrs(args)...)
+
+	_ = l.handler.Handle(Record{Time: time.Now(), Level: level, Message: msg, Attrs: attrs})
+}
+
+// toAttrs pairs the keys and values of args. An Attr is taken as is, and a
+// value without a string key before it gets badKey.
+func toAttrs(args []interface{}) []Attr {
+	attrs := []Attr{}
+	for len(args) > 0 {
+		switch key := args[0].(type) {
+		case Attr:
+			attrs = append(attrs, key)
+			args = args[1:]
+		case string:
+			if len(args) == 1 {
+				attrs = append(attrs, Attr{Key: badKey, Value: key})
+				args = nil
+				continue
+			}
+			attrs = append(attrs, Attr{Key: key, Value: args[1]})
+			args = args[2:]
+		default:
+			attrs = append(attrs, Attr{Key: badKey, Value: key})
+			args = args[1:]
+		}
+	}
+
+	return attrs
+}
@@ -11,11 +11,11 @@ Application breakdown
 		interactiveFlow() or runFromCli() methods.
 		Contains the CLI flags configurations, as well as the loading of config.yml.
 	- interactive.go
-		Contains all the logic to handle the interactive mode.
+		Starts the interactive mode, binding its flow to the loaders and runner.
 	- runner.go
-		Is the common point of contact from root.go and interactive.go and handles
-		the necessary execution steps in the correct order. This is where the pieces
-		get bound together, producing the final calculation.
+		Is the common point of contact from root.go and interactive.go. It opens
+		the dataset through the recipestats package and turns its stats into the
+		JSON output.
 - adapters
 	Contains the Adapter and AdapterMember interfaces that aims to provide a
 	baseline for input types (aiming on scaling support for input types).
@@ -23,13 +23,33 @@ Application breakdown
 		Is the adapter for the fixtures file provided on the requirements. It contains
 		all the specific methods to transform the input file into collections of
 		Recipes and Deliveries.
+	- general_recipe_validator.go
+		Contains the strict checks over each record of the input file, used to
+		report data-quality problems.
+	- delivery_parser.go
+		Contains the parsing policies that decide which variations of the
+		delivery string are accepted, along with their leniency rules.
+	- schema.go
+		Contains the Schema that maps the fields of other input JSON formats,
+		and picks the adapter for a given schema.
+	- mapped_recipe_adapter.go
+		Is the generic adapter for input files described by a Schema, with nested
+		paths and structured delivery slots.
+	- record_shards.go
+		Splits the records array into byte ranges at record boundaries found by
+		seeking into the file, so the adapters decode them with many workers,
+		putting the results back in the order of the file.
 - keepers
 	Keepers contains the files that holds the collections of pre-processed data of
 	Recipes, Deliveries and Recipes Names Slices. Those files  also contains the
 	methods can calculate the desired outputs.
+	Every keeper can Merge another one of its kind, which is how the keepers
+	built by many workers, or out of appended files, are put together.
 	- delivery_keeper.go
-		Holds the collections of deliveries and the methods to add deliveries,
-		calculate the busiest postcode and searching for deliveries intervals.
+		Holds how many deliveries every postcode has within every time range and
+		the methods to add deliveries, calculate the busiest postcode and
+		searching for deliveries intervals. It only keeps the deliveries
+		themselves when created to retain them.
 	- recipe_keeper.go
 		Holds the collection of distinct recipes and the methods to add recipes,
 		search by name and count the total of recipes.
@@ -42,11 +62,59 @@ Application breakdown
 	general_recipe_loader.go is the central loader for GeneralRecipe and it manages the
 	loading order and parallelization of tasks. In the end, it provides instances
 	of the required keepers so the runner can execute the calculations.
+	Every loader has a Context variant that checks for cancellation between
+	batches of records, the plain one being a thin wrapper over it. The Context
+	variants report every step to an Observer, a logger along with a metrics
+	Recorder, in place of the verbose flag of the plain ones.
 - models
 	Models are the basic common types where the data used throughout the
 	application relies on. Every input data gets transformed into one of the
 	models so the keepers don't need to change if new input formats should be
 	supported.
+- exporters
+	Exporters write the normalised dataset into other formats, so it can be
+	analysed by other tools.
+	- sqlite_exporter.go
+		Writes recipes, postcodes and deliveries into a SQLite database.
+- queries
+	Contains a small SQL-like language, with its parser and executor, to run
+	ad-hoc queries over every single delivery without writing one-off scripts.
+- shell
+	Contains the commands, tab-completion and line editing of the shell mode,
+	that runs commands against keepers loaded only once.
+- server
+	Answers the stats of a dataset over HTTP for the serve command, reloading
+	it on demand and serving its metrics to Prometheus.
+- interactive
+	Contains the interactive flow, a state machine going from choosing a file to
+	showing the results that can be tested with scripted answers, along with the
+	validators of its questions, such as file paths, times and postcode
+	suggestions.
+- history
+	Keeps the history of the queries run in interactive mode, along with the
+	saved ones that can be run with the run command.
+- snapshots
+	Contains the versioned binary format used to persist the keepers, along with
+	its checksum verification. The flag mode, the interactive mode, shell,
+	serve and run accept a snapshot as their --file in place of an input JSON
+	file.
+- recipestats
+	The public API to embed the calculations in other programs. A Dataset is
+	opened from a file or loaded from a reader, configured with functional
+	options, and returns typed results. The CLI is a thin client of it.
+- logging
+	A small structured logger in the shape of log/slog, with text and JSON
+	handlers and levels, used for the messages printed by --verbose.
+- metrics
+	Contains the Recorder interface that receives how long every loading phase
+	took, along with the records, bytes and rejects read, and the Collector
+	that keeps them for the timing summary. Its Prometheus recorder also keeps
+	keeper sizes and query latencies, and serves them in the Prometheus text
+	format as the /metrics of the serve command.
+- generator
+	Writes synthetic input files of any size, with their recipe vocabulary,
+	postcode and delivery window distributions set by a Config and a seed, used
+	by the generate command and the benchmarks.
 - reporters
 	Reporters contains the structure and encoding methods to generate an output in
 	a desired format.
@@ -0,0 +1,133 @@
+// Package metrics records how the loading and the calculations went: how long
+// each phase took, how many records and bytes were read and how many records
+// were rejected. A Recorder receives them as they happen, so they can be
+// collected for a summary or exported to a monitoring system.
+package metrics
+
+import (
+	"sync"
+	"time"
+)
+
+// The phases recorded while loading and calculating.
+const (
+	PhaseReadRecipes      = "read_recipes"
+	PhaseLoadRecipes      = "load_recipes"
+	PhaseBuildRecipeNames = "build_recipe_names"
+	PhaseMapDeliveries    = "map_deliveries"
+	PhaseAppendRecipes    = "append_recipes"
+	PhaseAppendDeliveries = "append_deliveries"
+	PhaseReadSnapshot     = "read_snapshot"
+	PhaseWriteSnapshot    = "write_snapshot"
+	PhaseWriteSQLite      = "write_sqlite"
+	PhaseRunQuery         = "run_query"
+	PhaseValidate         = "validate"
+	PhaseCalculate        = "calculate"
+	PhaseTotal            = "total"
+)
+
+// Recorder receives the metrics as they happen. It is called from many
+// goroutines while loading.
+type Recorder interface {
+	// Phase records that the phase named name took duration
+	Phase(name string, duration time.Duration)
+	// Records records that count records were read
+	Records(count int)
+	// BytesRead records that count bytes of input were read
+	BytesRead(count int64)
+	// Rejects records that count records were rejected
+	Rejects(count int)
+}
+
+// Nop is a Recorder that discards everything.
+type Nop struct{}
+
+// Phase does nothing.
+func (Nop) Phase(name string, duration time.Duration) {}
+
+// Records does nothing.
+func (Nop) Records(count int) {}
+
+// BytesRead does nothing.
+func (Nop) BytesRead(count int64) {}
+
+// Rejects does nothing.
+func (Nop) Rejects(count int) {}
+
+// PhaseSummary is how long every run of a phase took, added up.
+type PhaseSummary struct {
+	Name     string
+	Duration time.Duration
+	Runs     int
+}
+
+// Summary is everything a Collector recorded.
+type Summary struct {
+	// Phases are in the order they first finished
+	Phases    []PhaseSummary
+	Records   int
+	BytesRead int64
+	Rejects   int
+}
+
+// Collector is a Recorder that keeps everything it receives in memory.
+type Collector struct {
+	mu      *sync.Mutex
+	summary Summary
+}
+
+// NewCollector creates an empty Collector.
+func NewCollector() *Collector {
+	return &Collector{mu: new(sync.Mutex)}
+}
+
+// Phase adds duration to the phase named name.
+func (c *Collector) Phase(name string, duration time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i := range c.summary.Phases {
+		if c.summary.Phases[i].Name == name {
+			c.summary.Phases[i].Duration += duration
+			c.summary.Phases[i].Runs++
+			return
+		}
+	}
+
+	c.summary.Phases = append(c.summary.Phases, PhaseSummary{Name: name, Duration: duration, Runs: 1})
+}
+
+// Records adds count to the records read.
+func (c *Collector) Records(count int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.summary.Records += count
+}
+
+// BytesRead adds count to the bytes read.
+func (c *Collector) BytesRead(count int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.summary.BytesRead += count
+}
+
+// Rejects adds count to the records rejected.
+func (c *Collector) Rejects(count int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.summary.Rejects += count
+}
+
+// Summary returns a copy of everything recorded so far.
+func (c *Collector) Summary() Summary {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	summary := c.summary
+	summary.Phases = append([]PhaseSummary{}, c.summary.Phases...)
+
+	return summary
+}
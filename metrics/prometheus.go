@@ -0,0 +1,217 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PrometheusContentType is the content type of the Prometheus text format.
+const PrometheusContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// QueryBuckets are the upper bounds, in seconds, of the buckets of the query
+// latency histograms, the default ones of the Prometheus clients.
+var QueryBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// histogram counts observations into cumulative buckets
+type histogram struct {
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+func (h *histogram) observe(value float64) {
+	for i, bound := range QueryBuckets {
+		if value <= bound {
+			h.counts[i]++
+		}
+	}
+	h.count++
+	h.sum += value
+}
+
+// Prometheus is a Recorder for long-lived processes, keeping the metrics of
+// every load along with the sizes of the keepers and the latency of the
+// queries, and writing them in the Prometheus text format. It is an
+// http.Handler, meant to be served as /metrics.
+type Prometheus struct {
+	mu         *sync.Mutex
+	phases     map[string]*PhaseSummary
+	records    int
+	bytesRead  int64
+	rejects    int
+	recipes    int
+	postcodes  int
+	nameSlices int
+	lastReload time.Time
+	queries    map[string]*histogram
+}
+
+// NewPrometheus creates a Prometheus with nothing recorded yet.
+func NewPrometheus() *Prometheus {
+	return &Prometheus{mu: new(sync.Mutex), phases: map[string]*PhaseSummary{}, queries: map[string]*histogram{}}
+}
+
+// Phase adds duration to the phase named name.
+func (p *Prometheus) Phase(name string, duration time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	phase, ok := p.phases[name]
+	if !ok {
+		phase = &PhaseSummary{Name: name}
+		p.phases[name] = phase
+	}
+	phase.Duration += duration
+	phase.Runs++
+}
+
+// Records adds count to the records loaded.
+func (p *Prometheus) Records(count int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.records += count
+}
+
+// BytesRead adds count to the bytes read.
+func (p *Prometheus) BytesRead(count int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.bytesRead += count
+}
+
+// Rejects adds count to the records rejected.
+func (p *Prometheus) Rejects(count int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.rejects += count
+}
+
+// SetKeeperSizes sets how many distinct recipes, postcodes and recipe name
+// slices the keepers hold.
+func (p *Prometheus) SetKeeperSizes(recipes int, postcodes int, nameSlices int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.recipes, p.postcodes, p.nameSlices = recipes, postcodes, nameSlices
+}
+
+// Reloaded records that the dataset was last loaded at.
+func (p *Prometheus) Reloaded(at time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.lastReload = at
+}
+
+// ObserveQuery records that a query to endpoint took duration.
+func (p *Prometheus) ObserveQuery(endpoint string, duration time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	latency, ok := p.queries[endpoint]
+	if !ok {
+		latency = &histogram{counts: make([]uint64, len(QueryBuckets))}
+		p.queries[endpoint] = latency
+	}
+	latency.observe(duration.Seconds())
+}
+
+// ServeHTTP writes the metrics in the Prometheus text format.
+func (p *Prometheus) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", PrometheusContentType)
+	_ = p.Write(w)
+}
+
+// Write writes the metrics to w in the Prometheus text format, with the
+// labels sorted so the output is stable.
+func (p *Prometheus) Write(w io.Writer) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	out := bufio.NewWriter(w)
+
+	header(out, "recipe_stats_load_phase_duration_seconds", "summary", "Time spent on every loading phase.")
+	names := make([]string, 0, len(p.phases))
+	for name := range p.phases {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		phase := p.phases[name]
+		fmt.Fprintf(out, "recipe_stats_load_phase_duration_seconds_sum{phase=%s} %s\n", label(name), number(phase.Duration.Seconds()))
+		fmt.Fprintf(out, "recipe_stats_load_phase_duration_seconds_count{phase=%s} %d\n", label(name), phase.Runs)
+	}
+
+	sample(out, "recipe_stats_records_loaded_total", "counter", "Records read from the input files.", float64(p.records))
+	sample(out, "recipe_stats_bytes_read_total", "counter", "Bytes read from the input files.", float64(p.bytesRead))
+	sample(out, "recipe_stats_records_rejected_total", "counter", "Records that couldn't be parsed.", float64(p.rejects))
+	sample(out, "recipe_stats_recipes", "gauge", "Distinct recipes held by the keepers.", float64(p.recipes))
+	sample(out, "recipe_stats_postcodes", "gauge", "Distinct postcodes held by the keepers.", float64(p.postcodes))
+	sample(out, "recipe_stats_recipe_name_slices", "gauge", "Recipe name slices held by the keepers.", float64(p.nameSlices))
+
+	lastReload := 0.0
+	if !p.lastReload.IsZero() {
+		lastReload = float64(p.lastReload.UnixNano()) / float64(time.Second)
+	}
+	sample(out, "recipe_stats_last_reload_timestamp_seconds", "gauge", "When the dataset was last loaded, in seconds since the epoch.", lastReload)
+
+	header(out, "recipe_stats_query_duration_seconds", "histogram", "Latency of the queries per endpoint.")
+	endpoints := make([]string, 0, len(p.queries))
+	for endpoint := range p.queries {
+		endpoints = append(endpoints, endpoint)
+	}
+	sort.Strings(endpoints)
+	for _, endpoint := range endpoints {
+		latency := p.queries[endpoint]
+		for i, bound := range QueryBuckets {
+			fmt.Fprintf(out, "recipe_stats_query_duration_seconds_bucket{endpoint=%s,le=%s} %d\n", label(endpoint), label(number(bound)), latency.counts[i])
+		}
+		fmt.Fprintf(out, "recipe_stats_query_duration_seconds_bucket{endpoint=%s,le=\"+Inf\"} %d\n", label(endpoint), latency.count)
+		fmt.Fprintf(out, "recipe_stats_query_duration_seconds_sum{endpoint=%s} %s\n", label(endpoint), number(latency.sum))
+		fmt.Fprintf(out, "recipe_stats_query_duration_seconds_count{endpoint=%s} %d\n", label(endpoint), latency.count)
+	}
+
+	return out.Flush()
+}
+
+func header(out io.Writer, name string, kind string, help string) {
+	fmt.Fprintf(out, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+func sample(out io.Writer, name string, kind string, help string, value float64) {
+	header(out, name, kind, help)
+	fmt.Fprintf(out, "%s %s\n", name, number(value))
+}
+
+// number formats value the way Prometheus reads it
+func number(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	case math.IsNaN(value):
+		return "NaN"
+	}
+
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+// labelEscaper escapes the characters a label value can't hold as they are
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// label quotes value as a label value
+func label(value string) string {
+	return `"` + labelEscaper.Replace(value) + `"`
+}
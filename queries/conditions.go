@@ -0,0 +1,127 @@
+package queries
+
+import (
+	"strings"
+)
+
+// condition is a boolean expression over a row, used by WHERE.
+type condition interface {
+	match(r *Row) bool
+}
+
+type andCondition struct {
+	left  condition
+	right condition
+}
+
+func (c andCondition) match(r *Row) bool {
+	return c.left.match(r) && c.right.match(r)
+}
+
+type orCondition struct {
+	left  condition
+	right condition
+}
+
+func (c orCondition) match(r *Row) bool {
+	return c.left.match(r) || c.right.match(r)
+}
+
+type notCondition struct {
+	inner condition
+}
+
+func (c notCondition) match(r *Row) bool {
+	return !c.inner.match(r)
+}
+
+// comparison compares a field against a value of the same type.
+type comparison struct {
+	field    string
+	operator string
+	value    interface{}
+}
+
+func (c comparison) match(r *Row) bool {
+	result := compareValues(r.value(c.field), c.value)
+
+	switch c.operator {
+	case "=":
+		return result == 0
+	case "!=":
+		return result != 0
+	case "<":
+		return result < 0
+	case "<=":
+		return result <= 0
+	case ">":
+		return result > 0
+	case ">=":
+		return result >= 0
+	}
+
+	return false
+}
+
+type inCondition struct {
+	field  string
+	values []interface{}
+	negate bool
+}
+
+func (c inCondition) match(r *Row) bool {
+	value := r.value(c.field)
+	for _, candidate := range c.values {
+		if compareValues(value, candidate) == 0 {
+			return !c.negate
+		}
+	}
+
+	return c.negate
+}
+
+// likeCondition matches a text field against a pattern, where % matches any
+// sequence of characters and _ matches a single character. Like in SQLite,
+// the matching ignores the case of ASCII letters.
+type likeCondition struct {
+	field   string
+	pattern string
+	negate  bool
+}
+
+func (c likeCondition) match(r *Row) bool {
+	value, _ := r.value(c.field).(string)
+
+	return like(strings.ToLower(value), strings.ToLower(c.pattern)) != c.negate
+}
+
+// like is an iterative wildcard matcher that backtracks to the last % found,
+// so it never takes more than len(value) * len(pattern) steps.
+func like(value string, pattern string) bool {
+	v, p := 0, 0
+	lastWildcard, lastMatch := -1, 0
+
+	for v < len(value) {
+		switch {
+		case p < len(pattern) && (pattern[p] == '_' || pattern[p] == value[v]):
+			v++
+			p++
+		case p < len(pattern) && pattern[p] == '%':
+			lastWildcard = p
+			lastMatch = v
+			p++
+		case lastWildcard >= 0:
+			p = lastWildcard + 1
+			lastMatch++
+			v = lastMatch
+		default:
+			return false
+		}
+	}
+
+	for p < len(pattern) && pattern[p] == '%' {
+		p++
+	}
+
+	return p == len(pattern)
+}
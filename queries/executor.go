@@ -0,0 +1,219 @@
+package queries
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Result is the outcome of a query. Values are strings, ints, float64 (for
+// avg) or nil (for min, max and avg when there were no rows).
+type Result struct {
+	Columns []string
+	Rows    [][]interface{}
+}
+
+// accumulator keeps the running state of an aggregate within a group.
+type accumulator struct {
+	count int
+	sum   int
+	min   interface{}
+	max   interface{}
+}
+
+func (a *accumulator) add(value interface{}) {
+	a.count++
+	if number, ok := value.(int); ok {
+		a.sum += number
+	}
+	if a.min == nil || compareValues(value, a.min) < 0 {
+		a.min = value
+	}
+	if a.max == nil || compareValues(value, a.max) > 0 {
+		a.max = value
+	}
+}
+
+func (a *accumulator) result(aggregate string) interface{} {
+	switch aggregate {
+	case "count":
+		return a.count
+	case "sum":
+		return a.sum
+	case "avg":
+		if a.count == 0 {
+			return nil
+		}
+		return float64(a.sum) / float64(a.count)
+	case "min":
+		return a.min
+	case "max":
+		return a.max
+	}
+
+	return nil
+}
+
+// group holds the first row of a group, used for the grouped fields, and the
+// accumulators for every selected column.
+type group struct {
+	first        *Row
+	accumulators []accumulator
+}
+
+// contextBatch is how many rows are matched between checks of the context for
+// cancellation
+const contextBatch = 1024
+
+// Execute runs the query over rows.
+func (q *Query) Execute(rows []Row) *Result {
+	result, _ := q.ExecuteContext(context.Background(), rows)
+	return result
+}
+
+// ExecuteContext works like Execute, giving up with the error of ctx as soon
+// as it is canceled.
+func (q *Query) ExecuteContext(ctx context.Context, rows []Row) (*Result, error) {
+	result := &Result{Columns: make([]string, len(q.Columns))}
+	for i, column := range q.Columns {
+		result.Columns[i] = column.Name
+	}
+
+	var err error
+	if q.aggregated() {
+		result.Rows, err = q.aggregate(ctx, rows)
+	} else {
+		result.Rows, err = q.project(ctx, rows)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	q.sort(result.Rows)
+
+	if q.limit >= 0 && len(result.Rows) > q.limit {
+		result.Rows = result.Rows[:q.limit]
+	}
+
+	return result, nil
+}
+
+func (q *Query) project(ctx context.Context, rows []Row) ([][]interface{}, error) {
+	values := [][]interface{}{}
+
+	for i := range rows {
+		if i%contextBatch == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+		if q.where != nil && !q.where.match(&rows[i]) {
+			continue
+		}
+
+		value := make([]interface{}, len(q.Columns))
+		for j, column := range q.Columns {
+			value[j] = rows[i].value(column.expr.field)
+		}
+		values = append(values, value)
+	}
+
+	return values, nil
+}
+
+// aggregate groups the rows, keeping the groups in the order they were first
+// found so the output is stable when there is no ORDER BY.
+func (q *Query) aggregate(ctx context.Context, rows []Row) ([][]interface{}, error) {
+	groups := map[string]*group{}
+	order := []*group{}
+	key := strings.Builder{}
+
+	for i := range rows {
+		if i%contextBatch == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+		row := &rows[i]
+		if q.where != nil && !q.where.match(row) {
+			continue
+		}
+
+		key.Reset()
+		for _, field := range q.groupBy {
+			key.WriteString(valueKey(row.value(field)))
+			key.WriteByte(0)
+		}
+
+		found, exists := groups[key.String()]
+		if !exists {
+			found = &group{first: row, accumulators: make([]accumulator, len(q.Columns))}
+			groups[key.String()] = found
+			order = append(order, found)
+		}
+
+		for j, column := range q.Columns {
+			if column.expr.aggregate == "" {
+				continue
+			}
+			if column.expr.field == "" {
+				found.accumulators[j].count++
+			} else {
+				found.accumulators[j].add(row.value(column.expr.field))
+			}
+		}
+	}
+
+	// aggregating without grouping always produces a single row
+	if len(q.groupBy) == 0 && len(order) == 0 {
+		order = append(order, &group{accumulators: make([]accumulator, len(q.Columns))})
+	}
+
+	values := make([][]interface{}, 0, len(order))
+	for _, found := range order {
+		value := make([]interface{}, len(q.Columns))
+		for j, column := range q.Columns {
+			if column.expr.aggregate == "" {
+				value[j] = found.first.value(column.expr.field)
+			} else {
+				value[j] = found.accumulators[j].result(column.expr.aggregate)
+			}
+		}
+		values = append(values, value)
+	}
+
+	return values, nil
+}
+
+func (q *Query) sort(values [][]interface{}) {
+	if len(q.orderBy) == 0 {
+		return
+	}
+
+	sort.SliceStable(values, func(i, j int) bool {
+		for _, term := range q.orderBy {
+			result := compareValues(values[i][term.column], values[j][term.column])
+			if result == 0 {
+				continue
+			}
+			if term.descending {
+				return result > 0
+			}
+			return result < 0
+		}
+		return false
+	})
+}
+
+// valueKey renders a field value as part of a group key.
+func valueKey(value interface{}) string {
+	switch value := value.(type) {
+	case string:
+		return value
+	case int:
+		return strconv.Itoa(value)
+	}
+
+	return ""
+}
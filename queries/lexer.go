@@ -0,0 +1,166 @@
+package queries
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// tokenKind identifies the different kinds of tokens of a query.
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdentifier
+	tokenKeyword
+	tokenNumber
+	tokenString
+	tokenOperator
+	tokenComma
+	tokenLeftParen
+	tokenRightParen
+	tokenStar
+)
+
+// token is a single lexical unit of a query. Keywords and identifiers are
+// kept in upper and lower case respectively, so the parser doesn't need to
+// care about the case used in the query.
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+// keywords are the reserved words of the query language.
+var keywords = map[string]bool{
+	"SELECT": true,
+	"FROM":   true,
+	"WHERE":  true,
+	"AND":    true,
+	"OR":     true,
+	"NOT":    true,
+	"LIKE":   true,
+	"IN":     true,
+	"GROUP":  true,
+	"ORDER":  true,
+	"BY":     true,
+	"ASC":    true,
+	"DESC":   true,
+	"LIMIT":  true,
+	"AS":     true,
+}
+
+// SyntaxError describes an invalid query and the position, counted in bytes
+// from the start of the query, where the problem was found.
+type SyntaxError struct {
+	Pos     int
+	Message string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("syntax error at position %d: %s", e.Pos, e.Message)
+}
+
+// tokenize breaks the query into tokens, always finishing with a tokenEOF.
+func tokenize(query string) ([]token, error) {
+	tokens := []token{}
+
+	for i := 0; i < len(query); {
+		char := rune(query[i])
+
+		switch {
+		case unicode.IsSpace(char):
+			i++
+		case char == ',':
+			tokens = append(tokens, token{kind: tokenComma, text: ",", pos: i})
+			i++
+		case char == '(':
+			tokens = append(tokens, token{kind: tokenLeftParen, text: "(", pos: i})
+			i++
+		case char == ')':
+			tokens = append(tokens, token{kind: tokenRightParen, text: ")", pos: i})
+			i++
+		case char == '*':
+			tokens = append(tokens, token{kind: tokenStar, text: "*", pos: i})
+			i++
+		case char == '=':
+			tokens = append(tokens, token{kind: tokenOperator, text: "=", pos: i})
+			i++
+		case char == '<' || char == '>' || char == '!':
+			operator := string(char)
+			if i+1 < len(query) && (query[i+1] == '=' || (char == '<' && query[i+1] == '>')) {
+				operator += string(query[i+1])
+			}
+			if operator == "!" {
+				return nil, &SyntaxError{Pos: i, Message: "unexpected character '!'"}
+			}
+			if operator == "<>" {
+				operator = "!="
+			}
+			tokens = append(tokens, token{kind: tokenOperator, text: operator, pos: i})
+			i += len(operator)
+		case char == '\'':
+			value, length, err := readQuoted(query[i:], '\'')
+			if err != nil {
+				return nil, &SyntaxError{Pos: i, Message: err.Error()}
+			}
+			tokens = append(tokens, token{kind: tokenString, text: value, pos: i})
+			i += length
+		case char == '"':
+			value, length, err := readQuoted(query[i:], '"')
+			if err != nil {
+				return nil, &SyntaxError{Pos: i, Message: err.Error()}
+			}
+			// a double quoted word is always an identifier, even if it is a keyword
+			tokens = append(tokens, token{kind: tokenIdentifier, text: strings.ToLower(value), pos: i})
+			i += length
+		case char >= '0' && char <= '9' || char == '-':
+			start := i
+			i++
+			for i < len(query) && query[i] >= '0' && query[i] <= '9' {
+				i++
+			}
+			if query[start:i] == "-" {
+				return nil, &SyntaxError{Pos: start, Message: "unexpected character '-'"}
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: query[start:i], pos: start})
+		case char == '_' || unicode.IsLetter(char):
+			start := i
+			for i < len(query) && (query[i] == '_' || unicode.IsLetter(rune(query[i])) || unicode.IsDigit(rune(query[i]))) {
+				i++
+			}
+			word := query[start:i]
+			if keywords[strings.ToUpper(word)] {
+				tokens = append(tokens, token{kind: tokenKeyword, text: strings.ToUpper(word), pos: start})
+			} else {
+				tokens = append(tokens, token{kind: tokenIdentifier, text: strings.ToLower(word), pos: start})
+			}
+		default:
+			return nil, &SyntaxError{Pos: i, Message: fmt.Sprintf("unexpected character '%c'", char)}
+		}
+	}
+
+	return append(tokens, token{kind: tokenEOF, pos: len(query)}), nil
+}
+
+// readQuoted reads a quoted value from the start of input, where a doubled
+// quote character stands for the quote itself. It returns the unquoted value
+// and how many bytes were consumed.
+func readQuoted(input string, quote byte) (string, int, error) {
+	value := strings.Builder{}
+
+	for i := 1; i < len(input); i++ {
+		if input[i] != quote {
+			value.WriteByte(input[i])
+			continue
+		}
+		if i+1 < len(input) && input[i+1] == quote {
+			value.WriteByte(quote)
+			i++
+			continue
+		}
+		return value.String(), i + 1, nil
+	}
+
+	return "", 0, fmt.Errorf("unterminated quoted value")
+}
@@ -0,0 +1,533 @@
+package queries
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Table is the only table available for querying. Each one of its rows is a
+// single delivery of a recipe.
+const Table = "deliveries"
+
+// Query is a parsed query, ready to be executed over a collection of rows.
+type Query struct {
+	Columns []Column
+	where   condition
+	groupBy []string
+	orderBy []orderTerm
+	limit   int
+}
+
+// Column is a single selected column and the name it is reported with.
+type Column struct {
+	Name string
+	expr expression
+}
+
+// expression is either a field or an aggregate over a field.
+type expression struct {
+	field     string
+	aggregate string
+}
+
+// orderTerm is a single ORDER BY term, pointing to a selected column.
+type orderTerm struct {
+	column     int
+	descending bool
+}
+
+// aggregates are the supported aggregate functions.
+var aggregates = map[string]bool{
+	"count": true,
+	"sum":   true,
+	"avg":   true,
+	"min":   true,
+	"max":   true,
+}
+
+// String renders the expression the same way it should be written in the
+// query, such as "recipe" or "count(*)".
+func (e expression) String() string {
+	if e.aggregate == "" {
+		return e.field
+	}
+	if e.field == "" {
+		return e.aggregate + "(*)"
+	}
+
+	return e.aggregate + "(" + e.field + ")"
+}
+
+// parser is a recursive descent parser over the tokens of a query.
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+// Parse parses a query such as:
+//
+//	SELECT recipe, count(*) FROM deliveries
+//	WHERE postcode LIKE '101%' AND from >= 9
+//	GROUP BY recipe ORDER BY 2 DESC LIMIT 10
+//
+// Fields are recipe, postcode and weekday (strings), from and to (24h hours).
+// Conditions support =, != (or <>), <, <=, >, >=, LIKE, IN, AND, OR, NOT and
+// parentheses, and the aggregates are count, sum, avg, min and max.
+func Parse(query string) (*Query, error) {
+	tokens, err := tokenize(query)
+	if err != nil {
+		return nil, err
+	}
+
+	p := parser{tokens: tokens}
+	return p.parseQuery()
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+
+	return t
+}
+
+func (p *parser) errorf(t token, format string, args ...interface{}) error {
+	return &SyntaxError{Pos: t.pos, Message: fmt.Sprintf(format, args...)}
+}
+
+// isKeyword tells whether the next token is the given keyword, consuming it
+// when it is.
+func (p *parser) isKeyword(keyword string) bool {
+	if t := p.peek(); t.kind == tokenKeyword && t.text == keyword {
+		p.pos++
+		return true
+	}
+
+	return false
+}
+
+func (p *parser) expectKeyword(keyword string) error {
+	if !p.isKeyword(keyword) {
+		return p.errorf(p.peek(), "expected %s, found %s", keyword, describe(p.peek()))
+	}
+
+	return nil
+}
+
+func (p *parser) parseQuery() (*Query, error) {
+	q := &Query{limit: -1}
+
+	if err := p.expectKeyword("SELECT"); err != nil {
+		return nil, err
+	}
+
+	columns, err := p.parseColumns()
+	if err != nil {
+		return nil, err
+	}
+	q.Columns = columns
+
+	if err := p.expectKeyword("FROM"); err != nil {
+		return nil, err
+	}
+	if t := p.next(); t.kind != tokenIdentifier || t.text != Table {
+		return nil, p.errorf(t, "unknown table %s, the only table available is %s", describe(t), Table)
+	}
+
+	if p.isKeyword("WHERE") {
+		if q.where, err = p.parseOr(); err != nil {
+			return nil, err
+		}
+	}
+
+	if p.isKeyword("GROUP") {
+		if err := p.expectKeyword("BY"); err != nil {
+			return nil, err
+		}
+		if q.groupBy, err = p.parseGroupBy(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := q.checkGrouping(p.peek()); err != nil {
+		return nil, err
+	}
+
+	if p.isKeyword("ORDER") {
+		if err := p.expectKeyword("BY"); err != nil {
+			return nil, err
+		}
+		if q.orderBy, err = p.parseOrderBy(q.Columns); err != nil {
+			return nil, err
+		}
+	}
+
+	if p.isKeyword("LIMIT") {
+		t := p.next()
+		limit, err := strconv.Atoi(t.text)
+		if t.kind != tokenNumber || err != nil || limit < 0 {
+			return nil, p.errorf(t, "expected a non negative number after LIMIT, found %s", describe(t))
+		}
+		q.limit = limit
+	}
+
+	if t := p.peek(); t.kind != tokenEOF {
+		return nil, p.errorf(t, "unexpected %s", describe(t))
+	}
+
+	return q, nil
+}
+
+// parseColumns parses the selected columns, expanding * into every field.
+func (p *parser) parseColumns() ([]Column, error) {
+	columns := []Column{}
+
+	for {
+		if p.peek().kind == tokenStar {
+			p.next()
+			for _, field := range fieldNames {
+				columns = append(columns, Column{Name: field, expr: expression{field: field}})
+			}
+		} else {
+			expr, err := p.parseExpression()
+			if err != nil {
+				return nil, err
+			}
+
+			column := Column{Name: expr.String(), expr: expr}
+			if p.isKeyword("AS") {
+				t := p.next()
+				if t.kind != tokenIdentifier {
+					return nil, p.errorf(t, "expected a column name after AS, found %s", describe(t))
+				}
+				column.Name = t.text
+			}
+			columns = append(columns, column)
+		}
+
+		if p.peek().kind != tokenComma {
+			return columns, nil
+		}
+		p.next()
+	}
+}
+
+// parseExpression parses a field or an aggregate. The FROM keyword is
+// accepted as the from field, since the query always expects an expression
+// there.
+func (p *parser) parseExpression() (expression, error) {
+	t := p.peek()
+
+	// an identifier is never the last token, which is always tokenEOF
+	if t.kind == tokenIdentifier && aggregates[t.text] && p.tokens[p.pos+1].kind == tokenLeftParen {
+		p.pos += 2
+		expr := expression{aggregate: t.text}
+
+		if p.peek().kind == tokenStar && t.text == "count" {
+			p.next()
+		} else {
+			field, err := p.parseField()
+			if err != nil {
+				return expression{}, err
+			}
+			if (t.text == "sum" || t.text == "avg") && fieldTypes[field] != numberField {
+				return expression{}, p.errorf(t, "%s requires a numeric field, %s is a text field", t.text, field)
+			}
+			expr.field = field
+		}
+
+		if closing := p.next(); closing.kind != tokenRightParen {
+			return expression{}, p.errorf(closing, "expected ), found %s", describe(closing))
+		}
+		return expr, nil
+	}
+
+	field, err := p.parseField()
+	if err != nil {
+		return expression{}, err
+	}
+
+	return expression{field: field}, nil
+}
+
+func (p *parser) parseField() (string, error) {
+	t := p.next()
+
+	name := t.text
+	if t.kind == tokenKeyword && t.text == "FROM" {
+		name = "from"
+	} else if t.kind != tokenIdentifier {
+		return "", p.errorf(t, "expected a field, found %s", describe(t))
+	}
+
+	if _, found := fieldTypes[name]; !found {
+		return "", p.errorf(t, "unknown field %s, the fields available are %s", name, strings.Join(fieldNames, ", "))
+	}
+
+	return name, nil
+}
+
+func (p *parser) parseGroupBy() ([]string, error) {
+	fields := []string{}
+
+	for {
+		field, err := p.parseField()
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, field)
+
+		if p.peek().kind != tokenComma {
+			return fields, nil
+		}
+		p.next()
+	}
+}
+
+// parseOrderBy parses the ORDER BY terms. Each term is either the position of
+// a selected column, starting at 1, or the name or expression of a selected
+// column.
+func (p *parser) parseOrderBy(columns []Column) ([]orderTerm, error) {
+	terms := []orderTerm{}
+
+	for {
+		t := p.peek()
+		term := orderTerm{column: -1}
+
+		if t.kind == tokenNumber {
+			p.next()
+			position, err := strconv.Atoi(t.text)
+			if err != nil || position < 1 || position > len(columns) {
+				return nil, p.errorf(t, "ORDER BY position %s is out of range, there are %d columns", t.text, len(columns))
+			}
+			term.column = position - 1
+		} else {
+			var name string
+			if t.kind == tokenIdentifier && !aggregates[t.text] {
+				// it may be an alias, which is not a field
+				p.next()
+				name = t.text
+			} else {
+				expr, err := p.parseExpression()
+				if err != nil {
+					return nil, err
+				}
+				name = expr.String()
+			}
+
+			for i, column := range columns {
+				if column.Name == name || column.expr.String() == name {
+					term.column = i
+					break
+				}
+			}
+			if term.column < 0 {
+				return nil, p.errorf(t, "ORDER BY %s must be one of the selected columns", name)
+			}
+		}
+
+		if p.isKeyword("DESC") {
+			term.descending = true
+		} else {
+			p.isKeyword("ASC")
+		}
+		terms = append(terms, term)
+
+		if p.peek().kind != tokenComma {
+			return terms, nil
+		}
+		p.next()
+	}
+}
+
+// checkGrouping makes sure every selected field is grouped when the query
+// aggregates rows.
+func (q *Query) checkGrouping(t token) error {
+	if !q.aggregated() {
+		return nil
+	}
+
+	grouped := map[string]bool{}
+	for _, field := range q.groupBy {
+		grouped[field] = true
+	}
+
+	for _, column := range q.Columns {
+		if column.expr.aggregate == "" && !grouped[column.expr.field] {
+			return &SyntaxError{Pos: t.pos, Message: fmt.Sprintf("%s must appear in GROUP BY or be used in an aggregate", column.expr.field)}
+		}
+	}
+
+	return nil
+}
+
+// aggregated tells whether the query groups rows together.
+func (q *Query) aggregated() bool {
+	if len(q.groupBy) > 0 {
+		return true
+	}
+	for _, column := range q.Columns {
+		if column.expr.aggregate != "" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parseOr, parseAnd and parseNot handle the conditions precedence, from the
+// lowest to the highest.
+func (p *parser) parseOr() (condition, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.isKeyword("OR") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orCondition{left: left, right: right}
+	}
+
+	return left, nil
+}
+
+func (p *parser) parseAnd() (condition, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.isKeyword("AND") {
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = andCondition{left: left, right: right}
+	}
+
+	return left, nil
+}
+
+func (p *parser) parseNot() (condition, error) {
+	if p.isKeyword("NOT") {
+		inner, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return notCondition{inner: inner}, nil
+	}
+
+	if p.peek().kind == tokenLeftParen {
+		p.next()
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if t := p.next(); t.kind != tokenRightParen {
+			return nil, p.errorf(t, "expected ), found %s", describe(t))
+		}
+		return inner, nil
+	}
+
+	return p.parseComparison()
+}
+
+// parseComparison parses a single field comparison, checking that the value
+// has the same type as the field.
+func (p *parser) parseComparison() (condition, error) {
+	fieldToken := p.peek()
+	field, err := p.parseField()
+	if err != nil {
+		return nil, err
+	}
+
+	negate := p.isKeyword("NOT")
+
+	if p.isKeyword("LIKE") {
+		if fieldTypes[field] != textField {
+			return nil, p.errorf(fieldToken, "LIKE requires a text field, %s is a numeric field", field)
+		}
+		t := p.next()
+		if t.kind != tokenString {
+			return nil, p.errorf(t, "expected a quoted pattern after LIKE, found %s", describe(t))
+		}
+		return likeCondition{field: field, pattern: t.text, negate: negate}, nil
+	}
+
+	if p.isKeyword("IN") {
+		if t := p.next(); t.kind != tokenLeftParen {
+			return nil, p.errorf(t, "expected ( after IN, found %s", describe(t))
+		}
+		values := []interface{}{}
+		for {
+			value, err := p.parseValue(field)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+
+			t := p.next()
+			if t.kind == tokenRightParen {
+				break
+			}
+			if t.kind != tokenComma {
+				return nil, p.errorf(t, "expected , or ), found %s", describe(t))
+			}
+		}
+		return inCondition{field: field, values: values, negate: negate}, nil
+	}
+
+	if negate {
+		return nil, p.errorf(p.peek(), "expected LIKE or IN after NOT, found %s", describe(p.peek()))
+	}
+
+	operatorToken := p.next()
+	if operatorToken.kind != tokenOperator {
+		return nil, p.errorf(operatorToken, "expected a comparison operator, found %s", describe(operatorToken))
+	}
+	value, err := p.parseValue(field)
+	if err != nil {
+		return nil, err
+	}
+
+	return comparison{field: field, operator: operatorToken.text, value: value}, nil
+}
+
+// parseValue parses a literal, which must match the type of field.
+func (p *parser) parseValue(field string) (interface{}, error) {
+	t := p.next()
+
+	switch {
+	case t.kind == tokenString && fieldTypes[field] == textField:
+		return t.text, nil
+	case t.kind == tokenNumber && fieldTypes[field] == numberField:
+		return strconv.Atoi(t.text)
+	case t.kind == tokenString || t.kind == tokenNumber:
+		return nil, p.errorf(t, "%s can't be compared to %s", field, describe(t))
+	}
+
+	return nil, p.errorf(t, "expected a value, found %s", describe(t))
+}
+
+// describe renders a token for error messages.
+func describe(t token) string {
+	switch t.kind {
+	case tokenEOF:
+		return "end of query"
+	case tokenString:
+		return "'" + t.text + "'"
+	case tokenKeyword:
+		return "keyword " + t.text
+	}
+
+	return t.text
+}
@@ -0,0 +1,101 @@
+package queries
+
+// Row is a single delivery of a recipe, the unit the queries run over.
+type Row struct {
+	Recipe   string
+	Postcode string
+	Weekday  string
+	From     int
+	To       int
+}
+
+// fieldType tells how a field is compared and aggregated.
+type fieldType int
+
+const (
+	textField fieldType = iota
+	numberField
+)
+
+// fieldNames are the fields of a Row, in the order they are selected by *.
+var fieldNames = []string{"recipe", "postcode", "weekday", "from", "to"}
+
+var fieldTypes = map[string]fieldType{
+	"recipe":   textField,
+	"postcode": textField,
+	"weekday":  textField,
+	"from":     numberField,
+	"to":       numberField,
+}
+
+// value returns the value of field within the row, either a string or an int.
+func (r *Row) value(field string) interface{} {
+	switch field {
+	case "recipe":
+		return r.Recipe
+	case "postcode":
+		return r.Postcode
+	case "weekday":
+		return r.Weekday
+	case "from":
+		return r.From
+	case "to":
+		return r.To
+	}
+
+	return nil
+}
+
+// compareValues compares two values of the same type, returning a negative
+// number, zero or a positive number as a is less than, equal or greater than
+// b. A nil value is lower than everything else.
+func compareValues(a interface{}, b interface{}) int {
+	if a == nil || b == nil {
+		switch {
+		case a == nil && b == nil:
+			return 0
+		case a == nil:
+			return -1
+		}
+		return 1
+	}
+
+	switch a := a.(type) {
+	case int:
+		return compareNumbers(float64(a), toFloat(b))
+	case float64:
+		return compareNumbers(a, toFloat(b))
+	case string:
+		b := b.(string)
+		switch {
+		case a < b:
+			return -1
+		case a > b:
+			return 1
+		}
+	}
+
+	return 0
+}
+
+func compareNumbers(a float64, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+
+	return 0
+}
+
+func toFloat(value interface{}) float64 {
+	switch value := value.(type) {
+	case int:
+		return float64(value)
+	case float64:
+		return value
+	}
+
+	return 0
+}
@@ -0,0 +1,140 @@
+// Package recipestats loads a collection of recipe deliveries and answers the
+// questions the recipe-stats CLI answers, returning typed results instead of
+// printing them, so the same calculations can be embedded in other programs.
+//
+//	dataset, err := recipestats.Open(ctx, "data.json", recipestats.WithParsePolicy(adapters.LenientParsePolicy()))
+//	if err != nil {
+//		return err
+//	}
+//	stats, err := dataset.Stats(ctx, recipestats.Request{RecipeCount: true, Names: []string{"Cheese"}})
+package recipestats
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"recipe-stats/keepers"
+	"recipe-stats/loaders"
+	"recipe-stats/snapshots"
+)
+
+// ReaderName is how the records rejected when loading with Load refer to the
+// input, since a reader has no file path.
+const ReaderName = "-"
+
+// readChunkSize is how much of the input is read between cancellation checks
+const readChunkSize = 4 << 20
+
+// Dataset holds the keepers loaded from an input JSON file or a snapshot. It
+// is safe for concurrent use, including appending while querying.
+type Dataset struct {
+	recipeKeeper           *keepers.RecipeKeeper
+	recipeNameSlicesKeeper *keepers.RecipeNameSlicesKeeper
+	deliveryKeeper         *keepers.DeliveryKeeper
+	options                options
+}
+
+// Open loads the dataset from the file at filePath, either an input JSON file
+// or a snapshot, depending on its content.
+func Open(ctx context.Context, filePath string, opts ...Option) (*Dataset, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var size int64
+	if info, err := file.Stat(); err == nil {
+		size = info.Size()
+	}
+
+	return load(ctx, file, size, filePath, newOptions(opts))
+}
+
+// Load loads the dataset from r, either an input JSON file or a snapshot,
+// depending on its content.
+func Load(ctx context.Context, r io.Reader, opts ...Option) (*Dataset, error) {
+	return load(ctx, r, 0, ReaderName, newOptions(opts))
+}
+
+func load(ctx context.Context, r io.Reader, size int64, name string, o options) (*Dataset, error) {
+	data, err := readAll(ctx, r, size)
+	if err != nil {
+		return nil, err
+	}
+
+	if o.rejects == nil {
+		o.rejects, _ = loaders.NewRejects("")
+	}
+	dataset := &Dataset{options: o}
+
+	if snapshots.HasMagic(data) {
+		dataset.recipeKeeper, dataset.recipeNameSlicesKeeper, dataset.deliveryKeeper, err = loaders.LoadFromSnapshotBytesContext(ctx, data, o.rejects, o.observer())
+		if err != nil {
+			return nil, err
+		}
+		return dataset, nil
+	}
+
+	recipesAdapter, err := o.adapter()
+	if err != nil {
+		return nil, err
+	}
+
+	dataset.recipeKeeper, dataset.recipeNameSlicesKeeper, dataset.deliveryKeeper, err = loaders.LoadFromBytesContext(ctx, data, name, recipesAdapter, o.rejects, o.progress, o.parallelism, o.observer())
+	if err != nil {
+		return nil, err
+	}
+
+	return dataset, nil
+}
+
+// readAll reads r up to its end, like ioutil.ReadAll, but in chunks so a large
+// input can be abandoned through ctx. size is how much is expected to be read,
+// if known.
+func readAll(ctx context.Context, r io.Reader, size int64) ([]byte, error) {
+	buffer := bytes.Buffer{}
+	if size > 0 {
+		buffer.Grow(int(size) + bytes.MinRead)
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		_, err := io.CopyN(&buffer, r, readChunkSize)
+		if err == io.EOF {
+			return buffer.Bytes(), nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// Append reads the new records from the input JSON file at filePath and adds
+// them to the dataset, so daily deltas can be applied without loading
+// everything again. Canceling it once the records started being added leaves
+// the dataset with only part of them.
+func (d *Dataset) Append(ctx context.Context, filePath string) error {
+	recipesAdapter, err := d.options.adapter()
+	if err != nil {
+		return err
+	}
+
+	return loaders.AppendFromGeneralRecipeContext(ctx, filePath, d.recipeKeeper, d.recipeNameSlicesKeeper, d.deliveryKeeper, recipesAdapter, d.options.rejects, d.options.observer())
+}
+
+// RejectedRecords returns how many records couldn't be parsed, counting the
+// ones of every appended file as well.
+func (d *Dataset) RejectedRecords() int {
+	return d.options.rejects.Count()
+}
+
+// Keepers returns the keepers the dataset is made of, for the callers that
+// work with them directly.
+func (d *Dataset) Keepers() (*keepers.RecipeKeeper, *keepers.RecipeNameSlicesKeeper, *keepers.DeliveryKeeper) {
+	return d.recipeKeeper, d.recipeNameSlicesKeeper, d.deliveryKeeper
+}
@@ -0,0 +1,141 @@
+package recipestats
+
+import (
+	"recipe-stats/adapters"
+	"recipe-stats/loaders"
+	"recipe-stats/logging"
+	"recipe-stats/metrics"
+)
+
+// Option changes how a Dataset is loaded.
+type Option func(*options)
+
+// options are the settings of a Dataset, changed by the Option functions
+type options struct {
+	verbose        bool
+	logger         *logging.Logger
+	metrics        metrics.Recorder
+	parallelism    int
+	workers        int
+	policy         adapters.ParsePolicy
+	schema         adapters.Schema
+	recordsAdapter adapters.RecordsAdapter
+	rejects        *loaders.Rejects
+	progress       func(adapters.Progress)
+}
+
+func newOptions(opts []Option) options {
+	o := options{parallelism: loaders.DefaultParallelism}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return o
+}
+
+// adapter provides the adapter that decodes the input files
+func (o options) adapter() (adapters.RecordsAdapter, error) {
+	if o.recordsAdapter != nil {
+		return o.recordsAdapter, nil
+	}
+
+	adapter, err := adapters.NewRecordsAdapter(o.schema, o.policy)
+	if err != nil {
+		return nil, err
+	}
+	adapter.SetWorkers(o.workers)
+
+	return adapter, nil
+}
+
+// observer provides where the loaders report what they are doing
+func (o options) observer() loaders.Observer {
+	observer := loaders.VerboseObserver(o.verbose)
+	if o.logger != nil {
+		observer.Logger = o.logger
+	}
+	observer.Metrics = o.metrics
+
+	return observer
+}
+
+// WithVerbose prints how long every loading step takes to stderr, the same way
+// the --verbose flag does, unless WithLogger is used.
+func WithVerbose(verbose bool) Option {
+	return func(o *options) {
+		o.verbose = verbose
+	}
+}
+
+// WithLogger logs every loading step to logger.
+func WithLogger(logger *logging.Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// WithMetrics records how long every loading step takes, along with the
+// records and bytes read and the records rejected, into recorder.
+func WithMetrics(recorder metrics.Recorder) Option {
+	return func(o *options) {
+		o.metrics = recorder
+	}
+}
+
+// WithParallelism sets how many keepers are built at the same time, which is
+// loaders.DefaultParallelism by default. Since the name slices are built from
+// the recipes, 2 already builds every keeper at once, and values below 1 build
+// them one at a time.
+func WithParallelism(parallelism int) Option {
+	return func(o *options) {
+		o.parallelism = parallelism
+	}
+}
+
+// WithWorkers sets how many workers decode an input JSON file at the same
+// time, each one a range of its records, which is 1 by default. The dataset is
+// the same with any number of them.
+func WithWorkers(workers int) Option {
+	return func(o *options) {
+		o.workers = workers
+	}
+}
+
+// WithParsePolicy sets the policy used to parse the delivery strings, strict
+// by default.
+func WithParsePolicy(policy adapters.ParsePolicy) Option {
+	return func(o *options) {
+		o.policy = policy
+	}
+}
+
+// WithSchema sets where the fields are found in the input records, for inputs
+// that don't follow the default format.
+func WithSchema(schema adapters.Schema) Option {
+	return func(o *options) {
+		o.schema = schema
+	}
+}
+
+// WithRecordsAdapter decodes the input with recordsAdapter, ignoring
+// WithParsePolicy, WithSchema and WithWorkers.
+func WithRecordsAdapter(recordsAdapter adapters.RecordsAdapter) Option {
+	return func(o *options) {
+		o.recordsAdapter = recordsAdapter
+	}
+}
+
+// WithRejects hands the records that can't be parsed to rejects, such as to
+// write them to a quarantine file. They are only counted by default.
+func WithRejects(rejects *loaders.Rejects) Option {
+	return func(o *options) {
+		o.rejects = rejects
+	}
+}
+
+// WithProgress reports how far decoding an input JSON file went to progress.
+func WithProgress(progress func(adapters.Progress)) Option {
+	return func(o *options) {
+		o.progress = progress
+	}
+}
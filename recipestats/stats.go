@@ -0,0 +1,158 @@
+package recipestats
+
+import (
+	"context"
+	"fmt"
+	"recipe-stats/adapters"
+)
+
+// RecipeCount is a recipe along with how many times it was delivered.
+type RecipeCount struct {
+	Recipe string
+	Count  int
+}
+
+// PostcodeCount is a postcode along with how many deliveries it had.
+type PostcodeCount struct {
+	Postcode string
+	Count    int
+}
+
+// WindowCount is how many deliveries a postcode had within a time window.
+type WindowCount struct {
+	Postcode string
+	From     string
+	To       string
+	Count    int
+}
+
+// Request tells Stats what to calculate, the same way the CLI flags do. The
+// window count is only calculated when Postcode, From and To are all set.
+type Request struct {
+	RecipeCount bool
+	Names       []string
+	Postcode    string
+	From        string
+	To          string
+}
+
+// Stats are the results of a Request.
+type Stats struct {
+	// UniqueRecipeCount is only set when asked for by Request.RecipeCount
+	UniqueRecipeCount int
+	RejectedRecords   int
+	// Recipes are the ones matching any of the Request.Names, sorted by name
+	Recipes         []RecipeCount
+	BusiestPostcode PostcodeCount
+	// Window is nil unless a postcode and a time window were requested
+	Window *WindowCount
+}
+
+// Stats calculates everything asked for by request at once.
+func (d *Dataset) Stats(ctx context.Context, request Request) (*Stats, error) {
+	stats := &Stats{
+		RejectedRecords: d.RejectedRecords(),
+		BusiestPostcode: d.BusiestPostcode(),
+	}
+
+	if request.RecipeCount {
+		stats.UniqueRecipeCount = d.UniqueRecipeCount()
+	}
+
+	recipes, err := d.SearchRecipes(ctx, request.Names)
+	if err != nil {
+		return nil, err
+	}
+	stats.Recipes = recipes
+
+	if request.Postcode != "" && request.From != "" && request.To != "" {
+		count, err := d.CountDeliveries(ctx, request.Postcode, request.From, request.To)
+		if err != nil {
+			return nil, err
+		}
+		stats.Window = &WindowCount{Postcode: request.Postcode, From: request.From, To: request.To, Count: count}
+	}
+
+	return stats, nil
+}
+
+// UniqueRecipeCount returns how many distinct recipes were delivered.
+func (d *Dataset) UniqueRecipeCount() int {
+	return d.recipeKeeper.Count()
+}
+
+// SearchRecipes returns the recipes with any of words in their names, sorted
+// by name.
+func (d *Dataset) SearchRecipes(ctx context.Context, words []string) ([]RecipeCount, error) {
+	recipes, err := d.recipeNameSlicesKeeper.GetSomeContext(ctx, words)
+	if err != nil {
+		return nil, err
+	}
+
+	found := make([]RecipeCount, 0, len(recipes))
+	for _, recipe := range recipes {
+		found = append(found, RecipeCount{Recipe: recipe.Recipe, Count: recipe.Count})
+	}
+
+	return found, nil
+}
+
+// BusiestPostcode returns the postcode with the most deliveries.
+func (d *Dataset) BusiestPostcode() PostcodeCount {
+	busiest := d.deliveryKeeper.GetBusiestPostcode()
+
+	return PostcodeCount{Postcode: busiest.Code, Count: busiest.Count}
+}
+
+// TopPostcodes returns the n postcodes with the most deliveries, the busiest
+// first.
+func (d *Dataset) TopPostcodes(n int) []PostcodeCount {
+	top := []PostcodeCount{}
+	for _, postcode := range d.deliveryKeeper.TopPostcodes(n) {
+		top = append(top, PostcodeCount{Postcode: postcode.Code, Count: postcode.Count})
+	}
+
+	return top
+}
+
+// Postcodes returns every postcode with deliveries, sorted.
+func (d *Dataset) Postcodes() []string {
+	return d.deliveryKeeper.Postcodes()
+}
+
+// CountDeliveries returns how many deliveries postcode had within the time
+// window from - to, both 12h times such as 9AM. It fails if the times are not
+// valid or the window ends before it starts.
+func (d *Dataset) CountDeliveries(ctx context.Context, postcode string, from string, to string) (int, error) {
+	policy := adapters.StrictParsePolicy()
+	start, err := policy.ParseHour(from)
+	if err != nil {
+		return 0, err
+	}
+	end, err := policy.ParseHour(to)
+	if err != nil {
+		return 0, err
+	}
+	if end < start {
+		return 0, fmt.Errorf("the time window %s - %s ends before it starts", from, to)
+	}
+
+	return d.deliveryKeeper.CountByIntervalContext(ctx, postcode, from, to)
+}
+
+// Sizes are how many distinct items the keepers of a Dataset hold.
+type Sizes struct {
+	Recipes    int
+	Postcodes  int
+	NameSlices int
+}
+
+// Sizes returns how many distinct recipes, postcodes and recipe name slices
+// the dataset holds, such as to report them as metrics.
+func (d *Dataset) Sizes() Sizes {
+	return Sizes{
+		Recipes:    d.recipeKeeper.Count(),
+		Postcodes:  len(d.deliveryKeeper.Postcodes()),
+		NameSlices: len(d.recipeNameSlicesKeeper.Words()),
+	}
+}
@@ -1,6 +1,9 @@
 package reporters
 
-import "encoding/json"
+import (
+	"encoding/json"
+	"recipe-stats/recipestats"
+)
 
 // CountPerRecipe is the building block of the counting per recipe output
 type CountPerRecipe struct {
@@ -15,7 +18,7 @@ type BusiestPostCode struct {
 }
 
 // CountPerPostcodeAndTime is the building block of the count per postcode
-//output
+// output
 type CountPerPostcodeAndTime struct {
 	Postcode      string `json:"postcode"`
 	From          string `json:"from"`
@@ -31,6 +34,37 @@ type JSONReporter struct {
 	BusiestPostCode         *BusiestPostCode         `json:"busiest_postcode,omitempty"`
 	CountPerPostcodeAndTime *CountPerPostcodeAndTime `json:"count_per_postcode_and_time,omitempty"`
 	MatchByName             []string                 `json:"match_by_name,omitempty"`
+	RejectedRecords         int                      `json:"rejected_records,omitempty"`
+}
+
+// NewJSONReporter lays the stats out the way the JSON output expects them
+func NewJSONReporter(stats *recipestats.Stats) JSONReporter {
+	jsonOutput := JSONReporter{
+		UniqueRecipeCount: stats.UniqueRecipeCount,
+		RejectedRecords:   stats.RejectedRecords,
+		MatchByName:       []string{},
+		CountPerRecipe:    []CountPerRecipe{},
+		BusiestPostCode: &BusiestPostCode{
+			Postcode:      stats.BusiestPostcode.Postcode,
+			DeliveryCount: stats.BusiestPostcode.Count,
+		},
+	}
+
+	for _, recipe := range stats.Recipes {
+		jsonOutput.MatchByName = append(jsonOutput.MatchByName, recipe.Recipe)
+		jsonOutput.CountPerRecipe = append(jsonOutput.CountPerRecipe, CountPerRecipe{Recipe: recipe.Recipe, Count: recipe.Count})
+	}
+
+	if stats.Window != nil && stats.Window.Count > 0 {
+		jsonOutput.CountPerPostcodeAndTime = &CountPerPostcodeAndTime{
+			From:          stats.Window.From,
+			To:            stats.Window.To,
+			Postcode:      stats.Window.Postcode,
+			DeliveryCount: stats.Window.Count,
+		}
+	}
+
+	return jsonOutput
 }
 
 // Marshal is the encodinf function for JSONReporter and creates a formatted
@@ -0,0 +1,56 @@
+package reporters
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+)
+
+// QueryReporter is the main struct for the output of ad-hoc queries. Rows are
+// kept as lists so the values follow the order of the columns.
+type QueryReporter struct {
+	Columns []string        `json:"columns"`
+	Rows    [][]interface{} `json:"rows"`
+}
+
+// Marshal is the encoding function for QueryReporter and creates a formatted
+// JSON string.
+func (qr *QueryReporter) Marshal() (string, error) {
+	marshaled, err := json.MarshalIndent(qr, "", "  ")
+
+	if err != nil {
+		return "", err
+	}
+
+	return string(marshaled), nil
+}
+
+// MarshalTable creates a plain text table with the columns as the header,
+// which is easier to read on a terminal.
+func (qr *QueryReporter) MarshalTable() (string, error) {
+	output := new(strings.Builder)
+	table := tabwriter.NewWriter(output, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(table, strings.Join(qr.Columns, "\t"))
+	for _, row := range qr.Rows {
+		values := make([]string, len(row))
+		for i, value := range row {
+			switch value := value.(type) {
+			case nil:
+				values[i] = "NULL"
+			case float64:
+				values[i] = fmt.Sprintf("%.2f", value)
+			default:
+				values[i] = fmt.Sprint(value)
+			}
+		}
+		fmt.Fprintln(table, strings.Join(values, "\t"))
+	}
+
+	if err := table.Flush(); err != nil {
+		return "", err
+	}
+
+	return strings.TrimSuffix(output.String(), "\n"), nil
+}
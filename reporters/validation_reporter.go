@@ -0,0 +1,35 @@
+package reporters
+
+import "encoding/json"
+
+// ValidationSample is the building block of the sample offending records
+// output.
+type ValidationSample struct {
+	Class  string `json:"class"`
+	Index  int    `json:"index"`
+	Offset int64  `json:"offset"`
+	Reason string `json:"reason"`
+	Record string `json:"record"`
+}
+
+// ValidationReporter is the main struct for the data-quality report, holding
+// the counters and the samples of the problems found on the input file.
+type ValidationReporter struct {
+	TotalRecords   int                `json:"total_records"`
+	ValidRecords   int                `json:"valid_records"`
+	InvalidRecords int                `json:"invalid_records"`
+	ErrorsPerClass map[string]int     `json:"errors_per_class,omitempty"`
+	Samples        []ValidationSample `json:"samples,omitempty"`
+}
+
+// Marshal is the encoding function for ValidationReporter and creates a
+// formatted JSON string.
+func (vr *ValidationReporter) Marshal() (string, error) {
+	marshaled, err := json.MarshalIndent(vr, "", "  ")
+
+	if err != nil {
+		return "", err
+	}
+
+	return string(marshaled), nil
+}
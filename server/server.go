@@ -0,0 +1,161 @@
+// Package server answers the stats of a dataset over HTTP for as long as the
+// process lives, loading the dataset again when asked to and exposing how the
+// loads and the queries went to Prometheus.
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"recipe-stats/metrics"
+	"recipe-stats/recipestats"
+	"recipe-stats/reporters"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The endpoints of a Server.
+const (
+	EndpointStats   = "/stats"
+	EndpointReload  = "/reload"
+	EndpointMetrics = "/metrics"
+)
+
+// Loader loads the dataset served, every time the server is reloaded. It is
+// expected to record its metrics to the Prometheus of the server.
+type Loader func(ctx context.Context) (*recipestats.Dataset, error)
+
+// Server is an http.Handler answering the stats of the dataset given by its
+// Loader, the same ones as the flag mode in the same JSON output:
+//
+//	GET  /stats?count=true&search=Chicken,Steak&postcode=10120&from=9AM&to=2PM
+//	POST /reload
+//	GET  /metrics
+type Server struct {
+	load       Loader
+	prometheus *metrics.Prometheus
+	mux        *http.ServeMux
+
+	mu      *sync.RWMutex
+	dataset *recipestats.Dataset
+}
+
+// New creates a Server with nothing loaded yet, so Reload must be called
+// before serving it.
+func New(load Loader, prometheus *metrics.Prometheus) *Server {
+	s := &Server{load: load, prometheus: prometheus, mux: http.NewServeMux(), mu: new(sync.RWMutex)}
+	s.mux.HandleFunc(EndpointStats, s.stats)
+	s.mux.HandleFunc(EndpointReload, s.reload)
+	s.mux.Handle(EndpointMetrics, prometheus)
+
+	return s
+}
+
+// Reload loads the dataset again, replacing the one served once it is done.
+// The dataset served is kept when loading fails.
+func (s *Server) Reload(ctx context.Context) error {
+	dataset, err := s.load(ctx)
+	if err != nil {
+		return err
+	}
+
+	sizes := dataset.Sizes()
+	s.prometheus.SetKeeperSizes(sizes.Recipes, sizes.Postcodes, sizes.NameSlices)
+	s.prometheus.Reloaded(time.Now())
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dataset = dataset
+
+	return nil
+}
+
+// ServeHTTP routes the request to its endpoint.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+func (s *Server) stats(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	defer func() {
+		s.prometheus.ObserveQuery(EndpointStats, time.Since(start))
+	}()
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "only GET is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	request, err := parseRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.RLock()
+	dataset := s.dataset
+	s.mu.RUnlock()
+	if dataset == nil {
+		http.Error(w, "the dataset is not loaded yet", http.StatusServiceUnavailable)
+		return
+	}
+
+	stats, err := dataset.Stats(r.Context(), request)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	jsonOutput := reporters.NewJSONReporter(stats)
+	formattedOutput, err := jsonOutput.Marshal()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	fmt.Fprintln(w, formattedOutput)
+}
+
+func (s *Server) reload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "only POST is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err := s.Reload(r.Context()); err != nil {
+		http.Error(w, fmt.Sprintf("it was impossible to reload the dataset: %s", err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// parseRequest reads the request of the stats from the query parameters,
+// named after the flags of the flag mode
+func parseRequest(r *http.Request) (recipestats.Request, error) {
+	query := r.URL.Query()
+	request := recipestats.Request{
+		Postcode: query.Get("postcode"),
+		From:     query.Get("from"),
+		To:       query.Get("to"),
+	}
+
+	if count := query.Get("count"); count != "" {
+		recipeCount, err := strconv.ParseBool(count)
+		if err != nil {
+			return request, fmt.Errorf("invalid count %q, expected true or false", count)
+		}
+		request.RecipeCount = recipeCount
+	}
+
+	for _, name := range strings.Split(query.Get("search"), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			request.Names = append(request.Names, name)
+		}
+	}
+
+	return request, nil
+}
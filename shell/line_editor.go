@@ -0,0 +1,157 @@
+package shell
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/AlecAivazis/survey/v2/terminal"
+)
+
+// lineEditor reads command lines from a terminal, with tab-completion and the
+// arrow keys going through the previous lines. When the input is not a
+// terminal, such as a piped script, lines are read as they are.
+type lineEditor struct {
+	stdio    terminal.Stdio
+	reader   *terminal.RuneReader
+	scanner  *bufio.Scanner
+	complete func(line string) []string
+	history  []string
+}
+
+func newLineEditor(stdio terminal.Stdio, complete func(line string) []string) *lineEditor {
+	editor := &lineEditor{stdio: stdio, complete: complete}
+
+	reader := terminal.NewRuneReader(stdio)
+	if err := reader.SetTermMode(); err != nil {
+		editor.scanner = bufio.NewScanner(stdio.In)
+		return editor
+	}
+	editor.reader = reader
+
+	return editor
+}
+
+// close gives the terminal back in the mode it was found
+func (e *lineEditor) close() {
+	if e.reader != nil {
+		_ = e.reader.RestoreTermMode()
+	}
+}
+
+// readLine shows prompt and returns the line typed. It returns io.EOF when
+// the input ends or Ctrl+D is pressed on an empty line.
+func (e *lineEditor) readLine(prompt string) (string, error) {
+	fmt.Fprint(e.stdio.Out, prompt)
+
+	if e.scanner != nil {
+		if !e.scanner.Scan() {
+			if err := e.scanner.Err(); err != nil {
+				return "", err
+			}
+			return "", io.EOF
+		}
+		return e.scanner.Text(), nil
+	}
+
+	line := []rune{}
+	historyIndex := len(e.history)
+	redraw := func() {
+		fmt.Fprintf(e.stdio.Out, "\r\033[K%s%s", prompt, string(line))
+	}
+
+	for {
+		r, _, err := e.reader.ReadRune()
+		if err != nil {
+			return "", err
+		}
+
+		switch r {
+		case terminal.KeyEnter, '\n':
+			fmt.Fprintln(e.stdio.Out)
+			if text := strings.TrimSpace(string(line)); text != "" {
+				e.history = append(e.history, text)
+			}
+			return string(line), nil
+		case terminal.KeyEndTransmission:
+			if len(line) == 0 {
+				fmt.Fprintln(e.stdio.Out)
+				return "", io.EOF
+			}
+		case terminal.KeyInterrupt:
+			line = line[:0]
+			fmt.Fprintln(e.stdio.Out, "^C")
+			fmt.Fprint(e.stdio.Out, prompt)
+		case terminal.KeyBackspace, terminal.KeyDelete:
+			if len(line) > 0 {
+				line = line[:len(line)-1]
+				redraw()
+			}
+		case terminal.KeyArrowUp:
+			if historyIndex > 0 {
+				historyIndex--
+				line = []rune(e.history[historyIndex])
+				redraw()
+			}
+		case terminal.KeyArrowDown:
+			if historyIndex < len(e.history) {
+				historyIndex++
+				line = line[:0]
+				if historyIndex < len(e.history) {
+					line = []rune(e.history[historyIndex])
+				}
+				redraw()
+			}
+		case '\t':
+			line = e.completeLine(line)
+			redraw()
+		default:
+			if r >= ' ' {
+				line = append(line, r)
+				fmt.Fprintf(e.stdio.Out, "%c", r)
+			}
+		}
+	}
+}
+
+// completeLine completes the last word of line. A single candidate replaces
+// the word, while many extend it up to their common prefix, listing them if
+// there is nothing to extend.
+func (e *lineEditor) completeLine(line []rune) []rune {
+	text := string(line)
+	candidates := e.complete(text)
+	if len(candidates) == 0 {
+		return line
+	}
+
+	start := strings.LastIndex(text, " ") + 1
+	word := text[start:]
+
+	if len(candidates) == 1 {
+		return []rune(text[:start] + candidates[0] + " ")
+	}
+
+	prefix := commonPrefix(candidates)
+	if len(prefix) > len(word) {
+		return []rune(text[:start] + prefix)
+	}
+
+	fmt.Fprintf(e.stdio.Out, "\n%s\n", strings.Join(candidates, "  "))
+	return line
+}
+
+// commonPrefix returns the longest prefix shared by every candidate, ignoring
+// the case but keeping the one of the first candidate
+func commonPrefix(candidates []string) string {
+	prefix := candidates[0]
+	for _, candidate := range candidates[1:] {
+		size := 0
+		for size < len(prefix) && size < len(candidate) && strings.EqualFold(prefix[size:size+1], candidate[size:size+1]) {
+			size++
+		}
+		prefix = prefix[:size]
+	}
+
+	return prefix
+}
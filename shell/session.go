@@ -0,0 +1,220 @@
+package shell
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"recipe-stats/adapters"
+	"recipe-stats/keepers"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ErrQuit is returned by Execute when the session should end.
+var ErrQuit = errors.New("quit")
+
+// commands are the commands accepted by the shell, along with their usage
+var commands = map[string]string{
+	"count":    "count                      counts the unique recipes",
+	"search":   "search <word> [<word>...]  finds the recipes with any of the words in their names",
+	"postcode": "postcode <code> <from> <to> counts the deliveries to a postcode within a time window, such as postcode 10120 9AM 2PM",
+	"top":      "top [<n>]                  lists the n busiest postcodes, 10 by default",
+	"help":     "help                       shows this message",
+	"exit":     "exit                       leaves the shell, as does Ctrl+D",
+}
+
+// Session runs the shell commands against keepers that are loaded only once.
+type Session struct {
+	recipeKeeper           *keepers.RecipeKeeper
+	recipeNameSlicesKeeper *keepers.RecipeNameSlicesKeeper
+	deliveryKeeper         *keepers.DeliveryKeeper
+	out                    io.Writer
+
+	// words and postcodes are only listed when completing for the first time
+	words     []string
+	postcodes []string
+}
+
+// NewSession provides a usable instance of Session, writing the results of
+// the commands to out.
+func NewSession(recipeKeeper *keepers.RecipeKeeper, recipeNameSlicesKeeper *keepers.RecipeNameSlicesKeeper, deliveryKeeper *keepers.DeliveryKeeper, out io.Writer) *Session {
+	return &Session{
+		recipeKeeper:           recipeKeeper,
+		recipeNameSlicesKeeper: recipeNameSlicesKeeper,
+		deliveryKeeper:         deliveryKeeper,
+		out:                    out,
+	}
+}
+
+// Execute runs a single command line. Mistakes in the command are returned as
+// errors, so the session can go on after showing them. It returns ErrQuit when
+// asked to leave.
+func (s *Session) Execute(line string) error {
+	args := strings.Fields(line)
+	if len(args) == 0 {
+		return nil
+	}
+
+	switch args[0] {
+	case "count":
+		fmt.Fprintf(s.out, "%d unique recipes\n", s.recipeKeeper.Count())
+	case "search":
+		return s.search(args[1:])
+	case "postcode":
+		return s.postcode(args[1:])
+	case "top":
+		return s.top(args[1:])
+	case "help":
+		s.help()
+	case "exit", "quit":
+		return ErrQuit
+	default:
+		return fmt.Errorf("unknown command %q, type help to list the commands", args[0])
+	}
+
+	return nil
+}
+
+func (s *Session) search(words []string) error {
+	if len(words) == 0 {
+		return fmt.Errorf("usage: %s", commands["search"])
+	}
+
+	recipes := s.recipeNameSlicesKeeper.GetSome(words)
+	if len(recipes) == 0 {
+		fmt.Fprintln(s.out, "No recipes found")
+		return nil
+	}
+
+	for _, recipe := range recipes {
+		fmt.Fprintf(s.out, "%s (%d)\n", recipe.Recipe, recipe.Count)
+	}
+
+	return nil
+}
+
+func (s *Session) postcode(args []string) error {
+	if len(args) != 3 {
+		return fmt.Errorf("usage: %s", commands["postcode"])
+	}
+
+	// checking the times first, since the keeper only takes valid ones
+	policy := adapters.StrictParsePolicy()
+	from, err := policy.ParseHour(args[1])
+	if err != nil {
+		return err
+	}
+	to, err := policy.ParseHour(args[2])
+	if err != nil {
+		return err
+	}
+	if to < from {
+		return fmt.Errorf("the time window %s - %s ends before it starts", args[1], args[2])
+	}
+
+	count := s.deliveryKeeper.CountByInterval(args[0], args[1], args[2])
+	fmt.Fprintf(s.out, "%d deliveries to %s between %s and %s\n", count, args[0], args[1], args[2])
+
+	return nil
+}
+
+func (s *Session) top(args []string) error {
+	n := 10
+	if len(args) > 1 {
+		return fmt.Errorf("usage: %s", commands["top"])
+	}
+	if len(args) == 1 {
+		var err error
+		n, err = strconv.Atoi(args[0])
+		if err != nil || n < 1 {
+			return fmt.Errorf("%q is not a positive number", args[0])
+		}
+	}
+
+	for i, postcode := range s.deliveryKeeper.TopPostcodes(n) {
+		fmt.Fprintf(s.out, "%d. %s (%d deliveries)\n", i+1, postcode.Code, postcode.Count)
+	}
+
+	return nil
+}
+
+func (s *Session) help() {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		fmt.Fprintln(s.out, "  "+commands[name])
+	}
+}
+
+// Complete returns the candidates for the last word of line: commands for the
+// first word, recipe name words for search and postcodes and times for
+// postcode.
+func (s *Session) Complete(line string) []string {
+	args := strings.Fields(line)
+	// a trailing space means a new word is being started
+	if len(args) == 0 || strings.HasSuffix(line, " ") {
+		args = append(args, "")
+	}
+	word := args[len(args)-1]
+
+	if len(args) == 1 {
+		names := []string{}
+		for name := range commands {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return withPrefix(names, word)
+	}
+
+	switch args[0] {
+	case "search":
+		if s.words == nil {
+			s.words = s.recipeNameSlicesKeeper.Words()
+		}
+		return withPrefix(s.words, word)
+	case "postcode":
+		switch len(args) {
+		case 2:
+			if s.postcodes == nil {
+				s.postcodes = s.deliveryKeeper.Postcodes()
+			}
+			return withPrefix(s.postcodes, word)
+		case 3, 4:
+			return withPrefix(times, word)
+		}
+	}
+
+	return nil
+}
+
+// times are the 12h times accepted by the postcode command
+var times = func() []string {
+	list := []string{"12AM"}
+	for hour := 1; hour <= 11; hour++ {
+		list = append(list, fmt.Sprintf("%dAM", hour))
+	}
+	list = append(list, "12PM")
+	for hour := 1; hour <= 11; hour++ {
+		list = append(list, fmt.Sprintf("%dPM", hour))
+	}
+	return list
+}()
+
+// withPrefix filters the candidates starting with prefix, ignoring the case
+func withPrefix(candidates []string, prefix string) []string {
+	prefix = strings.ToLower(prefix)
+
+	found := []string{}
+	for _, candidate := range candidates {
+		if strings.HasPrefix(strings.ToLower(candidate), prefix) {
+			found = append(found, candidate)
+		}
+	}
+
+	return found
+}
@@ -0,0 +1,37 @@
+package shell
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/AlecAivazis/survey/v2/terminal"
+)
+
+// Prompt is shown before every command.
+const Prompt = "recipe-stats> "
+
+// Run reads commands from stdio and executes them in session until the input
+// ends or the exit command is used. Mistakes in the commands are shown, but
+// don't end the shell.
+func Run(session *Session, stdio terminal.Stdio) error {
+	editor := newLineEditor(stdio, session.Complete)
+	defer editor.close()
+
+	for {
+		line, err := editor.readLine(Prompt)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		err = session.Execute(line)
+		if err == ErrQuit {
+			return nil
+		}
+		if err != nil {
+			fmt.Fprintf(stdio.Err, "Error: %s\n", err.Error())
+		}
+	}
+}
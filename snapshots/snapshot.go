@@ -0,0 +1,193 @@
+package snapshots
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"recipe-stats/keepers"
+)
+
+// A snapshot is the binary representation of already loaded keepers, so they
+// can be loaded again without parsing the input JSON. The layout is:
+//
+//	magic      5 bytes, "RSIDX"
+//	version    uint16, big endian
+//	rejected   uint64 big endian, how many records of the input JSON couldn't
+//	           be parsed, so the stats of a snapshot match the ones of its input
+//	sections   for RecipeKeeper, RecipeNameSlicesKeeper and DeliveryKeeper,
+//	           in that order, each one as an uint64 big endian length followed
+//	           by the keeper's MarshalBinary output
+//	checksum   uint32 big endian, CRC-32 (Castagnoli) of everything before it
+//
+// Version must be bumped every time the layout or any keeper encoding changes.
+const Version uint16 = 2
+
+// Extension is the conventional file extension for snapshots.
+const Extension = ".rsidx"
+
+var magic = []byte("RSIDX")
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// headerSize is the size of the magic and the version.
+const headerSize = 5 + 2
+
+// checksumSize is the size of the trailing checksum.
+const checksumSize = 4
+
+// ErrNotSnapshot is returned when the data does not start with the snapshot
+// magic bytes.
+var ErrNotSnapshot = errors.New("not a recipe-stats snapshot")
+
+// ErrChecksumMismatch is returned when the snapshot content doesn't match its
+// checksum, usually because the file got truncated or corrupted.
+var ErrChecksumMismatch = errors.New("snapshot checksum mismatch, the file is corrupted")
+
+// VersionError is returned when the snapshot was written with a format
+// version this build doesn't know how to read.
+type VersionError struct {
+	Found     uint16
+	Supported uint16
+}
+
+func (e *VersionError) Error() string {
+	return fmt.Sprintf("snapshot format version %d is incompatible with the supported version %d, please rebuild it with `recipe-stats index build`", e.Found, e.Supported)
+}
+
+// Write encodes the keepers into w as a snapshot, along with how many records
+// were rejected when loading them.
+func Write(w io.Writer, recipeKeeper *keepers.RecipeKeeper, recipeNameSlicesKeeper *keepers.RecipeNameSlicesKeeper, deliveryKeeper *keepers.DeliveryKeeper, rejected int) error {
+	buf := new(bytes.Buffer)
+	buf.Write(magic)
+	_ = binary.Write(buf, binary.BigEndian, Version)
+	_ = binary.Write(buf, binary.BigEndian, uint64(rejected))
+
+	for _, keeper := range []encoding.BinaryMarshaler{recipeKeeper, recipeNameSlicesKeeper, deliveryKeeper} {
+		section, err := keeper.MarshalBinary()
+		if err != nil {
+			return err
+		}
+		_ = binary.Write(buf, binary.BigEndian, uint64(len(section)))
+		buf.Write(section)
+	}
+
+	_ = binary.Write(buf, binary.BigEndian, crc32.Checksum(buf.Bytes(), crcTable))
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// WriteFile writes the keepers as a snapshot into the file at filePath, along
+// with how many records were rejected when loading them.
+func WriteFile(filePath string, recipeKeeper *keepers.RecipeKeeper, recipeNameSlicesKeeper *keepers.RecipeNameSlicesKeeper, deliveryKeeper *keepers.DeliveryKeeper, rejected int) error {
+	file, err := os.Create(filePath)
+	if err != nil {
+		return err
+	}
+
+	if err := Write(file, recipeKeeper, recipeNameSlicesKeeper, deliveryKeeper, rejected); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
+
+// Decode checks the header and the checksum of data and decodes the keepers
+// from it, along with how many records were rejected when loading them.
+func Decode(data []byte) (*keepers.RecipeKeeper, *keepers.RecipeNameSlicesKeeper, *keepers.DeliveryKeeper, int, error) {
+	if !HasMagic(data) {
+		return nil, nil, nil, 0, ErrNotSnapshot
+	}
+	if len(data) < headerSize {
+		return nil, nil, nil, 0, ErrChecksumMismatch
+	}
+
+	version := binary.BigEndian.Uint16(data[len(magic):headerSize])
+	if version != Version {
+		return nil, nil, nil, 0, &VersionError{Found: version, Supported: Version}
+	}
+
+	if len(data) < headerSize+checksumSize {
+		return nil, nil, nil, 0, ErrChecksumMismatch
+	}
+	content := data[:len(data)-checksumSize]
+	if crc32.Checksum(content, crcTable) != binary.BigEndian.Uint32(data[len(content):]) {
+		return nil, nil, nil, 0, ErrChecksumMismatch
+	}
+
+	recipeKeeper := new(keepers.RecipeKeeper)
+	recipeNameSlicesKeeper := new(keepers.RecipeNameSlicesKeeper)
+	deliveryKeeper := new(keepers.DeliveryKeeper)
+
+	sections := content[headerSize:]
+	if len(sections) < 8 {
+		return nil, nil, nil, 0, keepers.ErrCorruptedData
+	}
+	rejected := binary.BigEndian.Uint64(sections[:8])
+	if rejected > math.MaxInt32 {
+		return nil, nil, nil, 0, keepers.ErrCorruptedData
+	}
+	sections = sections[8:]
+
+	for _, keeper := range []encoding.BinaryUnmarshaler{recipeKeeper, recipeNameSlicesKeeper, deliveryKeeper} {
+		if len(sections) < 8 {
+			return nil, nil, nil, 0, keepers.ErrCorruptedData
+		}
+		length := binary.BigEndian.Uint64(sections[:8])
+		sections = sections[8:]
+		if length > uint64(len(sections)) {
+			return nil, nil, nil, 0, keepers.ErrCorruptedData
+		}
+
+		if err := keeper.UnmarshalBinary(sections[:length]); err != nil {
+			return nil, nil, nil, 0, err
+		}
+		sections = sections[length:]
+	}
+
+	if len(sections) > 0 {
+		return nil, nil, nil, 0, keepers.ErrCorruptedData
+	}
+
+	return recipeKeeper, recipeNameSlicesKeeper, deliveryKeeper, int(rejected), nil
+}
+
+// ReadFile reads and decodes the snapshot at filePath.
+func ReadFile(filePath string) (*keepers.RecipeKeeper, *keepers.RecipeNameSlicesKeeper, *keepers.DeliveryKeeper, int, error) {
+	data, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return nil, nil, nil, 0, err
+	}
+
+	return Decode(data)
+}
+
+// HasMagic tells whether data starts with the snapshot magic bytes.
+func HasMagic(data []byte) bool {
+	return bytes.HasPrefix(data, magic)
+}
+
+// IsSnapshot peeks into the file at filePath and tells whether it is a
+// snapshot, regardless of its extension.
+func IsSnapshot(filePath string) bool {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+
+	header := make([]byte, len(magic))
+	if _, err := io.ReadFull(file, header); err != nil {
+		return false
+	}
+
+	return HasMagic(header)
+}
@@ -0,0 +1,258 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"recipe-stats/adapters"
+	"recipe-stats/generator"
+	"recipe-stats/keepers"
+	"recipe-stats/loaders"
+	"recipe-stats/queries"
+	"regexp"
+	"runtime"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+// benchmarkConfig is the dataset every benchmark runs against, 100k records
+// with a thousand recipes and postcodes, a few of them having most of the
+// deliveries
+var benchmarkConfig = generator.Config{
+	Seed:                 1,
+	Records:              100000,
+	Recipes:              1000,
+	Postcodes:            1000,
+	PostcodeDistribution: generator.PostcodesZipf,
+	WindowDistribution:   generator.WindowsUniform,
+}
+
+var (
+	benchmarkOnce    sync.Once
+	benchmarkInput   []byte
+	benchmarkRecipes []adapters.GeneralRecipe
+)
+
+// benchmarkDatasetHelper generates the benchmark dataset once, returning it
+// as a file and decoded
+func benchmarkDatasetHelper() ([]byte, []adapters.GeneralRecipe) {
+	benchmarkOnce.Do(func() {
+		input := bytes.Buffer{}
+		if err := generator.Generate(&input, benchmarkConfig); err != nil {
+			panic(err)
+		}
+		benchmarkInput = input.Bytes()
+
+		recipes, err := RecordsAdapterHelper(adapters.StrictParsePolicy()).DecodeRecords(context.Background(), benchmarkInput, func(*adapters.RecordError) {}, nil)
+		if err != nil {
+			panic(err)
+		}
+		benchmarkRecipes = *recipes
+	})
+
+	return benchmarkInput, benchmarkRecipes
+}
+
+// benchmarkKeepersHelper loads the benchmark dataset into keepers
+func benchmarkKeepersHelper(b *testing.B) (*keepers.RecipeKeeper, *keepers.RecipeNameSlicesKeeper, *keepers.DeliveryKeeper) {
+	input, _ := benchmarkDatasetHelper()
+
+	rk, rnsk, dk, err := loaders.LoadFromBytesContext(context.Background(), input, "benchmark", RecordsAdapterHelper(adapters.StrictParsePolicy()), nil, nil, loaders.DefaultParallelism, loaders.Observer{})
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	return rk, rnsk, dk
+}
+
+func BenchmarkDecodeRecords(b *testing.B) {
+	input, _ := benchmarkDatasetHelper()
+
+	workers := []int{1}
+	if cpus := runtime.NumCPU(); cpus > 1 {
+		workers = append(workers, cpus)
+	}
+
+	for _, workers := range workers {
+		b.Run(fmt.Sprintf("workers_%d", workers), func(b *testing.B) {
+			recipesAdapter := RecordsAdapterHelper(adapters.StrictParsePolicy())
+			recipesAdapter.SetWorkers(workers)
+			b.SetBytes(int64(len(input)))
+			b.ReportAllocs()
+
+			for i := 0; i < b.N; i++ {
+				if _, err := recipesAdapter.DecodeRecords(context.Background(), input, func(*adapters.RecordError) {}, nil); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// deliveryExpression parses the delivery strings the strict policy accepts,
+// to compare the policies against a regular expression
+var deliveryExpression = regexp.MustCompile(`^(Monday|Tuesday|Wednesday|Thursday|Friday|Saturday|Sunday) (1[0-2]|[1-9])(AM|PM) - (1[0-2]|[1-9])(AM|PM)$`)
+
+// parseDeliveryRegexp parses value with deliveryExpression
+func parseDeliveryRegexp(value string) (string, int, int, bool) {
+	matches := deliveryExpression.FindStringSubmatch(value)
+	if matches == nil {
+		return "", 0, 0, false
+	}
+
+	hour := func(digits string, suffix string) int {
+		value, _ := strconv.Atoi(digits)
+		if value == 12 {
+			value = 0
+		}
+		if suffix == "PM" {
+			value += 12
+		}
+		return value
+	}
+
+	return matches[1], hour(matches[2], matches[3]), hour(matches[4], matches[5]), true
+}
+
+// BenchmarkParseDelivery compares the parsing policies with a regular
+// expression, the reason they walk the strings by hand
+func BenchmarkParseDelivery(b *testing.B) {
+	deliveries := []string{"Wednesday 8AM - 2PM", "Saturday 12AM - 11PM", "Monday 10AM - 12PM"}
+
+	for _, policy := range []adapters.ParsePolicy{adapters.StrictParsePolicy(), adapters.LenientParsePolicy()} {
+		b.Run(policy.String(), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, _, _, err := policy.ParseDelivery(deliveries[i%len(deliveries)]); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+
+	b.Run("regexp", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, _, _, ok := parseDeliveryRegexp(deliveries[i%len(deliveries)]); !ok {
+				b.Fatal("delivery not parsed")
+			}
+		}
+	})
+}
+
+func BenchmarkBuildRecipeKeeper(b *testing.B) {
+	_, recipes := benchmarkDatasetHelper()
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		rk := keepers.NewRecipeKeeper()
+		for j := range recipes {
+			_ = rk.Add(recipes[j].ToRecipe())
+		}
+	}
+}
+
+func BenchmarkBuildRecipeNameSlicesKeeper(b *testing.B) {
+	rk, _, _ := benchmarkKeepersHelper(b)
+	recipes := rk.GetMap()
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		rnsk := keepers.NewRecipeNameSlicesKeeper()
+		rnsk.Load(recipes)
+	}
+}
+
+func BenchmarkBuildDeliveryKeeper(b *testing.B) {
+	_, recipes := benchmarkDatasetHelper()
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		dk := keepers.NewDeliveryKeeper()
+		for j := range recipes {
+			dk.Add(recipes[j].ToDelivery())
+		}
+	}
+}
+
+// BenchmarkLoad decodes the dataset and builds every keeper out of it, the way
+// the CLI does
+func BenchmarkLoad(b *testing.B) {
+	input, _ := benchmarkDatasetHelper()
+	b.SetBytes(int64(len(input)))
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		benchmarkKeepersHelper(b)
+	}
+}
+
+func BenchmarkUniqueRecipeCount(b *testing.B) {
+	rk, _, _ := benchmarkKeepersHelper(b)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		rk.Count()
+	}
+}
+
+func BenchmarkSearchRecipes(b *testing.B) {
+	_, rnsk, _ := benchmarkKeepersHelper(b)
+	names := []string{"Chicken", "Creamy", "Tacos"}
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		rnsk.GetSome(names)
+	}
+}
+
+func BenchmarkBusiestPostcode(b *testing.B) {
+	_, _, dk := benchmarkKeepersHelper(b)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		dk.GetBusiestPostcode()
+	}
+}
+
+func BenchmarkTopPostcodes(b *testing.B) {
+	_, _, dk := benchmarkKeepersHelper(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		dk.TopPostcodes(10)
+	}
+}
+
+func BenchmarkCountByInterval(b *testing.B) {
+	_, _, dk := benchmarkKeepersHelper(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		dk.CountByInterval("10000", "10AM", "3PM")
+	}
+}
+
+// BenchmarkAdHocQuery runs a query of the query command over every delivery
+func BenchmarkAdHocQuery(b *testing.B) {
+	_, recipes := benchmarkDatasetHelper()
+	rows := make([]queries.Row, len(recipes))
+	for i := range recipes {
+		delivery := recipes[i].ToDelivery()
+		rows[i] = queries.Row{Recipe: recipes[i].Recipe, Postcode: delivery.Postcode, Weekday: recipes[i].Delivery.Weekday, From: delivery.From, To: delivery.To}
+	}
+
+	query, err := queries.Parse("SELECT recipe, count(*) FROM deliveries WHERE recipe LIKE '%Chicken%' AND from >= 9 GROUP BY recipe ORDER BY 2 DESC LIMIT 10")
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		query.Execute(rows)
+	}
+}
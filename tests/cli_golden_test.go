@@ -0,0 +1,184 @@
+package tests
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"recipe-stats/cmd"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// update rewrites the golden files of TestCLIGolden with the current output:
+// go test ./tests -run TestCLIGolden -update
+var update = flag.Bool("update", false, "rewrite the golden files of the CLI tests with the current output")
+
+// cliEnv makes the test binary run the CLI with its arguments in place of the
+// tests, so every step of TestCLIGolden runs in a process of its own, with the
+// flags, the config file and the exit code of the real thing
+const cliEnv = "RECIPE_STATS_GOLDEN_CLI"
+
+// tmpPlaceholder stands for a temporary directory of the case in the
+// arguments and the output of its steps
+const tmpPlaceholder = "{{tmp}}"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(cliEnv) == "1" {
+		cmd.Execute()
+		os.Exit(0)
+	}
+
+	os.Exit(m.Run())
+}
+
+// cliGoldenCases are run from the root of the repository, so config.yml is read
+// and the fixtures are in tests/testdata. Every case is a sequence of steps,
+// the arguments of the CLI, with the output of all of them in
+// testdata/golden/<name>.golden.
+var cliGoldenCases = []struct {
+	name  string
+	steps [][]string
+}{
+	{"stats", [][]string{
+		{"-f", "tests/testdata/test_calculation_fixtures_full.json", "-c", "-s", "Chicken,Steak", "-p", "10145", "--from", "8AM", "--to", "2PM"},
+	}},
+	{"stats_window_without_deliveries", [][]string{
+		{"-f", "tests/testdata/test_calculation_fixtures_full.json", "-p", "10145", "--from", "1AM", "--to", "2AM"},
+	}},
+	{"stats_appended", [][]string{
+		{"-f", "tests/testdata/test_calculation_fixtures_single.json", "-a", "tests/testdata/test_calculation_fixtures_double.json", "-c"},
+	}},
+	{"stats_empty_file", [][]string{
+		{"-f", "tests/testdata/test_calculation_fixtures_empty.json", "-c"},
+	}},
+	{"stats_missing_file", [][]string{
+		{"-f", "tests/testdata/missing.json", "-c"},
+	}},
+	{"stats_missing_appended_file", [][]string{
+		{"-f", "tests/testdata/test_calculation_fixtures_single.json", "-a", "tests/testdata/missing.json", "-c"},
+	}},
+	{"stats_rejected_records", [][]string{
+		{"-f", "tests/testdata/test_parse_policy_fixtures.json", "-c", "-s", "Chicken"},
+	}},
+	{"stats_lenient_parse_policy", [][]string{
+		{"-f", "tests/testdata/test_parse_policy_fixtures.json", "-c", "-s", "Chicken", "--parse-policy", "lenient"},
+	}},
+	{"invalid_parse_policy", [][]string{
+		{"-f", "tests/testdata/test_calculation_fixtures_full.json", "-c", "--parse-policy", "sloppy"},
+	}},
+	{"invalid_workers", [][]string{
+		{"-f", "tests/testdata/test_calculation_fixtures_full.json", "-c", "--workers", "0"},
+	}},
+	{"snapshot", [][]string{
+		{"index", "build", "-f", "tests/testdata/test_calculation_fixtures_full.json", "-o", tmpPlaceholder + "/full.rsidx"},
+		{"-f", tmpPlaceholder + "/full.rsidx", "-c", "-s", "Chicken,Steak", "-p", "10145", "--from", "8AM", "--to", "2PM"},
+	}},
+	{"snapshot_rejected_records", [][]string{
+		{"index", "build", "-f", "tests/testdata/test_parse_policy_fixtures.json", "-o", tmpPlaceholder + "/rejected.rsidx", "--reject-file", tmpPlaceholder + "/rejected.ndjson"},
+		{"-f", tmpPlaceholder + "/rejected.rsidx", "-c", "-s", "Chicken"},
+	}},
+	{"export_snapshot", [][]string{
+		{"index", "build", "-f", "tests/testdata/test_calculation_fixtures_single.json", "-o", tmpPlaceholder + "/single.rsidx"},
+		{"export", "sqlite", "-f", tmpPlaceholder + "/single.rsidx", "-o", tmpPlaceholder + "/single.sqlite"},
+	}},
+	{"validate_valid", [][]string{
+		{"validate", "-f", "tests/testdata/test_calculation_fixtures_full.json"},
+	}},
+	{"validate_invalid", [][]string{
+		{"validate", "-f", "tests/testdata/test_validation_fixtures_invalid.json", "--samples", "2"},
+	}},
+	{"validate_snapshot", [][]string{
+		{"index", "build", "-f", "tests/testdata/test_calculation_fixtures_single.json", "-o", tmpPlaceholder + "/single.rsidx"},
+		{"validate", "-f", tmpPlaceholder + "/single.rsidx"},
+	}},
+	{"query", [][]string{
+		{"query", "-f", "tests/testdata/test_calculation_fixtures_full.json", "SELECT recipe, count(*) FROM deliveries WHERE recipe LIKE '%Chicken%' GROUP BY recipe ORDER BY 2 DESC, 1 LIMIT 3"},
+	}},
+	{"query_table", [][]string{
+		{"query", "-f", "tests/testdata/test_calculation_fixtures_full.json", "--format", "table", "SELECT postcode, count(*) FROM deliveries GROUP BY postcode ORDER BY 2 DESC, 1 LIMIT 3"},
+	}},
+	{"query_syntax_error", [][]string{
+		{"query", "-f", "tests/testdata/test_calculation_fixtures_full.json", "SELEC recipe FROM deliveries"},
+	}},
+	{"generate", [][]string{
+		{"generate", "-o", "-", "--records", "5", "--seed", "7", "--postcode-distribution", "zipf", "--window-distribution", "business"},
+	}},
+	{"generate_invalid", [][]string{
+		{"generate", "-o", "-", "--recipes", "0"},
+	}},
+	{"unknown_command", [][]string{
+		{"bogus"},
+	}},
+}
+
+func TestCLIGolden(t *testing.T) {
+	executable, err := os.Executable()
+	assert.NoError(t, err)
+
+	for _, c := range cliGoldenCases {
+		t.Run(c.name, func(t *testing.T) {
+			tmp := t.TempDir()
+
+			output := bytes.Buffer{}
+			for _, step := range c.steps {
+				output.WriteString(runCLIHelper(t, executable, tmp, step))
+			}
+
+			goldenPath := filepath.Join("testdata", "golden", c.name+".golden")
+			if *update {
+				assert.NoError(t, ioutil.WriteFile(goldenPath, output.Bytes(), 0644))
+				return
+			}
+
+			golden, err := ioutil.ReadFile(goldenPath)
+			if !assert.NoError(t, err, "run go test ./tests -run TestCLIGolden -update to create the golden file") {
+				return
+			}
+			assert.Equal(t, string(golden), output.String(), "run go test ./tests -run TestCLIGolden -update if the change is expected")
+		})
+	}
+}
+
+// runCLIHelper runs the CLI with args from the root of the repository,
+// returning the command line along with its exit code, stdout and stderr, as
+// they are kept in the golden files. tmp replaces tmpPlaceholder in args, and
+// the other way around in the output.
+func runCLIHelper(t *testing.T, executable string, tmp string, args []string) string {
+	replaced := make([]string, len(args))
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		replaced[i] = strings.Replace(arg, tmpPlaceholder, tmp, -1)
+		quoted[i] = arg
+		if strings.ContainsAny(arg, " '\"") {
+			quoted[i] = strconv.Quote(arg)
+		}
+	}
+
+	stdout, stderr := bytes.Buffer{}, bytes.Buffer{}
+	cli := exec.Command(executable, replaced...)
+	cli.Dir = ".."
+	cli.Env = append(os.Environ(), cliEnv+"=1")
+	cli.Stdout = &stdout
+	cli.Stderr = &stderr
+
+	exitCode := 0
+	if err := cli.Run(); err != nil {
+		exitErr, ok := err.(*exec.ExitError)
+		if !ok {
+			t.Fatal(err)
+		}
+		exitCode = exitErr.ExitCode()
+	}
+
+	return fmt.Sprintf("$ recipe-stats %s\nexit code: %d\n--- stdout\n%s--- stderr\n%s\n",
+		strings.Join(quoted, " "), exitCode,
+		strings.Replace(stdout.String(), tmp, tmpPlaceholder, -1),
+		strings.Replace(stderr.String(), tmp, tmpPlaceholder, -1))
+}
@@ -0,0 +1,128 @@
+package tests
+
+import (
+	"recipe-stats/keepers"
+	"recipe-stats/models"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Those tests are meant to be run with the -race flag so concurrent readers
+// and writers on the keepers get checked by the race detector.
+
+func TestRecipeKeeperConcurrentAddAndRead(t *testing.T) {
+	rk := keepers.NewRecipeKeeper()
+	wg := new(sync.WaitGroup)
+
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				_ = rk.Add(models.Recipe{Recipe: "Grilled Cheese"})
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				_ = rk.Count()
+				_ = rk.GetMap()
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 1, rk.Count())
+	assert.Equal(t, 800, rk.GetMap()["Grilled Cheese"].Count)
+}
+
+func TestRecipeKeeperConcurrentCountAndReplace(t *testing.T) {
+	source := keepers.NewRecipeKeeper()
+	_ = source.Add(models.Recipe{Recipe: "Grilled Cheese"})
+	data, err := source.MarshalBinary()
+	assert.NoError(t, err)
+
+	rk := keepers.NewRecipeKeeper()
+	wg := new(sync.WaitGroup)
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for j := 0; j < 1000; j++ {
+			_ = rk.UnmarshalBinary(data)
+			rk.Merge(&source)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for j := 0; j < 1000; j++ {
+			_ = rk.Count()
+		}
+	}()
+	wg.Wait()
+
+	assert.Equal(t, 1, rk.Count())
+}
+
+func TestRecipeKeeperGetMapIsCopy(t *testing.T) {
+	rk := keepers.NewRecipeKeeper()
+	_ = rk.Add(models.Recipe{Recipe: "Grilled Cheese"})
+
+	recipes := rk.GetMap()
+	delete(recipes, "Grilled Cheese")
+	recipes["Tex-Mex Tilapia"] = models.Recipe{Recipe: "Tex-Mex Tilapia", Count: 1}
+
+	assert.Equal(t, 1, rk.Count())
+	assert.Contains(t, rk.GetMap(), "Grilled Cheese")
+}
+
+func TestRecipeNameSlicesKeeperConcurrentLoadAndGet(t *testing.T) {
+	rnsk := keepers.NewRecipeNameSlicesKeeper()
+	wg := new(sync.WaitGroup)
+
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			rnsk.Load(map[string]models.Recipe{
+				"Grilled Cheese": {Recipe: "Grilled Cheese", Count: 1},
+			})
+		}()
+		go func() {
+			defer wg.Done()
+			_ = rnsk.GetSome([]string{"Cheese", "Grilled"})
+		}()
+	}
+	wg.Wait()
+
+	recipes, found := rnsk.Get("Cheese")
+	assert.True(t, found)
+	assert.Equal(t, []models.Recipe{{Recipe: "Grilled Cheese", Count: 8}}, recipes)
+}
+
+func TestDeliveryKeeperConcurrentAddAndCount(t *testing.T) {
+	dk := keepers.NewDeliveryKeeper()
+	wg := new(sync.WaitGroup)
+
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				dk.Add(models.Delivery{Postcode: "10120", From: 9, To: 14})
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				_ = dk.CountByInterval("10120", "9AM", "2PM")
+				_ = dk.GetBusiestPostcode()
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 800, dk.CountByInterval("10120", "9AM", "2PM"))
+	assert.Equal(t, keepers.BusiestPostcode{Code: "10120", Count: 800}, dk.GetBusiestPostcode())
+}
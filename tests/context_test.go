@@ -0,0 +1,117 @@
+package tests
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"recipe-stats/adapters"
+	"recipe-stats/loaders"
+	"recipe-stats/queries"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const contextFixture = "./testdata/test_calculation_fixtures_full.json"
+
+func canceledContextHelper() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	return ctx
+}
+
+func TestLoadFromGeneralRecipeContext(t *testing.T) {
+	rk, rnsk, dk, err := loaders.LoadFromGeneralRecipeContext(context.Background(), contextFixture, RecordsAdapterHelper(adapters.StrictParsePolicy()), nil, nil, loaders.Observer{})
+	assert.NoError(t, err)
+	assert.Equal(t, LoadRecipeKeeperHelper(contextFixture).GetMap(), rk.GetMap())
+	assert.Equal(t, LoadRecipeNameSliceKeeperHelper(contextFixture).Words(), rnsk.Words())
+	assert.Equal(t, LoadDeliveryKeeperHelper(contextFixture).GetBusiestPostcode(), dk.GetBusiestPostcode())
+
+	rk, rnsk, dk, err = loaders.LoadFromGeneralRecipeContext(canceledContextHelper(), contextFixture, RecordsAdapterHelper(adapters.StrictParsePolicy()), nil, nil, loaders.Observer{})
+	assert.Equal(t, context.Canceled, err)
+	assert.Nil(t, rk)
+	assert.Nil(t, rnsk)
+	assert.Nil(t, dk)
+}
+
+func TestLoadFromGeneralRecipeContextDeadline(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), -time.Second)
+	defer cancel()
+
+	_, _, _, err := loaders.LoadFromGeneralRecipeContext(ctx, contextFixture, RecordsAdapterHelper(adapters.StrictParsePolicy()), nil, nil, loaders.Observer{})
+	assert.Equal(t, context.DeadlineExceeded, err)
+}
+
+func TestAppendFromGeneralRecipeContext(t *testing.T) {
+	rk, rnsk, dk, err := loaders.LoadFromGeneralRecipe(contextFixture, false)
+	assert.NoError(t, err)
+	count := rk.Count()
+
+	err = loaders.AppendFromGeneralRecipeContext(canceledContextHelper(), contextFixture, rk, rnsk, dk, RecordsAdapterHelper(adapters.StrictParsePolicy()), nil, loaders.Observer{})
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, count, rk.Count())
+}
+
+func TestSnapshotContext(t *testing.T) {
+	snapshotPath := filepath.Join(t.TempDir(), "full.rsidx")
+
+	err := loaders.BuildSnapshotContext(canceledContextHelper(), contextFixture, snapshotPath, RecordsAdapterHelper(adapters.StrictParsePolicy()), nil, loaders.Observer{})
+	assert.Equal(t, context.Canceled, err)
+	_, err = os.Stat(snapshotPath)
+	assert.True(t, os.IsNotExist(err))
+
+	assert.NoError(t, loaders.BuildSnapshotContext(context.Background(), contextFixture, snapshotPath, RecordsAdapterHelper(adapters.StrictParsePolicy()), nil, loaders.Observer{}))
+
+	_, _, _, err = loaders.LoadFromSnapshotContext(canceledContextHelper(), snapshotPath, nil, loaders.Observer{})
+	assert.Equal(t, context.Canceled, err)
+}
+
+func TestQueryContext(t *testing.T) {
+	query, err := queries.Parse("SELECT recipe, count(*) FROM deliveries GROUP BY recipe")
+	assert.NoError(t, err)
+
+	result, err := query.ExecuteContext(context.Background(), queryRows)
+	assert.NoError(t, err)
+	assert.Equal(t, query.Execute(queryRows), result)
+
+	result, err = query.ExecuteContext(canceledContextHelper(), queryRows)
+	assert.Equal(t, context.Canceled, err)
+	assert.Nil(t, result)
+
+	projection, err := queries.Parse("SELECT recipe FROM deliveries")
+	assert.NoError(t, err)
+	_, err = projection.ExecuteContext(canceledContextHelper(), queryRows)
+	assert.Equal(t, context.Canceled, err)
+
+	_, err = loaders.QueryFromGeneralRecipeContext(canceledContextHelper(), contextFixture, query, RecordsAdapterHelper(adapters.StrictParsePolicy()), loaders.Observer{})
+	assert.Equal(t, context.Canceled, err)
+}
+
+func TestExportToSQLiteContext(t *testing.T) {
+	outputPath := filepath.Join(t.TempDir(), "full.db")
+
+	err := loaders.ExportToSQLiteContext(canceledContextHelper(), contextFixture, outputPath, RecordsAdapterHelper(adapters.StrictParsePolicy()), loaders.Observer{})
+	assert.Equal(t, context.Canceled, err)
+}
+
+func TestKeepersContext(t *testing.T) {
+	rnsk := LoadRecipeNameSliceKeeperHelper(contextFixture)
+	dk := LoadDeliveryKeeperHelper(contextFixture)
+
+	recipes, err := rnsk.GetSomeContext(context.Background(), []string{"Cheese", "Chicken"})
+	assert.NoError(t, err)
+	assert.Equal(t, rnsk.GetSome([]string{"Cheese", "Chicken"}), recipes)
+
+	recipes, err = rnsk.GetSomeContext(canceledContextHelper(), []string{"Cheese", "Chicken"})
+	assert.Equal(t, context.Canceled, err)
+	assert.Nil(t, recipes)
+
+	count, err := dk.CountByIntervalContext(context.Background(), "10120", "9AM", "2PM")
+	assert.NoError(t, err)
+	assert.Equal(t, dk.CountByInterval("10120", "9AM", "2PM"), count)
+
+	_, err = dk.CountByIntervalContext(canceledContextHelper(), "10120", "9AM", "2PM")
+	assert.Equal(t, context.Canceled, err)
+}
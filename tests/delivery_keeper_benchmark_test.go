@@ -0,0 +1,155 @@
+package tests
+
+import (
+	"fmt"
+	"math/rand"
+	"recipe-stats/keepers"
+	"recipe-stats/models"
+	"runtime"
+	"testing"
+)
+
+// slicesPostcode is the layout DeliveryKeeper used to have, keeping a copy of
+// every delivery within its time range, to compare the current one against
+type slicesPostcode struct {
+	Code            string
+	Deliveries      [24][24][]models.Delivery
+	DeliveriesCount int
+}
+
+// slicesDeliveryKeeper adds deliveries and counts them by interval the way
+// DeliveryKeeper used to
+type slicesDeliveryKeeper struct {
+	postcodes map[string]*slicesPostcode
+}
+
+func (dk *slicesDeliveryKeeper) Add(delivery models.Delivery) {
+	foundPostcode, found := dk.postcodes[delivery.Postcode]
+	if !found {
+		foundPostcode = &slicesPostcode{Code: delivery.Postcode}
+		dk.postcodes[delivery.Postcode] = foundPostcode
+	}
+	foundPostcode.DeliveriesCount++
+	foundPostcode.Deliveries[delivery.From][delivery.To] = append(foundPostcode.Deliveries[delivery.From][delivery.To], delivery)
+}
+
+func (dk *slicesDeliveryKeeper) CountByInterval(code string, start string, end string) int {
+	foundPostcode, found := dk.postcodes[code]
+	if !found {
+		return 0
+	}
+
+	rangeBottom, _ := keepers.TimeToIndex(start)
+	rangeTop, _ := keepers.TimeToIndex(end)
+	rangeTop++
+	count := 0
+	for _, endTimes := range foundPostcode.Deliveries[rangeBottom:rangeTop] {
+		for _, deliveries := range endTimes[rangeBottom:rangeTop] {
+			count += len(deliveries)
+		}
+	}
+
+	return count
+}
+
+// intervalCounter is what the benchmarks query on every layout
+type intervalCounter interface {
+	CountByInterval(code string, start string, end string) int
+}
+
+// deliveryKeeperLayouts are the layouts compared by the benchmarks
+var deliveryKeeperLayouts = []struct {
+	name  string
+	build func(deliveries []models.Delivery) intervalCounter
+}{
+	{"slices", func(deliveries []models.Delivery) intervalCounter {
+		dk := &slicesDeliveryKeeper{postcodes: map[string]*slicesPostcode{}}
+		for _, delivery := range deliveries {
+			dk.Add(delivery)
+		}
+		return dk
+	}},
+	{"counts", func(deliveries []models.Delivery) intervalCounter {
+		dk := keepers.NewDeliveryKeeper()
+		for _, delivery := range deliveries {
+			dk.Add(delivery)
+		}
+		return &dk
+	}},
+	{"counts_retained", func(deliveries []models.Delivery) intervalCounter {
+		dk := keepers.NewRetainingDeliveryKeeper()
+		for _, delivery := range deliveries {
+			dk.Add(delivery)
+		}
+		return &dk
+	}},
+}
+
+// benchmarkDeliveriesHelper generates count deliveries spread over postcodes,
+// every one with a postcode string of its own, as decoded from a file
+func benchmarkDeliveriesHelper(count int, postcodes int) []models.Delivery {
+	random := rand.New(rand.NewSource(1))
+
+	deliveries := make([]models.Delivery, count)
+	for i := range deliveries {
+		from := random.Intn(24)
+		deliveries[i] = models.Delivery{Postcode: fmt.Sprintf("%05d", 10000+random.Intn(postcodes)), From: from, To: from + random.Intn(24-from)}
+	}
+
+	return deliveries
+}
+
+// heapInUse is the memory held by live objects after a garbage collection
+func heapInUse() uint64 {
+	runtime.GC()
+	stats := runtime.MemStats{}
+	runtime.ReadMemStats(&stats)
+
+	return stats.HeapAlloc
+}
+
+// BenchmarkDeliveryKeeperBuild adds a million deliveries over a thousand
+// postcodes, reporting how much memory the keeper holds once built as
+// keeper-bytes
+func BenchmarkDeliveryKeeperBuild(b *testing.B) {
+	deliveries := benchmarkDeliveriesHelper(1000000, 1000)
+
+	for _, layout := range deliveryKeeperLayouts {
+		b.Run(layout.name, func(b *testing.B) {
+			b.ReportAllocs()
+
+			var keeperBytes uint64
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				before := heapInUse()
+				b.StartTimer()
+
+				dk := layout.build(deliveries)
+
+				b.StopTimer()
+				keeperBytes = heapInUse() - before
+				runtime.KeepAlive(dk)
+				b.StartTimer()
+			}
+
+			b.ReportMetric(float64(keeperBytes), "keeper-bytes")
+		})
+	}
+}
+
+// BenchmarkDeliveryKeeperCountByInterval counts the deliveries of a postcode
+// within a time window out of a million deliveries
+func BenchmarkDeliveryKeeperCountByInterval(b *testing.B) {
+	deliveries := benchmarkDeliveriesHelper(1000000, 1000)
+
+	for _, layout := range deliveryKeeperLayouts {
+		b.Run(layout.name, func(b *testing.B) {
+			dk := layout.build(deliveries)
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				dk.CountByInterval("10500", "10AM", "3PM")
+			}
+		})
+	}
+}
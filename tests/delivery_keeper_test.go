@@ -1,7 +1,10 @@
 package tests
 
 import (
+	"context"
+	"errors"
 	"recipe-stats/keepers"
+	"recipe-stats/models"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -40,11 +43,21 @@ func TestTimeToIndex(t *testing.T) {
 	}
 
 	for i := 0; i < len(expectations); i++ {
-		value := keepers.TimeToIndex(expectations[i].time)
+		value, err := keepers.TimeToIndex(expectations[i].time)
+		assert.NoError(t, err)
 		assert.Equal(t, expectations[i].value, value)
 	}
 }
 
+func TestTimeToIndexErrors(t *testing.T) {
+	times := []string{"", "M", "0", "AM", "0AM", "13PM", "123AM", "-1AM", "+1PM", "9XM", "9am", "9 AM", "\xff\xffPM"}
+
+	for _, time := range times {
+		_, err := keepers.TimeToIndex(time)
+		assert.True(t, errors.Is(err, keepers.ErrInvalidTime), time)
+	}
+}
+
 func TestCountByInterval(t *testing.T) {
 	filePath := "./testdata/test_calculation_fixtures_10122.json"
 	dk := LoadDeliveryKeeperHelper(filePath)
@@ -81,13 +94,110 @@ func TestCountByIntervalLowerLimit(t *testing.T) {
 	assert.Equal(t, 1, filtered)
 }
 
+func TestCountByIntervalInvalidTimes(t *testing.T) {
+	filePath := "./testdata/test_calculation_fixtures_lower_limit_delivery.json"
+	dk := LoadDeliveryKeeperHelper(filePath)
+
+	for _, window := range [][2]string{{"M", "8AM"}, {"12AM", "13PM"}, {"0AM", "8AM"}} {
+		count, err := dk.CountByIntervalContext(context.Background(), "10174", window[0], window[1])
+		assert.True(t, errors.Is(err, keepers.ErrInvalidTime), window)
+		assert.Zero(t, count)
+		assert.Zero(t, dk.CountByInterval("10174", window[0], window[1]))
+	}
+
+	// a time range ending before it starts has no deliveries
+	count, err := dk.CountByIntervalContext(context.Background(), "10174", "8AM", "12AM")
+	assert.NoError(t, err)
+	assert.Zero(t, count)
+}
+
 func TestBusiestPostcode(t *testing.T) {
 	filePath := "./testdata/test_calculation_fixtures_busiest_postalcode.json"
 	dk := LoadDeliveryKeeperHelper(filePath)
 
-	result := dk.BusiestPostcode
+	result := dk.GetBusiestPostcode()
 
-	assert.NotNil(t, result)
 	assert.Equal(t, "10129", result.Code)
 	assert.Equal(t, 6, result.Count)
 }
+
+func TestDeliveriesSortedByTimeRange(t *testing.T) {
+	dk := keepers.NewDeliveryKeeper()
+	dk.Add(models.Delivery{Postcode: "10120", From: 10, To: 14})
+	dk.Add(models.Delivery{Postcode: "10120", From: 8, To: 14})
+	dk.Add(models.Delivery{Postcode: "10120", From: 10, To: 14})
+
+	assert.False(t, dk.RetainsDeliveries())
+	assert.Equal(t, []models.Delivery{
+		{Postcode: "10120", From: 8, To: 14},
+		{Postcode: "10120", From: 10, To: 14},
+		{Postcode: "10120", From: 10, To: 14},
+	}, dk.Deliveries("10120"))
+	assert.Nil(t, dk.Deliveries("10224"))
+}
+
+func TestRetainingDeliveryKeeper(t *testing.T) {
+	dk := keepers.NewRetainingDeliveryKeeper()
+	dk.Add(models.Delivery{Postcode: "10120", From: 10, To: 14})
+	dk.Add(models.Delivery{Postcode: "10120", From: 8, To: 14})
+
+	other := keepers.NewDeliveryKeeper()
+	other.Add(models.Delivery{Postcode: "10120", From: 9, To: 13})
+	other.Add(models.Delivery{Postcode: "10120", From: 7, To: 13})
+	dk.Merge(&other)
+
+	assert.True(t, dk.RetainsDeliveries())
+	assert.Equal(t, []models.Delivery{
+		{Postcode: "10120", From: 10, To: 14},
+		{Postcode: "10120", From: 8, To: 14},
+		{Postcode: "10120", From: 7, To: 13},
+		{Postcode: "10120", From: 9, To: 13},
+	}, dk.Deliveries("10120"))
+	assert.Equal(t, 3, dk.CountByInterval("10120", "8AM", "2PM"))
+
+	// a keeper that doesn't retain them doesn't start to when merging one that does
+	other.Merge(&dk)
+	assert.False(t, other.RetainsDeliveries())
+	assert.Equal(t, 6, other.CountByInterval("10120", "7AM", "2PM"))
+}
+
+func TestRetainingDeliveryKeeperFromSnapshot(t *testing.T) {
+	dk := keepers.NewDeliveryKeeper()
+	dk.Add(models.Delivery{Postcode: "10120", From: 10, To: 14})
+	dk.Add(models.Delivery{Postcode: "10120", From: 8, To: 14})
+	data, err := dk.MarshalBinary()
+	assert.NoError(t, err)
+
+	decoded := keepers.NewRetainingDeliveryKeeper()
+	assert.NoError(t, decoded.UnmarshalBinary(data))
+	decoded.Add(models.Delivery{Postcode: "10120", From: 9, To: 13})
+
+	assert.Equal(t, []models.Delivery{
+		{Postcode: "10120", From: 8, To: 14},
+		{Postcode: "10120", From: 10, To: 14},
+		{Postcode: "10120", From: 9, To: 13},
+	}, decoded.Deliveries("10120"))
+	assert.Equal(t, keepers.BusiestPostcode{Code: "10120", Count: 3}, decoded.GetBusiestPostcode())
+}
+
+func TestDeliveryKeeperZeroValue(t *testing.T) {
+	dk := keepers.DeliveryKeeper{}
+
+	assert.Zero(t, dk.CountByInterval("10120", "9AM", "2PM"))
+	count, err := dk.CountByIntervalContext(context.Background(), "10120", "9AM", "2PM")
+	assert.NoError(t, err)
+	assert.Zero(t, count)
+	assert.Equal(t, keepers.BusiestPostcode{}, dk.GetBusiestPostcode())
+	assert.Empty(t, dk.Postcodes())
+	assert.Empty(t, dk.TopPostcodes(10))
+	data, err := dk.MarshalBinary()
+	assert.NoError(t, err)
+
+	other := keepers.NewDeliveryKeeper()
+	other.Merge(&dk)
+	assert.Equal(t, keepers.BusiestPostcode{}, other.GetBusiestPostcode())
+
+	decoded := keepers.DeliveryKeeper{}
+	assert.NoError(t, decoded.UnmarshalBinary(data))
+	assert.Empty(t, decoded.Postcodes())
+}
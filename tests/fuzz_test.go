@@ -0,0 +1,77 @@
+//go:build go1.18
+// +build go1.18
+
+package tests
+
+import (
+	"errors"
+	"recipe-stats/adapters"
+	"recipe-stats/keepers"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// The fuzz targets run their seeds and the corpus in testdata/fuzz along with
+// the other tests. To look for new crashes, run one of them at a time:
+// go test ./tests -run '^$' -fuzz FuzzTimeToIndex -fuzztime 1m
+
+// FuzzTimeToIndex checks TimeToIndex takes any string, turning the times the
+// strict policy accepts into the same hours and reporting anything else as
+// ErrInvalidTime
+func FuzzTimeToIndex(f *testing.F) {
+	for _, seed := range []string{"12AM", "1AM", "11AM", "12PM", "1PM", "11PM", "08AM", "0AM", "13PM", "M", "", "AM", "9 AM", "9am", "-1PM"} {
+		f.Add(seed)
+	}
+
+	policy := adapters.StrictParsePolicy()
+	f.Fuzz(func(t *testing.T, value string) {
+		index, err := keepers.TimeToIndex(value)
+
+		hour, parseErr := policy.ParseHour(value)
+		if parseErr != nil {
+			assert.True(t, errors.Is(err, keepers.ErrInvalidTime), "%q: %v", value, err)
+			return
+		}
+
+		assert.NoError(t, err, value)
+		assert.Equal(t, hour, index, value)
+	})
+}
+
+// FuzzGetDeliveryTimes checks GetDeliveryTimes takes any string, returning
+// hours that can be used as time range indexes or a *DeliveryError, and that
+// the lenient policy reads the strings the strict one accepts the same way
+func FuzzGetDeliveryTimes(f *testing.F) {
+	seeds := []string{
+		"Wednesday 9AM - 2PM", "Saturday 12AM - 12PM", "Monday 11PM - 11PM", "Monday 5PM - 2PM",
+		"", "W", "Wednesday", "Wednesday ", "Wednesday 9AM", "Wednesday 9AM - ", "Funday 9AM - 2PM",
+		"Monday 13PM - 2PM", "Monday 9XM - 2PM", "monday 9 am – 2pm", "Sat noon - midnight", " Monday  9AM - 2PM ",
+	}
+	for _, seed := range seeds {
+		f.Add(seed)
+	}
+
+	delivery := adapters.GeneralDelivery{}
+	lenient := adapters.LenientParsePolicy()
+	f.Fuzz(func(t *testing.T, value string) {
+		from, to, err := delivery.GetDeliveryTimes(value)
+		_, lenientFrom, lenientTo, lenientErr := lenient.ParseDelivery(value)
+
+		if lenientErr != nil {
+			assert.IsType(t, &adapters.DeliveryError{}, lenientErr, value)
+		} else {
+			assert.True(t, lenientFrom >= 0 && lenientFrom < 24 && lenientTo >= 0 && lenientTo < 24, value)
+		}
+
+		if err != nil {
+			assert.IsType(t, &adapters.DeliveryError{}, err, value)
+			return
+		}
+
+		assert.True(t, from >= 0 && from < 24 && to >= 0 && to < 24, value)
+		assert.NoError(t, lenientErr, value)
+		assert.Equal(t, from, lenientFrom, value)
+		assert.Equal(t, to, lenientTo, value)
+	})
+}
@@ -25,3 +25,34 @@ func TestUnmarshalParsing(t *testing.T) {
 	assert.NoError(t, err)
 	assert.IsType(t, adapters.GeneralRecipe{}, (*result)[0])
 }
+
+func TestGetDeliveryTimes(t *testing.T) {
+	type expected struct {
+		delivery string
+		from     int
+		to       int
+	}
+	expectations := []expected{
+		expected{delivery: "Wednesday 9AM - 2PM", from: 9, to: 14},
+		expected{delivery: "Saturday 12AM - 12PM", from: 0, to: 12},
+		expected{delivery: "Monday 11PM - 11PM", from: 23, to: 23},
+	}
+
+	delivery := adapters.GeneralDelivery{}
+	for _, expectation := range expectations {
+		from, to, err := delivery.GetDeliveryTimes(expectation.delivery)
+		assert.NoError(t, err)
+		assert.Equal(t, expectation.from, from, expectation.delivery)
+		assert.Equal(t, expectation.to, to, expectation.delivery)
+	}
+}
+
+func TestGetDeliveryTimesErrors(t *testing.T) {
+	deliveries := []string{"", "W", "Wednesday", "Wednesday 9AM", "Funday 9AM - 2PM", "Monday 13PM - 2PM", "Monday 9XM - 2PM"}
+
+	delivery := adapters.GeneralDelivery{}
+	for _, value := range deliveries {
+		_, _, err := delivery.GetDeliveryTimes(value)
+		assert.IsType(t, &adapters.DeliveryError{}, err, value)
+	}
+}
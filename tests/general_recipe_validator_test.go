@@ -0,0 +1,100 @@
+package tests
+
+import (
+	"recipe-stats/adapters"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDeliveryStrict(t *testing.T) {
+	type expected struct {
+		delivery string
+		weekday  string
+		from     int
+		to       int
+	}
+	expectations := []expected{
+		expected{delivery: "Wednesday 8AM - 2PM", weekday: "Wednesday", from: 8, to: 14},
+		expected{delivery: "Saturday 12AM - 12PM", weekday: "Saturday", from: 0, to: 12},
+		expected{delivery: "Monday 11AM - 11PM", weekday: "Monday", from: 11, to: 23},
+	}
+
+	for _, expectation := range expectations {
+		weekday, from, to, err := adapters.ParseDeliveryStrict(expectation.delivery)
+		assert.NoError(t, err)
+		assert.Equal(t, expectation.weekday, weekday)
+		assert.Equal(t, expectation.from, from)
+		assert.Equal(t, expectation.to, to)
+	}
+}
+
+func TestParseDeliveryStrictErrors(t *testing.T) {
+	expectations := map[string]string{
+		"":                     adapters.ErrorClassInvalidDelivery,
+		"Wednesday":            adapters.ErrorClassInvalidDelivery,
+		"Wednesday 8AM 2PM":    adapters.ErrorClassInvalidDelivery,
+		"Wed 8AM - 2PM":        adapters.ErrorClassUnknownWeekday,
+		"Wednesday 8 AM - 2PM": adapters.ErrorClassInvalidTime,
+		"Wednesday 25PM - 2PM": adapters.ErrorClassInvalidTime,
+		"Wednesday 0AM - 2PM":  adapters.ErrorClassInvalidTime,
+		"Wednesday 8am - 2PM":  adapters.ErrorClassInvalidTime,
+		"Wednesday M - 2PM":    adapters.ErrorClassInvalidTime,
+		"Wednesday 5PM - 2PM":  adapters.ErrorClassInvertedWindow,
+	}
+
+	for delivery, class := range expectations {
+		_, _, _, err := adapters.ParseDeliveryStrict(delivery)
+		if assert.IsType(t, &adapters.DeliveryError{}, err, delivery) {
+			assert.Equal(t, class, err.(*adapters.DeliveryError).Class, delivery)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	filePath := "./testdata/test_validation_fixtures_invalid.json"
+	adptr := adapters.NewGeneralRecipeAdapter()
+
+	classes := map[string]int{}
+	offsets := map[int]int64{}
+	total, err := adptr.Validate(filePath, func(recordError *adapters.RecordError) {
+		classes[recordError.Class]++
+		offsets[recordError.Index] = recordError.Offset
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 7, total)
+	assert.Equal(t, map[string]int{
+		adapters.ErrorClassEmptyRecipe:     1,
+		adapters.ErrorClassInvalidPostcode: 1,
+		adapters.ErrorClassUnknownWeekday:  1,
+		adapters.ErrorClassInvalidTime:     1,
+		adapters.ErrorClassInvertedWindow:  1,
+		adapters.ErrorClassMalformedRecord: 2,
+	}, classes)
+	assert.Equal(t, int64(110), offsets[1])
+	assert.NotContains(t, offsets, 0)
+}
+
+func TestValidateValidFile(t *testing.T) {
+	filePath := "./testdata/test_calculation_fixtures_full.json"
+	adptr := adapters.NewGeneralRecipeAdapter()
+
+	problems := 0
+	total, err := adptr.Validate(filePath, func(recordError *adapters.RecordError) {
+		problems++
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 73, total)
+	assert.Equal(t, 0, problems)
+}
+
+func TestValidateMalformedFile(t *testing.T) {
+	filePath := "./testdata/test_calculation_fixtures_empty.json"
+	adptr := adapters.NewGeneralRecipeAdapter()
+
+	_, err := adptr.Validate(filePath, func(recordError *adapters.RecordError) {})
+
+	assert.IsType(t, &adapters.MalformedFileError{}, err)
+}
@@ -0,0 +1,101 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"recipe-stats/adapters"
+	"recipe-stats/generator"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// generateHelper generates the file of config, decoding it strictly
+func generateHelper(t *testing.T, config generator.Config) ([]byte, []adapters.GeneralRecipe) {
+	output := bytes.Buffer{}
+	assert.NoError(t, generator.Generate(&output, config))
+
+	rejected := 0
+	recipes, err := RecordsAdapterHelper(adapters.StrictParsePolicy()).DecodeRecords(context.Background(), output.Bytes(), func(*adapters.RecordError) {
+		rejected++
+	}, nil)
+	assert.NoError(t, err)
+	assert.Zero(t, rejected)
+
+	return output.Bytes(), *recipes
+}
+
+func TestGenerate(t *testing.T) {
+	config := generator.Config{Seed: 7, Records: 5000, Recipes: 20, Postcodes: 50, PostcodeDistribution: generator.PostcodesUniform, WindowDistribution: generator.WindowsUniform}
+	_, recipes := generateHelper(t, config)
+
+	assert.Len(t, recipes, config.Records)
+
+	names := map[string]bool{}
+	postcodes := map[string]bool{}
+	for _, recipe := range recipes {
+		names[recipe.Recipe] = true
+		postcodes[recipe.Postcode] = true
+		assert.Len(t, recipe.Postcode, 5)
+		assert.True(t, recipe.Delivery.From < recipe.Delivery.To, recipe.Delivery)
+	}
+	assert.Len(t, names, config.Recipes)
+	assert.Len(t, postcodes, config.Postcodes)
+}
+
+func TestGenerateIsReproducible(t *testing.T) {
+	config := generator.DefaultConfig()
+	config.Records = 1000
+
+	first, _ := generateHelper(t, config)
+	second, _ := generateHelper(t, config)
+	assert.Equal(t, first, second)
+
+	config.Seed++
+	other, _ := generateHelper(t, config)
+	assert.NotEqual(t, first, other)
+}
+
+func TestGenerateDistributions(t *testing.T) {
+	config := generator.Config{Seed: 1, Records: 20000, Recipes: 30, Postcodes: 1000, PostcodeDistribution: generator.PostcodesZipf, WindowDistribution: generator.WindowsBusiness}
+	_, recipes := generateHelper(t, config)
+
+	busiest := map[string]int{}
+	for _, recipe := range recipes {
+		busiest[recipe.Postcode]++
+		assert.True(t, recipe.Delivery.From >= 6 && recipe.Delivery.From <= 12, recipe.Delivery)
+		assert.True(t, recipe.Delivery.To-recipe.Delivery.From >= 2 && recipe.Delivery.To-recipe.Delivery.From <= 8, recipe.Delivery)
+	}
+
+	// with a thousand postcodes, a uniform distribution would give each one
+	// about 20 deliveries
+	assert.Greater(t, busiest["10000"], config.Records/10)
+}
+
+func TestGenerateEmpty(t *testing.T) {
+	config := generator.DefaultConfig()
+	config.Records = 0
+
+	output, recipes := generateHelper(t, config)
+	assert.Equal(t, "[]\n", string(output))
+	assert.Empty(t, recipes)
+}
+
+func TestGenerateInvalidConfig(t *testing.T) {
+	invalid := []func(*generator.Config){
+		func(c *generator.Config) { c.Records = -1 },
+		func(c *generator.Config) { c.Recipes = 0 },
+		func(c *generator.Config) { c.Recipes = generator.MaxRecipes + 1 },
+		func(c *generator.Config) { c.Postcodes = generator.MaxPostcodes + 1 },
+		func(c *generator.Config) { c.PostcodeDistribution = "normal" },
+		func(c *generator.Config) { c.WindowDistribution = "" },
+	}
+
+	for _, change := range invalid {
+		config := generator.DefaultConfig()
+		change(&config)
+
+		assert.Error(t, config.Validate())
+		assert.Error(t, generator.Generate(&bytes.Buffer{}, config))
+	}
+}
@@ -1,18 +1,19 @@
 package tests
 
 import (
+	"recipe-stats/adapters"
 	"recipe-stats/keepers"
 	"recipe-stats/loaders"
 )
 
-func LoadRecipeKeeperHelper(filePath string) keepers.RecipeKeeper {
+func LoadRecipeKeeperHelper(filePath string) *keepers.RecipeKeeper {
 	rk, _, _, err := loaders.LoadFromGeneralRecipe(filePath, false)
 
 	if err != nil {
 		panic(err)
 	}
 
-	return *rk
+	return rk
 }
 
 func LoadRecipeNameSliceKeeperHelper(filePath string) *keepers.RecipeNameSlicesKeeper {
@@ -25,12 +26,22 @@ func LoadRecipeNameSliceKeeperHelper(filePath string) *keepers.RecipeNameSlicesK
 	return rnsk
 }
 
-func LoadDeliveryKeeperHelper(filePath string) keepers.DeliveryKeeper {
+func LoadDeliveryKeeperHelper(filePath string) *keepers.DeliveryKeeper {
 	_, _, dk, err := loaders.LoadFromGeneralRecipe(filePath, false)
 
 	if err != nil {
 		panic(err)
 	}
 
-	return *dk
+	return dk
+}
+
+func RecordsAdapterHelper(policy adapters.ParsePolicy) adapters.RecordsAdapter {
+	recipesAdapter, err := adapters.NewRecordsAdapter(adapters.DefaultSchema(), policy)
+
+	if err != nil {
+		panic(err)
+	}
+
+	return recipesAdapter
 }
@@ -0,0 +1,69 @@
+package tests
+
+import (
+	"path/filepath"
+	"recipe-stats/history"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHistoryAdd(t *testing.T) {
+	store := history.NewStore(filepath.Join(t.TempDir(), "history.json"))
+
+	entries, err := store.History()
+	assert.NoError(t, err)
+	assert.Empty(t, entries)
+
+	first := history.Query{FilePath: "/data/a.json", RecipeCount: true}
+	second := history.Query{FilePath: "/data/b.json", Names: []string{"Pasta", "Cheese"}}
+	assert.NoError(t, store.Add(first))
+	assert.NoError(t, store.Add(second))
+	assert.NoError(t, store.Add(first))
+
+	entries, err = store.History()
+	assert.NoError(t, err)
+	if assert.Len(t, entries, 2) {
+		assert.Equal(t, first, entries[0].Query)
+		assert.Equal(t, second, entries[1].Query)
+	}
+}
+
+func TestHistoryKeepsMaxEntries(t *testing.T) {
+	store := history.NewStore(filepath.Join(t.TempDir(), "history.json"))
+
+	for i := 0; i < history.MaxEntries+5; i++ {
+		assert.NoError(t, store.Add(history.Query{FilePath: "a.json", Postcode: string(rune('A' + i))}))
+	}
+
+	entries, err := store.History()
+	assert.NoError(t, err)
+	assert.Len(t, entries, history.MaxEntries)
+	assert.Equal(t, string(rune('A'+history.MaxEntries+4)), entries[0].Postcode)
+}
+
+func TestHistorySave(t *testing.T) {
+	store := history.NewStore(filepath.Join(t.TempDir(), "history.json"))
+
+	query := history.Query{FilePath: "/data/a.json", Postcode: "10120", From: "9AM", To: "2PM"}
+	assert.NoError(t, store.Save("weekly", query))
+	assert.NoError(t, store.Save("daily", history.Query{FilePath: "/data/b.json"}))
+	assert.Error(t, store.Save(" ", query))
+
+	saved, err := store.Saved("weekly")
+	assert.NoError(t, err)
+	assert.Equal(t, query, saved)
+
+	_, err = store.Saved("monthly")
+	assert.Equal(t, history.ErrQueryNotFound, err)
+
+	names, err := store.SavedNames()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"daily", "weekly"}, names)
+}
+
+func TestQueryString(t *testing.T) {
+	query := history.Query{FilePath: "/data/a.json", RecipeCount: true, Names: []string{"Pasta", "Cheese"}, Postcode: "10120", From: "9AM", To: "2PM"}
+	assert.Equal(t, "a.json: count, search Pasta,Cheese, postcode 10120 9AM-2PM", query.String())
+	assert.Equal(t, "a.json: busiest postcode", history.Query{FilePath: "a.json"}.String())
+}
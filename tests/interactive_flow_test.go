@@ -0,0 +1,478 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"recipe-stats/adapters"
+	"recipe-stats/history"
+	"recipe-stats/interactive"
+	"recipe-stats/recipestats"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const flowFixture = "./testdata/test_calculation_fixtures_full.json"
+
+// scriptedAnswer is the answer given to the question with message. A value
+// of type func() interface{} is called when the question is asked, and its
+// result is the answer.
+type scriptedAnswer struct {
+	message string
+	value   interface{}
+}
+
+// scriptedAsker answers the questions of the flow from a script, failing the
+// test when a question is not the one expected or an answer is not accepted by
+// its validator
+type scriptedAsker struct {
+	t       *testing.T
+	answers []scriptedAnswer
+}
+
+func (a *scriptedAsker) next(message string) interface{} {
+	if len(a.answers) == 0 {
+		a.t.Fatalf("unexpected question %q", message)
+	}
+
+	answer := a.answers[0]
+	a.answers = a.answers[1:]
+	if answer.message != message {
+		a.t.Fatalf("expected question %q, got %q", answer.message, message)
+	}
+	if value, ok := answer.value.(func() interface{}); ok {
+		return value()
+	}
+
+	return answer.value
+}
+
+func (a *scriptedAsker) Select(message string, options []string) (int, error) {
+	value := a.next(message)
+	for i, option := range options {
+		if option == value {
+			return i, nil
+		}
+	}
+
+	a.t.Fatalf("%q is not one of %v", value, options)
+	return 0, nil
+}
+
+func (a *scriptedAsker) MultiSelect(message string, options []string) ([]int, error) {
+	chosen := []int{}
+	for _, value := range a.next(message).([]string) {
+		for i, option := range options {
+			if option == value {
+				chosen = append(chosen, i)
+			}
+		}
+	}
+
+	return chosen, nil
+}
+
+func (a *scriptedAsker) Input(message string, validator func(answer interface{}) error) (string, error) {
+	value := a.next(message)
+	if err, ok := value.(error); ok {
+		return "", err
+	}
+	if validator != nil {
+		if err := validator(value); err != nil {
+			a.t.Fatalf("answer %q to %q was not accepted: %s", value, message, err.Error())
+		}
+	}
+
+	return value.(string), nil
+}
+
+func (a *scriptedAsker) Confirm(message string, defaultAnswer bool) (bool, error) {
+	return a.next(message).(bool), nil
+}
+
+// recordingRunner keeps the queries run and the datasets they ran over
+type recordingRunner struct {
+	mu       sync.Mutex
+	queries  []history.Query
+	datasets []*recipestats.Dataset
+}
+
+func (r *recordingRunner) run(dataset *recipestats.Dataset, query history.Query) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.queries = append(r.queries, query)
+	r.datasets = append(r.datasets, dataset)
+}
+
+// countingLoader loads the fixtures, counting how many times it did so. It
+// fails for the file paths in failures, and never ends loading the ones in
+// slow until canceled, telling started when it begins. When release is set,
+// loading waits for it to be closed.
+type countingLoader struct {
+	mu       sync.Mutex
+	loads    int
+	failures map[string]bool
+	slow     map[string]bool
+	started  chan struct{}
+	release  chan struct{}
+}
+
+func (l *countingLoader) load(ctx context.Context, filePath string, progress func(adapters.Progress)) (*recipestats.Dataset, error) {
+	l.mu.Lock()
+	l.loads++
+	l.mu.Unlock()
+
+	if l.failures[filePath] {
+		return nil, fmt.Errorf("broken file %s", filePath)
+	}
+	if l.slow[filePath] {
+		progress(adapters.Progress{Read: 10, Total: 100, Records: 1, Elapsed: time.Second})
+		l.started <- struct{}{}
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	if l.release != nil {
+		<-l.release
+	}
+
+	return recipestats.Open(ctx, filePath)
+}
+
+func newFlowHelper(t *testing.T, answers []scriptedAnswer, loader *countingLoader, runner *recordingRunner, store *history.Store) (*interactive.Flow, *scriptedAsker, *bytes.Buffer) {
+	asker := &scriptedAsker{t: t, answers: answers}
+	errors := &bytes.Buffer{}
+
+	flow := interactive.NewFlow(interactive.Config{
+		Asker:           asker,
+		Load:            loader.load,
+		Run:             runner.run,
+		BuiltInFilePath: flowFixture,
+		History:         store,
+		Errors:          errors,
+	})
+
+	return flow, asker, errors
+}
+
+func TestFlowStates(t *testing.T) {
+	loader := &countingLoader{}
+	runner := &recordingRunner{}
+	flow, asker, _ := newFlowHelper(t, []scriptedAnswer{
+		{interactive.MessageFileOption, interactive.OptionBuiltInFile},
+		{interactive.MessageOptions, []string{interactive.OptionCount}},
+		{interactive.MessageRunAgain, false},
+	}, loader, runner, nil)
+
+	states := []interactive.State{flow.State()}
+	for flow.State() != interactive.StateDone {
+		assert.NoError(t, flow.Step())
+		states = append(states, flow.State())
+	}
+
+	assert.Equal(t, []interactive.State{
+		interactive.StateChooseFile,
+		interactive.StateLoading,
+		interactive.StateChooseOptions,
+		interactive.StateShowResults,
+		interactive.StateRunAgain,
+		interactive.StateDone,
+	}, states)
+	assert.Empty(t, asker.answers)
+	assert.Equal(t, []history.Query{{FilePath: flowFixture, RecipeCount: true}}, runner.queries)
+}
+
+func TestFlowQuery(t *testing.T) {
+	loader := &countingLoader{}
+	runner := &recordingRunner{}
+	flow, asker, _ := newFlowHelper(t, []scriptedAnswer{
+		{interactive.MessageFileOption, interactive.OptionCustomFile},
+		{interactive.MessageCustomFile, " " + flowFixture + " "},
+		{interactive.MessageOptions, []string{interactive.OptionSearch, interactive.OptionPostcode}},
+		{interactive.MessageRecipeNames, " Pasta, ,Cheese ,"},
+		{interactive.MessagePostcode, "10120"},
+		{interactive.MessageFrom, "9 am"},
+		{interactive.MessageTo, "2pm"},
+		{interactive.MessageRunAgain, false},
+	}, loader, runner, nil)
+
+	assert.NoError(t, flow.Run())
+	assert.Empty(t, asker.answers)
+	assert.Equal(t, []history.Query{{
+		FilePath: flowFixture,
+		Names:    []string{"Pasta", "Cheese"},
+		Postcode: "10120",
+		From:     "9AM",
+		To:       "2PM",
+	}}, runner.queries)
+}
+
+func TestFlowPostcodeWhileLoading(t *testing.T) {
+	slow := "./testdata/test_calculation_fixtures_double.json"
+	loader := &countingLoader{slow: map[string]bool{slow: true}, started: make(chan struct{}, 1)}
+	runner := &recordingRunner{}
+	var flow *interactive.Flow
+	flow, asker, errors := newFlowHelper(t, []scriptedAnswer{
+		{interactive.MessageFileOption, interactive.OptionCustomFile},
+		{interactive.MessageCustomFile, slow},
+		{interactive.MessageOptions, []string{interactive.OptionPostcode}},
+		// the postcode is accepted without waiting for the load, which only
+		// ends once canceled
+		{interactive.MessagePostcode, "10120"},
+		{interactive.MessageFrom, func() interface{} {
+			<-loader.started
+			flow.CancelLoading()
+			return "9AM"
+		}},
+		{interactive.MessageTo, "2PM"},
+		{interactive.MessageLoadCanceled, false},
+	}, loader, runner, nil)
+
+	assert.NoError(t, flow.Run())
+	assert.Empty(t, asker.answers)
+	assert.Contains(t, errors.String(), "Loading was canceled")
+	assert.Empty(t, runner.queries)
+}
+
+func TestFlowUnknownPostcodeCheckedAfterLoading(t *testing.T) {
+	loader := &countingLoader{release: make(chan struct{})}
+	runner := &recordingRunner{}
+	flow, asker, errors := newFlowHelper(t, []scriptedAnswer{
+		{interactive.MessageFileOption, interactive.OptionBuiltInFile},
+		{interactive.MessageOptions, []string{interactive.OptionPostcode}},
+		{interactive.MessagePostcode, "10122"},
+		{interactive.MessageFrom, func() interface{} {
+			close(loader.release)
+			return "9AM"
+		}},
+		{interactive.MessageTo, "2PM"},
+		{interactive.MessageOptions, []string{interactive.OptionCount}},
+		{interactive.MessageRunAgain, false},
+	}, loader, runner, nil)
+
+	assert.NoError(t, flow.Run())
+	assert.Empty(t, asker.answers)
+	assert.Contains(t, errors.String(), "The postcode can't be searched. The error was: there are no deliveries to 10122, try")
+	assert.Equal(t, []history.Query{{FilePath: flowFixture, RecipeCount: true}}, runner.queries)
+}
+
+func TestFlowTryAgainAfterLoadError(t *testing.T) {
+	broken := "./testdata/test_mapped_fixtures_slot.json"
+	loader := &countingLoader{failures: map[string]bool{broken: true}}
+	runner := &recordingRunner{}
+	flow, asker, errors := newFlowHelper(t, []scriptedAnswer{
+		{interactive.MessageFileOption, interactive.OptionCustomFile},
+		{interactive.MessageCustomFile, broken},
+		{interactive.MessageOptions, []string{interactive.OptionCount}},
+		{interactive.MessageTryAgain, true},
+		{interactive.MessageFileOption, interactive.OptionBuiltInFile},
+		{interactive.MessageOptions, []string{interactive.OptionCount}},
+		{interactive.MessageRunAgain, false},
+	}, loader, runner, nil)
+
+	assert.NoError(t, flow.Run())
+	assert.Empty(t, asker.answers)
+	assert.Contains(t, errors.String(), "broken file")
+	assert.Equal(t, 2, loader.loads)
+	// the broken dataset is never run
+	assert.Len(t, runner.queries, 1)
+	assert.NotNil(t, runner.datasets[0])
+}
+
+func TestFlowGiveUpAfterLoadError(t *testing.T) {
+	loader := &countingLoader{failures: map[string]bool{flowFixture: true}}
+	runner := &recordingRunner{}
+	flow, asker, _ := newFlowHelper(t, []scriptedAnswer{
+		{interactive.MessageFileOption, interactive.OptionBuiltInFile},
+		{interactive.MessageOptions, []string{interactive.OptionCount}},
+		{interactive.MessageTryAgain, false},
+	}, loader, runner, nil)
+
+	assert.NoError(t, flow.Run())
+	assert.Empty(t, asker.answers)
+	assert.Equal(t, interactive.StateDone, flow.State())
+	assert.Empty(t, runner.queries)
+}
+
+func TestFlowReusesDataset(t *testing.T) {
+	loader := &countingLoader{}
+	runner := &recordingRunner{}
+	flow, asker, _ := newFlowHelper(t, []scriptedAnswer{
+		{interactive.MessageFileOption, interactive.OptionBuiltInFile},
+		{interactive.MessageOptions, []string{interactive.OptionCount}},
+		{interactive.MessageRunAgain, true},
+		{interactive.MessageReuseDataset, true},
+		{interactive.MessageOptions, []string{interactive.OptionSearch}},
+		{interactive.MessageRecipeNames, "Pasta"},
+		{interactive.MessageRunAgain, true},
+		{interactive.MessageReuseDataset, false},
+		{interactive.MessageFileOption, interactive.OptionBuiltInFile},
+		{interactive.MessageOptions, []string{interactive.OptionCount}},
+		{interactive.MessageRunAgain, false},
+	}, loader, runner, nil)
+
+	assert.NoError(t, flow.Run())
+	assert.Empty(t, asker.answers)
+	assert.Equal(t, 2, loader.loads)
+	if assert.Len(t, runner.datasets, 3) {
+		assert.Same(t, runner.datasets[0], runner.datasets[1])
+		assert.NotSame(t, runner.datasets[1], runner.datasets[2])
+	}
+}
+
+func TestFlowRunsManyTimes(t *testing.T) {
+	iterations := 200
+
+	answers := []scriptedAnswer{{interactive.MessageFileOption, interactive.OptionBuiltInFile}}
+	for i := 0; i < iterations; i++ {
+		answers = append(answers,
+			scriptedAnswer{interactive.MessageOptions, []string{interactive.OptionCount}},
+			scriptedAnswer{interactive.MessageRunAgain, i < iterations-1},
+		)
+		if i < iterations-1 {
+			answers = append(answers, scriptedAnswer{interactive.MessageReuseDataset, true})
+		}
+	}
+
+	loader := &countingLoader{}
+	runner := &recordingRunner{}
+	flow, asker, _ := newFlowHelper(t, answers, loader, runner, nil)
+
+	assert.NoError(t, flow.Run())
+	assert.Empty(t, asker.answers)
+	assert.Equal(t, 1, loader.loads)
+	assert.Len(t, runner.queries, iterations)
+}
+
+func TestFlowStopsOnAskError(t *testing.T) {
+	interrupted := fmt.Errorf("interrupt")
+	loader := &countingLoader{}
+	runner := &recordingRunner{}
+	flow, _, _ := newFlowHelper(t, []scriptedAnswer{
+		{interactive.MessageFileOption, interactive.OptionCustomFile},
+		{interactive.MessageCustomFile, interrupted},
+	}, loader, runner, nil)
+
+	assert.Equal(t, interrupted, flow.Run())
+	assert.Equal(t, interactive.StateDone, flow.State())
+	assert.Zero(t, loader.loads)
+}
+
+func TestFlowHistory(t *testing.T) {
+	store := history.NewStore(filepath.Join(t.TempDir(), "history.json"))
+	absoluteFixture, _ := filepath.Abs(flowFixture)
+
+	loader := &countingLoader{}
+	runner := &recordingRunner{}
+	flow, asker, _ := newFlowHelper(t, []scriptedAnswer{
+		{interactive.MessageFileOption, interactive.OptionBuiltInFile},
+		{interactive.MessageOptions, []string{interactive.OptionCount}},
+		{interactive.MessageSaveQuery, "counting"},
+		{interactive.MessageRunAgain, true},
+		{interactive.MessageReuseDataset, false},
+		{interactive.MessageStart, interactive.OptionSavedQuery},
+		{interactive.MessageSavedQuery, "counting"},
+		{interactive.MessageSaveQuery, ""},
+		{interactive.MessageRunAgain, false},
+	}, loader, runner, &store)
+
+	assert.NoError(t, flow.Run())
+	assert.Empty(t, asker.answers)
+
+	saved, err := store.Saved("counting")
+	assert.NoError(t, err)
+	assert.Equal(t, history.Query{FilePath: absoluteFixture, RecipeCount: true}, saved)
+	if assert.Len(t, runner.queries, 2) {
+		assert.Equal(t, saved, runner.queries[1])
+	}
+}
+
+func TestFlowSavedQueryReadError(t *testing.T) {
+	historyPath := filepath.Join(t.TempDir(), "history.json")
+	store := history.NewStore(historyPath)
+	assert.NoError(t, store.Save("counting", history.Query{FilePath: flowFixture, RecipeCount: true}))
+
+	// the history file breaks after the saved query is chosen
+	breakHistory := func() interface{} {
+		assert.NoError(t, ioutil.WriteFile(historyPath, []byte("{"), 0600))
+		return "counting"
+	}
+
+	loader := &countingLoader{}
+	runner := &recordingRunner{}
+	flow, asker, errors := newFlowHelper(t, []scriptedAnswer{
+		{interactive.MessageStart, interactive.OptionSavedQuery},
+		{interactive.MessageSavedQuery, breakHistory},
+		{interactive.MessageFileOption, interactive.OptionBuiltInFile},
+		{interactive.MessageOptions, []string{interactive.OptionCount}},
+		{interactive.MessageRunAgain, false},
+	}, loader, runner, &store)
+
+	assert.NoError(t, flow.Run())
+	assert.Empty(t, asker.answers)
+	assert.Contains(t, errors.String(), "It was impossible to read the saved query.")
+	assert.Len(t, runner.queries, 1)
+}
+
+func TestFlowCancelLoading(t *testing.T) {
+	slow := "./testdata/test_calculation_fixtures_double.json"
+	loader := &countingLoader{slow: map[string]bool{slow: true}, started: make(chan struct{}, 1)}
+	runner := &recordingRunner{}
+	flow, asker, errors := newFlowHelper(t, []scriptedAnswer{
+		{interactive.MessageFileOption, interactive.OptionCustomFile},
+		{interactive.MessageCustomFile, slow},
+		{interactive.MessageOptions, []string{interactive.OptionCount}},
+		{interactive.MessageLoadCanceled, true},
+		{interactive.MessageFileOption, interactive.OptionBuiltInFile},
+		{interactive.MessageOptions, []string{interactive.OptionCount}},
+		{interactive.MessageRunAgain, false},
+	}, loader, runner, nil)
+
+	go func() {
+		<-loader.started
+		flow.CancelLoading()
+	}()
+
+	assert.NoError(t, flow.Run())
+	assert.Empty(t, asker.answers)
+	assert.Contains(t, errors.String(), "Loading was canceled")
+	assert.Equal(t, 2, loader.loads)
+	if assert.Len(t, runner.queries, 1) {
+		assert.Equal(t, flowFixture, runner.queries[0].FilePath)
+	}
+}
+
+func TestFlowRunContextCanceled(t *testing.T) {
+	slow := "./testdata/test_calculation_fixtures_double.json"
+	loader := &countingLoader{slow: map[string]bool{slow: true}, started: make(chan struct{}, 1)}
+	runner := &recordingRunner{}
+	progress := &bytes.Buffer{}
+	asker := &scriptedAsker{t: t, answers: []scriptedAnswer{
+		{interactive.MessageFileOption, interactive.OptionCustomFile},
+		{interactive.MessageCustomFile, slow},
+		{interactive.MessageOptions, []string{interactive.OptionCount}},
+	}}
+	flow := interactive.NewFlow(interactive.Config{
+		Asker:    asker,
+		Load:     loader.load,
+		Run:      runner.run,
+		Progress: progress,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-loader.started
+		time.Sleep(150 * time.Millisecond)
+		cancel()
+	}()
+
+	assert.Equal(t, context.Canceled, flow.RunContext(ctx))
+	assert.Equal(t, interactive.StateDone, flow.State())
+	assert.Empty(t, runner.queries)
+	assert.Contains(t, progress.String(), "10%")
+}
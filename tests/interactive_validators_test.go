@@ -0,0 +1,74 @@
+package tests
+
+import (
+	"recipe-stats/interactive"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateFilePath(t *testing.T) {
+	assert.NoError(t, interactive.ValidateFilePath("./testdata/test_calculation_fixtures_single.json"))
+	assert.NoError(t, interactive.ValidateFilePath(" ./testdata/test_calculation_fixtures_single.json "))
+	assert.Error(t, interactive.ValidateFilePath(""))
+	assert.Error(t, interactive.ValidateFilePath("./testdata/missing.json"))
+	assert.Error(t, interactive.ValidateFilePath("./testdata"))
+}
+
+func TestValidateTime(t *testing.T) {
+	for _, answer := range []string{"9AM", "9 am", "12PM", "noon", "11pm"} {
+		assert.NoError(t, interactive.ValidateTime(answer), answer)
+	}
+	for _, answer := range []string{"", "9", "AM", "25PM", "0AM", "9:30AM"} {
+		assert.Error(t, interactive.ValidateTime(answer), answer)
+	}
+}
+
+func TestValidateWindowEnd(t *testing.T) {
+	from := "9AM"
+	validate := interactive.ValidateWindowEnd(func() string { return from })
+
+	assert.NoError(t, validate("9AM"))
+	assert.NoError(t, validate("2PM"))
+	assert.Error(t, validate("8AM"))
+	assert.Error(t, validate("2"))
+
+	from = "1 pm"
+	assert.Error(t, validate("12PM"))
+	assert.NoError(t, validate("1PM"))
+}
+
+func TestNormalizeTime(t *testing.T) {
+	expectations := map[string]string{
+		"9 am":     "9AM",
+		"12am":     "12AM",
+		"noon":     "12PM",
+		"midnight": "12AM",
+		" 11 PM ":  "11PM",
+		"2PM":      "2PM",
+	}
+
+	for answer, expected := range expectations {
+		assert.Equal(t, expected, interactive.NormalizeTime(answer), answer)
+	}
+}
+
+func TestValidatePostcode(t *testing.T) {
+	known := []string{"10120", "10121", "10129", "10145", "10201", "20120"}
+	validate := interactive.ValidatePostcode(func() []string { return known })
+
+	assert.NoError(t, validate("10129"))
+	assert.Error(t, validate(""))
+	assert.EqualError(t, validate("10122"), "there are no deliveries to 10122, try 10121, 10120, 10129, 10145, 10201")
+
+	validate = interactive.ValidatePostcode(func() []string { return nil })
+	assert.NoError(t, validate("99999"))
+}
+
+func TestSuggestPostcodes(t *testing.T) {
+	known := []string{"10120", "10121", "10129", "10145", "10201", "20120"}
+
+	assert.Equal(t, []string{"10201", "10145"}, interactive.SuggestPostcodes(known, "10200", 2))
+	assert.Equal(t, []string{"20120"}, interactive.SuggestPostcodes(known, "2", 1))
+	assert.Empty(t, interactive.SuggestPostcodes(nil, "10120", 5))
+}
@@ -0,0 +1,94 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"recipe-stats/logging"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseLevel(t *testing.T) {
+	for name, expected := range map[string]logging.Level{
+		"debug":   logging.LevelDebug,
+		"INFO":    logging.LevelInfo,
+		"warn":    logging.LevelWarn,
+		"Warning": logging.LevelWarn,
+		"error":   logging.LevelError,
+	} {
+		level, err := logging.ParseLevel(name)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, level)
+	}
+
+	_, err := logging.ParseLevel("loud")
+	assert.EqualError(t, err, `unknown log level "loud", expected debug, info, warn or error`)
+	assert.Equal(t, "Level(1)", logging.Level(1).String())
+}
+
+func TestTextHandler(t *testing.T) {
+	output := &bytes.Buffer{}
+	logger := logging.New(logging.NewTextHandler(output, logging.LevelInfo))
+
+	logger.Debug("Left out")
+	logger.Info("Reading recipes file finished", "duration", 1500*time.Millisecond, "records", 10, "file", "my data.json")
+	logger.With("phase", "read").Error("Failed", "error", errors.New("bad file"), "empty", "")
+
+	lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+	assert.Len(t, lines, 2)
+	assert.Regexp(t, `^time=\S+ level=INFO msg="Reading recipes file finished" duration=1.5s records=10 file="my data.json"$`, lines[0])
+	assert.Regexp(t, `^time=\S+ level=ERROR msg=Failed phase=read error="bad file" empty=""$`, lines[1])
+}
+
+func TestJSONHandler(t *testing.T) {
+	output := &bytes.Buffer{}
+	logger := logging.New(logging.NewJSONHandler(output, logging.LevelDebug))
+
+	logger.Debug("Reading recipes file finished", "duration", 1500*time.Millisecond, "records", 10, "error", errors.New("bad file"), "orphan")
+
+	record := map[string]interface{}{}
+	assert.NoError(t, json.Unmarshal(output.Bytes(), &record))
+	assert.Equal(t, "DEBUG", record["level"])
+	assert.Equal(t, "Reading recipes file finished", record["msg"])
+	assert.Equal(t, float64(1500*time.Millisecond), record["duration"])
+	assert.Equal(t, float64(10), record["records"])
+	assert.Equal(t, "bad file", record["error"])
+	assert.Equal(t, "orphan", record["!BADKEY"])
+	_, err := time.Parse(time.RFC3339, record["time"].(string))
+	assert.NoError(t, err)
+}
+
+func TestJSONHandlerConcurrentLines(t *testing.T) {
+	output := &bytes.Buffer{}
+	logger := logging.New(logging.NewJSONHandler(output, logging.LevelInfo))
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			logger.Info("Logged", "i", i)
+		}(i)
+	}
+	wg.Wait()
+
+	lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+	assert.Len(t, lines, 50)
+	for _, line := range lines {
+		assert.True(t, json.Valid([]byte(line)), line)
+	}
+}
+
+func TestNilLogger(t *testing.T) {
+	var logger *logging.Logger
+
+	assert.False(t, logger.Enabled(logging.LevelError))
+	assert.NotPanics(t, func() {
+		logger.With("phase", "read").Error("Discarded", "error", errors.New("bad file"))
+	})
+}
@@ -0,0 +1,150 @@
+package tests
+
+import (
+	"recipe-stats/adapters"
+	"recipe-stats/loaders"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func slotSchemaHelper() adapters.Schema {
+	return adapters.Schema{
+		Recipe:   "dish",
+		Postcode: "zip",
+		Slot:     &adapters.SlotSchema{Weekday: "slot.day", From: "slot.start", To: "slot.end"},
+	}
+}
+
+func TestSchemaWithDefaults(t *testing.T) {
+	assert.True(t, adapters.Schema{}.WithDefaults().IsDefault())
+
+	schema := adapters.Schema{Recipe: "dish"}.WithDefaults()
+	assert.False(t, schema.IsDefault())
+	assert.Equal(t, "postcode", schema.Postcode)
+	assert.Equal(t, "delivery", schema.Delivery)
+
+	schema = slotSchemaHelper().WithDefaults()
+	assert.Equal(t, "", schema.Delivery)
+}
+
+func TestSchemaValidate(t *testing.T) {
+	assert.NoError(t, adapters.DefaultSchema().Validate())
+	assert.NoError(t, slotSchemaHelper().Validate())
+
+	schema := slotSchemaHelper()
+	schema.Delivery = "delivery"
+	assert.Error(t, schema.Validate())
+
+	schema = slotSchemaHelper()
+	schema.Slot.To = ""
+	assert.Error(t, schema.Validate())
+
+	schema = adapters.DefaultSchema()
+	schema.Recipe = "meal..name"
+	assert.Error(t, schema.Validate())
+
+	_, err := adapters.NewRecordsAdapter(schema, adapters.StrictParsePolicy())
+	assert.Error(t, err)
+}
+
+func TestNewRecordsAdapter(t *testing.T) {
+	recipesAdapter, err := adapters.NewRecordsAdapter(adapters.Schema{}, adapters.LenientParsePolicy())
+	assert.NoError(t, err)
+	assert.IsType(t, &adapters.GeneralRecipeAdapter{}, recipesAdapter)
+	assert.Equal(t, "lenient", recipesAdapter.ParsePolicy().String())
+
+	recipesAdapter, err = adapters.NewRecordsAdapter(slotSchemaHelper(), adapters.StrictParsePolicy())
+	assert.NoError(t, err)
+	assert.IsType(t, &adapters.MappedRecipeAdapter{}, recipesAdapter)
+}
+
+func TestMappedUnmarshalRecordsWithSlot(t *testing.T) {
+	recipesAdapter, err := adapters.NewMappedRecipeAdapter(slotSchemaHelper())
+	assert.NoError(t, err)
+
+	classes := map[int]string{}
+	recipes, err := recipesAdapter.UnmarshalRecords("./testdata/test_mapped_fixtures_slot.json", func(recordError *adapters.RecordError) {
+		classes[recordError.Index] = recordError.Class
+	})
+	assert.NoError(t, err)
+
+	assert.Equal(t, map[int]string{
+		2: adapters.ErrorClassUnknownWeekday,
+		3: adapters.ErrorClassInvalidTime,
+		4: adapters.ErrorClassMalformedRecord,
+	}, classes)
+
+	assert.Len(t, *recipes, 3)
+	assert.Equal(t, adapters.GeneralRecipe{
+		Recipe:   "Creamy Dill Chicken",
+		Postcode: "10120",
+		Delivery: adapters.GeneralDelivery{Weekday: "Wednesday", From: 8, To: 14},
+	}, (*recipes)[0])
+	assert.Equal(t, adapters.GeneralDelivery{Weekday: "Thursday", From: 9, To: 13}, (*recipes)[1].Delivery)
+	assert.Equal(t, adapters.GeneralDelivery{Weekday: "Friday", From: 15, To: 13}, (*recipes)[2].Delivery)
+}
+
+func TestMappedValidateWithSlot(t *testing.T) {
+	recipesAdapter, err := adapters.NewMappedRecipeAdapter(slotSchemaHelper())
+	assert.NoError(t, err)
+
+	classes := map[string]int{}
+	total, err := recipesAdapter.Validate("./testdata/test_mapped_fixtures_slot.json", func(recordError *adapters.RecordError) {
+		classes[recordError.Class]++
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 6, total)
+	assert.Equal(t, map[string]int{
+		adapters.ErrorClassUnknownWeekday:  1,
+		adapters.ErrorClassInvalidTime:     1,
+		adapters.ErrorClassEmptyRecipe:     1,
+		adapters.ErrorClassInvalidPostcode: 1,
+		adapters.ErrorClassInvertedWindow:  1,
+	}, classes)
+}
+
+func TestMappedNumericPostcodes(t *testing.T) {
+	recipesAdapter, err := adapters.NewMappedRecipeAdapter(slotSchemaHelper())
+	assert.NoError(t, err)
+	filePath := "./testdata/test_mapped_fixtures_numeric_postcode.json"
+
+	classes := map[int]string{}
+	recipes, err := recipesAdapter.UnmarshalRecords(filePath, func(recordError *adapters.RecordError) {
+		classes[recordError.Index] = recordError.Class
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, map[int]string{2: adapters.ErrorClassMalformedRecord}, classes)
+	assert.Len(t, *recipes, 2)
+	assert.Equal(t, "10120", (*recipes)[0].Postcode)
+	assert.Equal(t, "01224", (*recipes)[1].Postcode)
+
+	problems := map[string]int{}
+	total, err := recipesAdapter.Validate(filePath, func(recordError *adapters.RecordError) {
+		problems[recordError.Class]++
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, total)
+	assert.Equal(t, map[string]int{adapters.ErrorClassInvalidPostcode: 1}, problems)
+}
+
+func TestLoadWithNestedSchema(t *testing.T) {
+	schema := adapters.Schema{Recipe: "meal.name", Postcode: "address.postcode", Delivery: "window"}
+	filePath := "./testdata/test_mapped_fixtures_nested.json"
+
+	recipesAdapter, err := adapters.NewRecordsAdapter(schema, adapters.StrictParsePolicy())
+	assert.NoError(t, err)
+	rejects, _ := loaders.NewRejects("")
+	rk, _, dk, err := loaders.LoadFromGeneralRecipeWithRejects(filePath, recipesAdapter, rejects, false)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, rejects.Count())
+	assert.Equal(t, 1, rk.Count())
+	assert.Equal(t, 1, dk.CountByInterval("10120", "8AM", "2PM"))
+
+	recipesAdapter, err = adapters.NewRecordsAdapter(schema, adapters.LenientParsePolicy())
+	assert.NoError(t, err)
+	rk, _, dk, err = loaders.LoadFromGeneralRecipeWithRejects(filePath, recipesAdapter, nil, false)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, rk.Count())
+	assert.Equal(t, 2, dk.CountByInterval("10120", "8AM", "2PM"))
+}
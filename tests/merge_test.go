@@ -0,0 +1,189 @@
+package tests
+
+import (
+	"fmt"
+	"math/rand"
+	"recipe-stats/keepers"
+	"recipe-stats/models"
+	"sort"
+	"strings"
+	"testing"
+	"testing/quick"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// mergeRecord is a single record of the inputs generated by the merge
+// properties
+type mergeRecord struct {
+	recipe   string
+	delivery models.Delivery
+}
+
+// mergeRecordsHelper generates up to 300 records out of a few words and
+// postcodes, so recipes share name slices and postcodes often tie for the
+// busiest one
+func mergeRecordsHelper(random *rand.Rand) []mergeRecord {
+	words := []string{"Creamy", "Dill", "Chicken", "Speedy", "Steak", "Fajitas", "Pork", "Chops"}
+
+	records := make([]mergeRecord, random.Intn(300))
+	for i := range records {
+		name := make([]string, 1+random.Intn(3))
+		for j := range name {
+			name[j] = words[random.Intn(len(words))]
+		}
+
+		from := random.Intn(24)
+		records[i] = mergeRecord{
+			recipe:   strings.Join(name, " "),
+			delivery: models.Delivery{Postcode: fmt.Sprintf("101%02d", random.Intn(6)), From: from, To: from + random.Intn(24-from)},
+		}
+	}
+
+	return records
+}
+
+// mergeKeepersHelper loads records into new keepers, the way the loaders do
+func mergeKeepersHelper(records []mergeRecord) (*keepers.RecipeKeeper, *keepers.RecipeNameSlicesKeeper, *keepers.DeliveryKeeper) {
+	rk := keepers.NewRecipeKeeper()
+	dk := keepers.NewDeliveryKeeper()
+	for _, record := range records {
+		_ = rk.Add(models.Recipe{Recipe: record.recipe})
+		dk.Add(record.delivery)
+	}
+
+	rnsk := keepers.NewRecipeNameSlicesKeeper()
+	rnsk.Load(rk.GetMap())
+
+	return &rk, &rnsk, &dk
+}
+
+// sameKeepers tells whether both sets of keepers hold the same data, comparing
+// what can be read from them since the order of the name slices indexes is not
+// kept
+func sameKeepers(t *testing.T, rk *keepers.RecipeKeeper, rnsk *keepers.RecipeNameSlicesKeeper, dk *keepers.DeliveryKeeper, expectedRk *keepers.RecipeKeeper, expectedRnsk *keepers.RecipeNameSlicesKeeper, expectedDk *keepers.DeliveryKeeper) bool {
+	same := assert.Equal(t, expectedRk.GetMap(), rk.GetMap())
+
+	same = assert.Equal(t, expectedRnsk.Words(), rnsk.Words()) && same
+	for _, word := range expectedRnsk.Words() {
+		expectedRecipes, _ := expectedRnsk.Get(word)
+		recipes, _ := rnsk.Get(word)
+		sort.Sort(keepers.ByRecipe(expectedRecipes))
+		sort.Sort(keepers.ByRecipe(recipes))
+		same = assert.Equal(t, expectedRecipes, recipes, word) && same
+	}
+
+	same = assert.Equal(t, expectedDk.GetBusiestPostcode(), dk.GetBusiestPostcode()) && same
+	same = assert.Equal(t, expectedDk.TopPostcodes(-1), dk.TopPostcodes(-1)) && same
+	for _, code := range expectedDk.Postcodes() {
+		same = assert.Equal(t, expectedDk.CountByInterval(code, "12AM", "11PM"), dk.CountByInterval(code, "12AM", "11PM")) && same
+		same = assert.Equal(t, expectedDk.CountByInterval(code, "10AM", "3PM"), dk.CountByInterval(code, "10AM", "3PM")) && same
+	}
+
+	expectedBinary, _ := expectedDk.MarshalBinary()
+	binary, _ := dk.MarshalBinary()
+
+	return assert.Equal(t, expectedBinary, binary) && same
+}
+
+func TestMergeKeepers(t *testing.T) {
+	property := func(seed int64) bool {
+		random := rand.New(rand.NewSource(seed))
+		records := mergeRecordsHelper(random)
+		split := 0
+		if len(records) > 0 {
+			split = random.Intn(len(records) + 1)
+		}
+
+		rk, rnsk, dk := mergeKeepersHelper(records[:split])
+		otherRk, otherRnsk, otherDk := mergeKeepersHelper(records[split:])
+		rk.Merge(otherRk)
+		rnsk.Merge(otherRnsk)
+		dk.Merge(otherDk)
+
+		expectedRk, expectedRnsk, expectedDk := mergeKeepersHelper(records)
+
+		return sameKeepers(t, rk, rnsk, dk, expectedRk, expectedRnsk, expectedDk)
+	}
+
+	assert.NoError(t, quick.Check(property, &quick.Config{MaxCount: 500}))
+}
+
+func TestMergeKeepersManyShards(t *testing.T) {
+	property := func(seed int64, shards uint8) bool {
+		random := rand.New(rand.NewSource(seed))
+		records := mergeRecordsHelper(random)
+		count := 1 + int(shards)%8
+
+		rk, rnsk, dk := mergeKeepersHelper(nil)
+		for i := 0; i < count; i++ {
+			otherRk, otherRnsk, otherDk := mergeKeepersHelper(records[i*len(records)/count : (i+1)*len(records)/count])
+			rk.Merge(otherRk)
+			rnsk.Merge(otherRnsk)
+			dk.Merge(otherDk)
+		}
+
+		expectedRk, expectedRnsk, expectedDk := mergeKeepersHelper(records)
+
+		return sameKeepers(t, rk, rnsk, dk, expectedRk, expectedRnsk, expectedDk)
+	}
+
+	assert.NoError(t, quick.Check(property, &quick.Config{MaxCount: 200}))
+}
+
+func TestMergeKeepersIntoItself(t *testing.T) {
+	property := func(seed int64) bool {
+		records := mergeRecordsHelper(rand.New(rand.NewSource(seed)))
+
+		rk, rnsk, dk := mergeKeepersHelper(records)
+		rk.Merge(rk)
+		rnsk.Merge(rnsk)
+		dk.Merge(dk)
+
+		expectedRk, expectedRnsk, expectedDk := mergeKeepersHelper(append(append([]mergeRecord{}, records...), records...))
+
+		return sameKeepers(t, rk, rnsk, dk, expectedRk, expectedRnsk, expectedDk)
+	}
+
+	assert.NoError(t, quick.Check(property, &quick.Config{MaxCount: 100}))
+}
+
+func TestMergeLeavesOtherAsItIs(t *testing.T) {
+	records := mergeRecordsHelper(rand.New(rand.NewSource(1)))
+
+	rk, rnsk, dk := mergeKeepersHelper(records)
+	otherRk, otherRnsk, otherDk := mergeKeepersHelper(records)
+	rk.Merge(otherRk)
+	rnsk.Merge(otherRnsk)
+	dk.Merge(otherDk)
+
+	// adding to the merged keepers must not show up in the ones merged in
+	dk.Add(models.Delivery{Postcode: "10100", From: 1, To: 2})
+	_ = rk.Add(models.Recipe{Recipe: "Creamy Dill Chicken"})
+
+	expectedRk, expectedRnsk, expectedDk := mergeKeepersHelper(records)
+	assert.True(t, sameKeepers(t, otherRk, otherRnsk, otherDk, expectedRk, expectedRnsk, expectedDk))
+}
+
+func TestMergeSnapshotKeepsBusiestPostcodeOnTies(t *testing.T) {
+	dk := keepers.NewDeliveryKeeper()
+	dk.Add(models.Delivery{Postcode: "10120", From: 8, To: 14})
+	dk.Add(models.Delivery{Postcode: "10224", From: 8, To: 14})
+	dk.Add(models.Delivery{Postcode: "10224", From: 9, To: 13})
+	dk.Add(models.Delivery{Postcode: "10120", From: 9, To: 13})
+	assert.Equal(t, keepers.BusiestPostcode{Code: "10224", Count: 2}, dk.GetBusiestPostcode())
+
+	data, err := dk.MarshalBinary()
+	assert.NoError(t, err)
+	decoded := keepers.NewDeliveryKeeper()
+	assert.NoError(t, decoded.UnmarshalBinary(data))
+
+	other := keepers.NewDeliveryKeeper()
+	other.Add(models.Delivery{Postcode: "10300", From: 8, To: 14})
+	decoded.Merge(&other)
+	assert.Equal(t, keepers.BusiestPostcode{Code: "10224", Count: 2}, decoded.GetBusiestPostcode())
+
+	other.Add(models.Delivery{Postcode: "10120", From: 8, To: 14})
+	decoded.Merge(&other)
+	assert.Equal(t, keepers.BusiestPostcode{Code: "10120", Count: 3}, decoded.GetBusiestPostcode())
+}
@@ -0,0 +1,97 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"recipe-stats/adapters"
+	"recipe-stats/loaders"
+	"recipe-stats/logging"
+	"recipe-stats/metrics"
+	"recipe-stats/recipestats"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const metricsFixture = "./testdata/test_parse_policy_fixtures.json"
+
+func phaseNamesHelper(summary metrics.Summary) []string {
+	names := []string{}
+	for _, phase := range summary.Phases {
+		names = append(names, phase.Name)
+	}
+
+	return names
+}
+
+func TestCollector(t *testing.T) {
+	collector := metrics.NewCollector()
+	collector.Phase(metrics.PhaseReadRecipes, time.Second)
+	collector.Phase(metrics.PhaseMapDeliveries, time.Second)
+	collector.Phase(metrics.PhaseReadRecipes, 2*time.Second)
+	collector.Records(10)
+	collector.Records(5)
+	collector.BytesRead(100)
+	collector.Rejects(2)
+
+	summary := collector.Summary()
+	assert.Equal(t, []metrics.PhaseSummary{
+		{Name: metrics.PhaseReadRecipes, Duration: 3 * time.Second, Runs: 2},
+		{Name: metrics.PhaseMapDeliveries, Duration: time.Second, Runs: 1},
+	}, summary.Phases)
+	assert.Equal(t, 15, summary.Records)
+	assert.Equal(t, int64(100), summary.BytesRead)
+	assert.Equal(t, 2, summary.Rejects)
+
+	// the summary is a copy
+	summary.Phases[0].Runs = 10
+	assert.Equal(t, 2, collector.Summary().Phases[0].Runs)
+}
+
+func TestLoaderMetrics(t *testing.T) {
+	collector := metrics.NewCollector()
+	output := &bytes.Buffer{}
+	observer := loaders.Observer{Logger: logging.New(logging.NewTextHandler(output, logging.LevelInfo)), Metrics: collector}
+
+	_, _, _, err := loaders.LoadFromGeneralRecipeContext(context.Background(), metricsFixture, RecordsAdapterHelper(adapters.StrictParsePolicy()), nil, nil, observer)
+	assert.NoError(t, err)
+
+	summary := collector.Summary()
+	assert.ElementsMatch(t, []string{metrics.PhaseReadRecipes, metrics.PhaseLoadRecipes, metrics.PhaseBuildRecipeNames, metrics.PhaseMapDeliveries}, phaseNamesHelper(summary))
+	assert.Equal(t, metrics.PhaseReadRecipes, summary.Phases[0].Name)
+	assert.Equal(t, 5, summary.Records)
+	assert.Equal(t, 4, summary.Rejects)
+	assert.Equal(t, int64(554), summary.BytesRead)
+
+	assert.Equal(t, 4, strings.Count(output.String(), "level=INFO"))
+	assert.NotContains(t, output.String(), "level=DEBUG")
+	assert.Contains(t, output.String(), `msg="Reading recipes file finished" phase=read_recipes`)
+}
+
+func TestLoaderMetricsAppend(t *testing.T) {
+	collector := metrics.NewCollector()
+	dataset, err := recipestats.Open(context.Background(), metricsFixture, recipestats.WithMetrics(collector))
+	assert.NoError(t, err)
+	assert.NoError(t, dataset.Append(context.Background(), metricsFixture))
+
+	summary := collector.Summary()
+	assert.Equal(t, 10, summary.Records)
+	assert.Equal(t, 8, summary.Rejects)
+	assert.Equal(t, int64(2*554), summary.BytesRead)
+	assert.Contains(t, phaseNamesHelper(summary), metrics.PhaseAppendRecipes)
+	assert.Contains(t, phaseNamesHelper(summary), metrics.PhaseAppendDeliveries)
+}
+
+func TestLoaderMetricsSnapshot(t *testing.T) {
+	snapshotPath := t.TempDir() + "/metrics.rsidx"
+	collector := metrics.NewCollector()
+	assert.NoError(t, loaders.BuildSnapshotContext(context.Background(), metricsFixture, snapshotPath, RecordsAdapterHelper(adapters.StrictParsePolicy()), nil, loaders.Observer{Metrics: collector}))
+	assert.Contains(t, phaseNamesHelper(collector.Summary()), metrics.PhaseWriteSnapshot)
+
+	collector = metrics.NewCollector()
+	_, _, _, err := loaders.LoadFromSnapshotContext(context.Background(), snapshotPath, nil, loaders.Observer{Metrics: collector})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{metrics.PhaseReadSnapshot}, phaseNamesHelper(collector.Summary()))
+}
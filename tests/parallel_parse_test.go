@@ -0,0 +1,186 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	stdjson "encoding/json"
+	"io/ioutil"
+	"recipe-stats/adapters"
+	"recipe-stats/recipestats"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// parallelWorkers are the numbers of workers compared against decoding
+// sequentially, including more workers than the input has room for
+var parallelWorkers = []int{2, 3, 8, 64}
+
+// largeInputHelper repeats the records of the fixture at filePath until the
+// input is large enough to be split among many workers, varying the whitespace
+// between them
+func largeInputHelper(filePath string) []byte {
+	file, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		panic(err)
+	}
+
+	records := []stdjson.RawMessage{}
+	if err := stdjson.Unmarshal(file, &records); err != nil {
+		panic(err)
+	}
+
+	separators := []string{",", ",\n  ", " , ", ",\n\t"}
+	input := bytes.NewBufferString("[\n")
+	for i := 0; input.Len() < 1<<20; i++ {
+		if i > 0 {
+			input.WriteString(separators[i%len(separators)])
+		}
+		input.Write(records[i%len(records)])
+	}
+	input.WriteString("\n]\n")
+
+	return input.Bytes()
+}
+
+// decodeHelper decodes input with recipesAdapter using workers, returning the
+// recipes, the rejects in the order they were handed over and the last
+// progress report
+func decodeHelper(recipesAdapter adapters.RecordsAdapter, input []byte, workers int) (*[]adapters.GeneralRecipe, []*adapters.RecordError, adapters.Progress, error) {
+	rejects := []*adapters.RecordError{}
+	last := adapters.Progress{}
+
+	recipesAdapter.SetWorkers(workers)
+	recipes, err := recipesAdapter.DecodeRecords(context.Background(), input, func(recordError *adapters.RecordError) {
+		rejects = append(rejects, recordError)
+	}, func(progress adapters.Progress) {
+		last = progress
+	})
+
+	return recipes, rejects, last, err
+}
+
+func TestParallelDecodeRecordsMatchesSequential(t *testing.T) {
+	mapped, err := adapters.NewRecordsAdapter(slotSchemaHelper(), adapters.StrictParsePolicy())
+	assert.NoError(t, err)
+
+	cases := []struct {
+		recipesAdapter adapters.RecordsAdapter
+		filePath       string
+	}{
+		{RecordsAdapterHelper(adapters.StrictParsePolicy()), "./testdata/test_parse_policy_fixtures.json"},
+		{RecordsAdapterHelper(adapters.LenientParsePolicy()), "./testdata/test_parse_policy_fixtures.json"},
+		{RecordsAdapterHelper(adapters.StrictParsePolicy()), "./testdata/test_calculation_fixtures_full.json"},
+		{mapped, "./testdata/test_mapped_fixtures_slot.json"},
+	}
+
+	for _, c := range cases {
+		input := largeInputHelper(c.filePath)
+
+		recipes, rejects, progress, err := decodeHelper(c.recipesAdapter, input, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, len(*recipes)+len(rejects), progress.Records)
+
+		for _, workers := range parallelWorkers {
+			parallelRecipes, parallelRejects, parallelProgress, err := decodeHelper(c.recipesAdapter, input, workers)
+
+			assert.NoError(t, err)
+			assert.Equal(t, recipes, parallelRecipes, "%s with %d workers", c.filePath, workers)
+			assert.Equal(t, rejects, parallelRejects, "%s with %d workers", c.filePath, workers)
+			assert.Equal(t, progress.Records, parallelProgress.Records)
+			assert.Equal(t, 100.0, parallelProgress.Percent())
+		}
+	}
+}
+
+func TestParallelDecodeRecordsMalformedFile(t *testing.T) {
+	input := largeInputHelper("./testdata/test_calculation_fixtures_full.json")
+	malformed := append(append([]byte{}, input[:len(input)/2]...), '}')
+
+	recipesAdapter := RecordsAdapterHelper(adapters.StrictParsePolicy())
+	_, _, _, sequentialErr := decodeHelper(recipesAdapter, malformed, 1)
+	assert.Error(t, sequentialErr)
+
+	for _, workers := range parallelWorkers {
+		recipes, _, _, err := decodeHelper(recipesAdapter, malformed, workers)
+		assert.Nil(t, recipes)
+		assert.Equal(t, sequentialErr, err)
+	}
+}
+
+func TestParallelDecodeRecordsBoundariesInStrings(t *testing.T) {
+	// most of the input is recipe names looking like the boundary between two
+	// records, so the shards are bound to start within them
+	name := strings.Repeat(`Pasta}, {"recipe": "Cheese`, 100)
+	records := []map[string]string{}
+	for i := 0; i < 1000; i++ {
+		records = append(records, map[string]string{"recipe": name, "postcode": "10120", "delivery": "Wednesday 8AM - 2PM"})
+	}
+	records[500]["delivery"] = "Funday 8AM - 2PM"
+	input, err := stdjson.Marshal(records)
+	assert.NoError(t, err)
+
+	recipesAdapter := RecordsAdapterHelper(adapters.StrictParsePolicy())
+	recipes, rejects, _, err := decodeHelper(recipesAdapter, input, 1)
+	assert.NoError(t, err)
+	assert.Len(t, *recipes, 999)
+	assert.Equal(t, 500, rejects[0].Index)
+
+	for _, workers := range parallelWorkers {
+		parallelRecipes, parallelRejects, parallelProgress, err := decodeHelper(recipesAdapter, input, workers)
+
+		assert.NoError(t, err)
+		assert.Equal(t, recipes, parallelRecipes, "%d workers", workers)
+		assert.Equal(t, rejects, parallelRejects, "%d workers", workers)
+		assert.Equal(t, 1000, parallelProgress.Records)
+	}
+}
+
+func TestParallelDecodeRecordsCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	recipesAdapter := RecordsAdapterHelper(adapters.StrictParsePolicy())
+	recipesAdapter.SetWorkers(8)
+
+	recipes, err := recipesAdapter.DecodeRecords(ctx, largeInputHelper("./testdata/test_calculation_fixtures_full.json"), func(*adapters.RecordError) {}, nil)
+	assert.Equal(t, context.Canceled, err)
+	assert.Nil(t, recipes)
+}
+
+func TestWorkers(t *testing.T) {
+	recipesAdapter := RecordsAdapterHelper(adapters.StrictParsePolicy())
+	assert.Equal(t, 1, recipesAdapter.Workers())
+
+	recipesAdapter.SetWorkers(4)
+	assert.Equal(t, 4, recipesAdapter.Workers())
+
+	recipesAdapter.SetWorkers(0)
+	assert.Equal(t, 1, recipesAdapter.Workers())
+}
+
+func TestDatasetWithWorkers(t *testing.T) {
+	input := largeInputHelper("./testdata/test_calculation_fixtures_full.json")
+	request := recipestats.Request{RecipeCount: true, Names: []string{"Chicken", "Steak"}, Postcode: "10145", From: "8AM", To: "2PM"}
+
+	sequential, err := recipestats.Load(context.Background(), bytes.NewReader(input))
+	assert.NoError(t, err)
+	expected, err := sequential.Stats(context.Background(), request)
+	assert.NoError(t, err)
+
+	for _, workers := range parallelWorkers {
+		parallel, err := recipestats.Load(context.Background(), bytes.NewReader(input), recipestats.WithWorkers(workers))
+		assert.NoError(t, err)
+
+		stats, err := parallel.Stats(context.Background(), request)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, stats)
+		assert.Equal(t, sequential.Sizes(), parallel.Sizes())
+
+		// the keepers were built out of partial ones, merged in the end
+		expectedRk, expectedRnsk, expectedDk := sequential.Keepers()
+		rk, rnsk, dk := parallel.Keepers()
+		assert.True(t, sameKeepers(t, rk, rnsk, dk, expectedRk, expectedRnsk, expectedDk))
+	}
+}
@@ -0,0 +1,124 @@
+package tests
+
+import (
+	"recipe-stats/adapters"
+	"recipe-stats/loaders"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewParsePolicy(t *testing.T) {
+	policy, err := adapters.NewParsePolicy(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "strict", policy.String())
+
+	policy, err = adapters.NewParsePolicy([]string{"strict"})
+	assert.NoError(t, err)
+	assert.Equal(t, "strict", policy.String())
+
+	policy, err = adapters.NewParsePolicy([]string{"lenient"})
+	assert.NoError(t, err)
+	assert.Equal(t, "lenient", policy.String())
+	assert.Len(t, policy.Rules(), 6)
+
+	policy, err = adapters.NewParsePolicy([]string{adapters.RuleNoonMidnight, adapters.RuleDashVariants})
+	assert.NoError(t, err)
+	assert.Equal(t, "rules: dash_variants, noon_midnight", policy.String())
+
+	_, err = adapters.NewParsePolicy([]string{"lenient", "strict"})
+	assert.Error(t, err)
+
+	_, err = adapters.NewParsePolicy([]string{"forgiving"})
+	assert.Error(t, err)
+}
+
+func TestParseDeliveryWithRules(t *testing.T) {
+	type expected struct {
+		rule     string
+		delivery string
+		weekday  string
+		from     int
+		to       int
+	}
+	expectations := []expected{
+		expected{rule: adapters.RuleLowercaseMeridiem, delivery: "Wednesday 8am - 2pm", weekday: "Wednesday", from: 8, to: 14},
+		expected{rule: adapters.RuleMeridiemSpace, delivery: "Friday 10 AM - 12 PM", weekday: "Friday", from: 10, to: 12},
+		expected{rule: adapters.RuleNoonMidnight, delivery: "Thursday midnight - noon", weekday: "Thursday", from: 0, to: 12},
+		expected{rule: adapters.RuleDashVariants, delivery: "Thursday 9AM – 1PM", weekday: "Thursday", from: 9, to: 13},
+		expected{rule: adapters.RuleDashVariants, delivery: "Thursday 9AM—1PM", weekday: "Thursday", from: 9, to: 13},
+		expected{rule: adapters.RuleDashVariants, delivery: "Thursday 9AM-1PM", weekday: "Thursday", from: 9, to: 13},
+		expected{rule: adapters.RuleWeekdayVariants, delivery: "thurs 9AM - 1PM", weekday: "Thursday", from: 9, to: 13},
+		expected{rule: adapters.RuleWeekdayVariants, delivery: "SUNDAY 9AM - 1PM", weekday: "Sunday", from: 9, to: 13},
+		expected{rule: adapters.RuleExtraWhitespace, delivery: " Monday  9AM  -  1PM ", weekday: "Monday", from: 9, to: 13},
+	}
+
+	for _, expectation := range expectations {
+		_, _, _, err := adapters.StrictParsePolicy().ParseDelivery(expectation.delivery)
+		assert.Error(t, err, expectation.delivery)
+
+		policy, err := adapters.NewParsePolicy([]string{expectation.rule})
+		assert.NoError(t, err)
+
+		weekday, from, to, err := policy.ParseDelivery(expectation.delivery)
+		if assert.NoError(t, err, expectation.delivery) {
+			assert.Equal(t, expectation.weekday, weekday, expectation.delivery)
+			assert.Equal(t, expectation.from, from, expectation.delivery)
+			assert.Equal(t, expectation.to, to, expectation.delivery)
+		}
+	}
+}
+
+func TestParseDeliveryLenientErrors(t *testing.T) {
+	expectations := map[string]string{
+		"Wednesday":               adapters.ErrorClassInvalidDelivery,
+		"Wednesday 8AM 2PM":       adapters.ErrorClassInvalidDelivery,
+		"Funday 8AM - 2PM":        adapters.ErrorClassUnknownWeekday,
+		"Wednesday 13PM - 2PM":    adapters.ErrorClassInvalidTime,
+		"Wednesday 8 A M - 2PM":   adapters.ErrorClassInvalidTime,
+		"Wednesday noonish - 2PM": adapters.ErrorClassInvalidTime,
+	}
+
+	for delivery, class := range expectations {
+		_, _, _, err := adapters.LenientParsePolicy().ParseDelivery(delivery)
+		if assert.IsType(t, &adapters.DeliveryError{}, err, delivery) {
+			assert.Equal(t, class, err.(*adapters.DeliveryError).Class, delivery)
+		}
+	}
+}
+
+func TestLoadWithParsePolicy(t *testing.T) {
+	filePath := "./testdata/test_parse_policy_fixtures.json"
+
+	rejects, _ := loaders.NewRejects("")
+	rk, _, dk, err := loaders.LoadFromGeneralRecipeWithRejects(filePath, RecordsAdapterHelper(adapters.StrictParsePolicy()), rejects, false)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, rejects.Count())
+	assert.Equal(t, 1, rk.Count())
+	assert.Equal(t, 1, dk.CountByInterval("10120", "8AM", "2PM"))
+
+	rejects, _ = loaders.NewRejects("")
+	rk, _, dk, err = loaders.LoadFromGeneralRecipeWithRejects(filePath, RecordsAdapterHelper(adapters.LenientParsePolicy()), rejects, false)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, rejects.Count())
+	assert.Equal(t, 3, rk.Count())
+	assert.Equal(t, 3, dk.CountByInterval("10120", "8AM", "2PM"))
+	assert.Equal(t, 2, dk.CountByInterval("10224", "10AM", "3PM"))
+}
+
+func TestValidateWithParsePolicy(t *testing.T) {
+	filePath := "./testdata/test_parse_policy_fixtures.json"
+
+	adptr := adapters.NewGeneralRecipeAdapter()
+	invalid := 0
+	total, err := adptr.Validate(filePath, func(recordError *adapters.RecordError) { invalid++ })
+	assert.NoError(t, err)
+	assert.Equal(t, 5, total)
+	assert.Equal(t, 4, invalid)
+
+	adptr.SetParsePolicy(adapters.LenientParsePolicy())
+	invalid = 0
+	_, err = adptr.Validate(filePath, func(recordError *adapters.RecordError) { invalid++ })
+	assert.NoError(t, err)
+	assert.Equal(t, 0, invalid)
+}
@@ -0,0 +1,66 @@
+package tests
+
+import (
+	"context"
+	"recipe-stats/adapters"
+	"recipe-stats/interactive"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProgress(t *testing.T) {
+	progress := adapters.Progress{Read: 250, Total: 1000, Records: 50, Elapsed: 2 * time.Second}
+
+	assert.Equal(t, 25.0, progress.Percent())
+	assert.Equal(t, 25.0, progress.RecordsPerSecond())
+	assert.Equal(t, 6*time.Second, progress.ETA())
+
+	assert.Zero(t, adapters.Progress{}.Percent())
+	assert.Zero(t, adapters.Progress{}.RecordsPerSecond())
+	assert.Zero(t, adapters.Progress{Read: 1000, Total: 1000}.ETA())
+}
+
+func TestUnmarshalRecordsContextProgress(t *testing.T) {
+	for _, policy := range []adapters.ParsePolicy{adapters.StrictParsePolicy(), adapters.LenientParsePolicy()} {
+		reports := []adapters.Progress{}
+		recipes, err := RecordsAdapterHelper(policy).UnmarshalRecordsContext(context.Background(), "./testdata/test_calculation_fixtures_full.json", func(*adapters.RecordError) {}, func(progress adapters.Progress) {
+			reports = append(reports, progress)
+		})
+
+		assert.NoError(t, err)
+		if assert.NotEmpty(t, reports) {
+			first := reports[0]
+			assert.True(t, first.Reading)
+			assert.Equal(t, first.Total, first.Read)
+			assert.Zero(t, first.Records)
+
+			last := reports[len(reports)-1]
+			assert.False(t, last.Reading)
+			assert.Equal(t, 100.0, last.Percent())
+			assert.Equal(t, len(*recipes), last.Records)
+		}
+	}
+}
+
+func TestUnmarshalRecordsContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	recipes, err := RecordsAdapterHelper(adapters.StrictParsePolicy()).UnmarshalRecordsContext(ctx, "./testdata/test_calculation_fixtures_full.json", func(*adapters.RecordError) {}, nil)
+	assert.Equal(t, context.Canceled, err)
+	assert.Nil(t, recipes)
+
+	mapped, err := adapters.NewRecordsAdapter(slotSchemaHelper(), adapters.StrictParsePolicy())
+	assert.NoError(t, err)
+	_, err = mapped.UnmarshalRecordsContext(ctx, "./testdata/test_mapped_fixtures_slot.json", func(*adapters.RecordError) {}, nil)
+	assert.Equal(t, context.Canceled, err)
+}
+
+func TestRenderProgress(t *testing.T) {
+	assert.Equal(t, "[=====>    ]  50% 10 records/s ETA 10s", interactive.RenderProgress(adapters.Progress{Read: 50, Total: 100, Records: 100, Elapsed: 10 * time.Second}, 10))
+	assert.Equal(t, "[==========] 100% 10 records/s", interactive.RenderProgress(adapters.Progress{Read: 100, Total: 100, Records: 100, Elapsed: 10 * time.Second}, 10))
+	assert.Equal(t, "[==>       ]  25% reading ETA 30s", interactive.RenderProgress(adapters.Progress{Read: 25, Total: 100, Elapsed: 10 * time.Second, Reading: true}, 10))
+	assert.Equal(t, "Loading... 0 records", interactive.RenderProgress(adapters.Progress{}, 10))
+}
@@ -0,0 +1,71 @@
+package tests
+
+import (
+	"context"
+	"net/http/httptest"
+	"recipe-stats/metrics"
+	"recipe-stats/recipestats"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrometheusEmpty(t *testing.T) {
+	builder := strings.Builder{}
+	assert.NoError(t, metrics.NewPrometheus().Write(&builder))
+
+	output := builder.String()
+	assert.Contains(t, output, "# TYPE recipe_stats_records_loaded_total counter\nrecipe_stats_records_loaded_total 0\n")
+	assert.Contains(t, output, "recipe_stats_last_reload_timestamp_seconds 0\n")
+	assert.Contains(t, output, "# TYPE recipe_stats_query_duration_seconds histogram\n")
+	assert.NotContains(t, output, "recipe_stats_query_duration_seconds_bucket")
+}
+
+func TestPrometheusDataset(t *testing.T) {
+	prometheus := metrics.NewPrometheus()
+	dataset, err := recipestats.Open(context.Background(), metricsFixture, recipestats.WithMetrics(prometheus))
+	assert.NoError(t, err)
+
+	sizes := dataset.Sizes()
+	assert.Equal(t, recipestats.Sizes{Recipes: 1, Postcodes: 1, NameSlices: len(LoadRecipeNameSliceKeeperHelper(metricsFixture).Words())}, sizes)
+	prometheus.SetKeeperSizes(sizes.Recipes, sizes.Postcodes, sizes.NameSlices)
+	prometheus.Reloaded(time.Unix(1614592800, 500000000))
+
+	prometheus.ObserveQuery("/stats", 20*time.Millisecond)
+	prometheus.ObserveQuery("/stats", 3*time.Second)
+	prometheus.ObserveQuery(`/search"`, time.Minute)
+
+	recorder := httptest.NewRecorder()
+	prometheus.ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	assert.Equal(t, metrics.PrometheusContentType, recorder.Header().Get("Content-Type"))
+
+	output := recorder.Body.String()
+	for _, line := range []string{
+		`recipe_stats_load_phase_duration_seconds_count{phase="read_recipes"} 1`,
+		`recipe_stats_load_phase_duration_seconds_count{phase="map_deliveries"} 1`,
+		"recipe_stats_records_loaded_total 5",
+		"recipe_stats_bytes_read_total 554",
+		"recipe_stats_records_rejected_total 4",
+		"recipe_stats_recipes 1",
+		"recipe_stats_postcodes 1",
+		"recipe_stats_last_reload_timestamp_seconds 1.6145928005e+09",
+		`recipe_stats_query_duration_seconds_bucket{endpoint="/stats",le="0.01"} 0`,
+		`recipe_stats_query_duration_seconds_bucket{endpoint="/stats",le="0.025"} 1`,
+		`recipe_stats_query_duration_seconds_bucket{endpoint="/stats",le="5"} 2`,
+		`recipe_stats_query_duration_seconds_bucket{endpoint="/stats",le="+Inf"} 2`,
+		`recipe_stats_query_duration_seconds_sum{endpoint="/stats"} 3.02`,
+		`recipe_stats_query_duration_seconds_count{endpoint="/stats"} 2`,
+		`recipe_stats_query_duration_seconds_bucket{endpoint="/search\"",le="10"} 0`,
+		`recipe_stats_query_duration_seconds_bucket{endpoint="/search\"",le="+Inf"} 1`,
+	} {
+		assert.Contains(t, output, line+"\n")
+	}
+
+	// every line is a comment or a sample
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		assert.Regexp(t, `^(# (HELP|TYPE) \w+ .+|\w+(\{.+\})? \S+)$`, line)
+	}
+	assert.True(t, strings.Index(output, `endpoint="/search\""`) < strings.Index(output, `endpoint="/stats"`))
+}
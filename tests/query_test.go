@@ -0,0 +1,110 @@
+package tests
+
+import (
+	"recipe-stats/adapters"
+	"recipe-stats/loaders"
+	"recipe-stats/queries"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var queryRows = []queries.Row{
+	{Recipe: "Grilled Cheese", Postcode: "10120", Weekday: "Monday", From: 9, To: 14},
+	{Recipe: "Tex-Mex Tilapia", Postcode: "10120", Weekday: "Tuesday", From: 10, To: 15},
+	{Recipe: "Grilled Cheese", Postcode: "10131", Weekday: "Monday", From: 8, To: 12},
+	{Recipe: "Grilled Cheese", Postcode: "10120", Weekday: "Sunday", From: 11, To: 13},
+	{Recipe: "Speedy Steak Fajitas", Postcode: "20120", Weekday: "Friday", From: 9, To: 17},
+}
+
+func TestQueryGroupByOrderLimit(t *testing.T) {
+	query, err := queries.Parse("SELECT recipe, count(*) FROM deliveries WHERE postcode LIKE '101%' AND from >= 9 GROUP BY recipe ORDER BY 2 DESC LIMIT 10")
+	assert.NoError(t, err)
+
+	result := query.Execute(queryRows)
+
+	assert.Equal(t, []string{"recipe", "count(*)"}, result.Columns)
+	assert.Equal(t, [][]interface{}{{"Grilled Cheese", 2}, {"Tex-Mex Tilapia", 1}}, result.Rows)
+}
+
+func TestQueryAggregatesWithoutGroupBy(t *testing.T) {
+	query, err := queries.Parse("select count(*) as total, sum(to), avg(from), min(weekday), max(to) from deliveries where weekday in ('Monday', 'Sunday')")
+	assert.NoError(t, err)
+
+	result := query.Execute(queryRows)
+
+	assert.Equal(t, []string{"total", "sum(to)", "avg(from)", "min(weekday)", "max(to)"}, result.Columns)
+	assert.Equal(t, [][]interface{}{{3, 39, float64(28) / 3, "Monday", 14}}, result.Rows)
+}
+
+func TestQueryAggregatesWithoutRows(t *testing.T) {
+	query, err := queries.Parse("SELECT count(*), avg(from) FROM deliveries WHERE postcode = 'nowhere'")
+	assert.NoError(t, err)
+
+	result := query.Execute(queryRows)
+
+	assert.Equal(t, [][]interface{}{{0, nil}}, result.Rows)
+}
+
+func TestQueryProjection(t *testing.T) {
+	query, err := queries.Parse(`SELECT postcode, "from" FROM deliveries WHERE NOT (recipe = 'Grilled Cheese' OR to > 15) ORDER BY from`)
+	assert.NoError(t, err)
+
+	result := query.Execute(queryRows)
+
+	assert.Equal(t, [][]interface{}{{"10120", 10}}, result.Rows)
+}
+
+func TestQueryLike(t *testing.T) {
+	query, err := queries.Parse("SELECT recipe FROM deliveries WHERE recipe LIKE '%s_ee%' AND recipe NOT LIKE 'tex%'")
+	assert.NoError(t, err)
+
+	result := query.Execute(queryRows)
+
+	assert.Equal(t, [][]interface{}{{"Speedy Steak Fajitas"}}, result.Rows)
+}
+
+func TestQuerySyntaxErrors(t *testing.T) {
+	invalidQueries := []string{
+		"SELECT recipe FROM recipes",
+		"SELECT recipe, count(*) FROM deliveries",
+		"SELECT unknown FROM deliveries",
+		"SELECT recipe FROM deliveries WHERE from = '9AM'",
+		"SELECT recipe FROM deliveries WHERE from LIKE '9%'",
+		"SELECT sum(recipe) FROM deliveries",
+		"SELECT recipe FROM deliveries ORDER BY 2",
+		"SELECT recipe FROM deliveries ORDER BY postcode",
+		"SELECT recipe FROM deliveries LIMIT -1",
+		"SELECT recipe FROM deliveries WHERE recipe = 'unterminated",
+		"SELECT",
+		"SELECT recipe FROM deliveries ORDER BY",
+	}
+
+	for _, invalidQuery := range invalidQueries {
+		_, err := queries.Parse(invalidQuery)
+		assert.IsType(t, &queries.SyntaxError{}, err, invalidQuery)
+	}
+}
+
+func TestQuerySyntaxErrorsAtEndOfQuery(t *testing.T) {
+	incompleteQueries := map[string]string{
+		"SELECT":                                 "syntax error at position 6: expected a field, found end of query",
+		"SELECT recipe FROM deliveries ORDER BY": "syntax error at position 38: expected a field, found end of query",
+	}
+
+	for incompleteQuery, expected := range incompleteQueries {
+		_, err := queries.Parse(incompleteQuery)
+		assert.EqualError(t, err, expected, incompleteQuery)
+	}
+}
+
+func TestQueryFromGeneralRecipe(t *testing.T) {
+	filePath := "./testdata/test_calculation_fixtures_busiest_postalcode.json"
+	query, err := queries.Parse("SELECT postcode, count(*) FROM deliveries GROUP BY postcode ORDER BY 2 DESC LIMIT 1")
+	assert.NoError(t, err)
+
+	result, err := loaders.QueryFromGeneralRecipe(filePath, query, RecordsAdapterHelper(adapters.StrictParsePolicy()), false)
+
+	assert.NoError(t, err)
+	assert.Equal(t, [][]interface{}{{"10129", 6}}, result.Rows)
+}
@@ -1,6 +1,8 @@
 package tests
 
 import (
+	"recipe-stats/keepers"
+	"recipe-stats/models"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -12,3 +14,21 @@ func TestCount(t *testing.T) {
 
 	assert.Equal(t, 26, rk.Count())
 }
+
+func TestRecipeKeeperZeroValue(t *testing.T) {
+	rk := keepers.RecipeKeeper{}
+
+	assert.Zero(t, rk.Count())
+	assert.Empty(t, rk.GetMap())
+	data, err := rk.MarshalBinary()
+	assert.NoError(t, err)
+
+	other := keepers.NewRecipeKeeper()
+	_ = other.Add(models.Recipe{Recipe: "Grilled Cheese"})
+	other.Merge(&rk)
+	assert.Equal(t, 1, other.Count())
+
+	decoded := keepers.RecipeKeeper{}
+	assert.NoError(t, decoded.UnmarshalBinary(data))
+	assert.Zero(t, decoded.Count())
+}
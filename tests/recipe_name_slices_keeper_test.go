@@ -1,6 +1,11 @@
 package tests
 
 import (
+	"context"
+	"recipe-stats/adapters"
+	"recipe-stats/keepers"
+	"recipe-stats/loaders"
+	"recipe-stats/models"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -38,3 +43,76 @@ func TestGetSomeDuplicatedQuery(t *testing.T) {
 	assert.Equal(t, filteredRecipes[1].Recipe, "Stovetop Mac 'N' Cheese")
 	assert.Equal(t, len(filteredRecipes), 2)
 }
+
+func TestAddKeepsCountsUpToDate(t *testing.T) {
+	filePath := "./testdata/test_calculation_fixtures_double.json"
+	rnsk := LoadRecipeNameSliceKeeperHelper(filePath)
+
+	err := rnsk.Add(models.Recipe{Recipe: "Cherry Balsamic Pork Chops"})
+	assert.NoError(t, err)
+	err = rnsk.Add(models.Recipe{Recipe: "Stovetop Mac 'N' Cheese"})
+	assert.NoError(t, err)
+
+	filteredRecipes := rnsk.GetSome([]string{"Pork", "Cheese"})
+
+	assert.Equal(t, []models.Recipe{
+		{Recipe: "Cherry Balsamic Pork Chops", Count: 2},
+		{Recipe: "Parmesan-Crusted Pork Tenderloin", Count: 1},
+		{Recipe: "Stovetop Mac 'N' Cheese", Count: 1},
+	}, filteredRecipes)
+}
+
+func TestRemove(t *testing.T) {
+	filePath := "./testdata/test_calculation_fixtures_double.json"
+	rnsk := LoadRecipeNameSliceKeeperHelper(filePath)
+
+	err := rnsk.Remove(models.Recipe{Recipe: "Cherry Balsamic Pork Chops"})
+	assert.NoError(t, err)
+
+	filteredRecipes, ok := rnsk.Get("Pork")
+	assert.True(t, ok)
+	assert.Equal(t, []models.Recipe{{Recipe: "Parmesan-Crusted Pork Tenderloin", Count: 1}}, filteredRecipes)
+
+	_, ok = rnsk.Get("Cherry")
+	assert.False(t, ok)
+
+	err = rnsk.Remove(models.Recipe{Recipe: "Cherry Balsamic Pork Chops"})
+	assert.Equal(t, keepers.ErrRecipeNotFound, err)
+}
+
+func TestAppendFromGeneralRecipe(t *testing.T) {
+	rk, rnsk, dk, err := loaders.LoadFromGeneralRecipe("./testdata/test_calculation_fixtures_double.json", false)
+	assert.NoError(t, err)
+
+	err = loaders.AppendFromGeneralRecipe("./testdata/test_calculation_fixtures_single.json", rk, rnsk, dk, RecordsAdapterHelper(adapters.StrictParsePolicy()), nil, false)
+	assert.NoError(t, err)
+
+	filteredRecipes, ok := rnsk.Get("Parmesan-Crusted")
+	assert.True(t, ok)
+	assert.Equal(t, rk.GetMap()["Parmesan-Crusted Pork Tenderloin"], filteredRecipes[0])
+	assert.Equal(t, 2, filteredRecipes[0].Count)
+	assert.Equal(t, keepers.BusiestPostcode{Code: "10145", Count: 2}, dk.GetBusiestPostcode())
+}
+
+func TestRecipeNameSlicesKeeperZeroValue(t *testing.T) {
+	rnsk := keepers.RecipeNameSlicesKeeper{}
+
+	recipes, found := rnsk.Get("Cheese")
+	assert.False(t, found)
+	assert.Empty(t, recipes)
+	assert.Empty(t, rnsk.Words())
+	assert.Empty(t, rnsk.GetSome([]string{"Cheese"}))
+	recipes, err := rnsk.GetSomeContext(context.Background(), []string{"Cheese"})
+	assert.NoError(t, err)
+	assert.Empty(t, recipes)
+	data, err := rnsk.MarshalBinary()
+	assert.NoError(t, err)
+
+	other := keepers.NewRecipeNameSlicesKeeper()
+	other.Merge(&rnsk)
+	assert.Empty(t, other.Words())
+
+	decoded := keepers.RecipeNameSlicesKeeper{}
+	assert.NoError(t, decoded.UnmarshalBinary(data))
+	assert.Empty(t, decoded.Words())
+}
@@ -0,0 +1,216 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"recipe-stats/adapters"
+	"recipe-stats/loaders"
+	"recipe-stats/logging"
+	"recipe-stats/recipestats"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	recipeStatsFixture       = "./testdata/test_calculation_fixtures_full.json"
+	recipeStatsPolicyFixture = "./testdata/test_parse_policy_fixtures.json"
+)
+
+var recipeStatsRequest = recipestats.Request{
+	RecipeCount: true,
+	Names:       []string{"Cheese", "Pasta"},
+	Postcode:    "10120",
+	From:        "10AM",
+	To:          "3PM",
+}
+
+func TestRecipeStatsOpen(t *testing.T) {
+	dataset, err := recipestats.Open(context.Background(), recipeStatsFixture)
+	assert.NoError(t, err)
+
+	stats, err := dataset.Stats(context.Background(), recipeStatsRequest)
+	assert.NoError(t, err)
+	assert.Equal(t, 26, stats.UniqueRecipeCount)
+	assert.Equal(t, 0, stats.RejectedRecords)
+	assert.Equal(t, []recipestats.RecipeCount{
+		{Recipe: "Grilled Cheese and Veggie Jumble", Count: 1},
+		{Recipe: "Spinach Artichoke Pasta Bake", Count: 7},
+		{Recipe: "Stovetop Mac 'N' Cheese", Count: 2},
+	}, stats.Recipes)
+	assert.Equal(t, recipestats.PostcodeCount{Postcode: "10145", Count: 4}, stats.BusiestPostcode)
+
+	count := LoadDeliveryKeeperHelper(recipeStatsFixture).CountByInterval("10120", "10AM", "3PM")
+	assert.Equal(t, &recipestats.WindowCount{Postcode: "10120", From: "10AM", To: "3PM", Count: count}, stats.Window)
+}
+
+func TestRecipeStatsStatsWithoutWindow(t *testing.T) {
+	dataset, err := recipestats.Open(context.Background(), recipeStatsFixture)
+	assert.NoError(t, err)
+
+	stats, err := dataset.Stats(context.Background(), recipestats.Request{Postcode: "10120"})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, stats.UniqueRecipeCount)
+	assert.Empty(t, stats.Recipes)
+	assert.Nil(t, stats.Window)
+}
+
+func TestRecipeStatsLoad(t *testing.T) {
+	opened, err := recipestats.Open(context.Background(), recipeStatsFixture)
+	assert.NoError(t, err)
+
+	file, err := os.Open(recipeStatsFixture)
+	assert.NoError(t, err)
+	defer file.Close()
+
+	loaded, err := recipestats.Load(context.Background(), file)
+	assert.NoError(t, err)
+
+	openedStats, err := opened.Stats(context.Background(), recipeStatsRequest)
+	assert.NoError(t, err)
+	loadedStats, err := loaded.Stats(context.Background(), recipeStatsRequest)
+	assert.NoError(t, err)
+	assert.Equal(t, openedStats, loadedStats)
+	assert.Equal(t, opened.Postcodes(), loaded.Postcodes())
+}
+
+func TestRecipeStatsSnapshot(t *testing.T) {
+	snapshotPath := filepath.Join(t.TempDir(), "full.rsidx")
+	assert.NoError(t, loaders.BuildSnapshot(recipeStatsFixture, snapshotPath, RecordsAdapterHelper(adapters.StrictParsePolicy()), false))
+
+	fromJSON, err := recipestats.Open(context.Background(), recipeStatsFixture)
+	assert.NoError(t, err)
+	fromSnapshot, err := recipestats.Open(context.Background(), snapshotPath)
+	assert.NoError(t, err)
+
+	jsonStats, err := fromJSON.Stats(context.Background(), recipeStatsRequest)
+	assert.NoError(t, err)
+	snapshotStats, err := fromSnapshot.Stats(context.Background(), recipeStatsRequest)
+	assert.NoError(t, err)
+	assert.Equal(t, jsonStats, snapshotStats)
+}
+
+func TestRecipeStatsParallelism(t *testing.T) {
+	parallel, err := recipestats.Open(context.Background(), recipeStatsFixture, recipestats.WithParallelism(3))
+	assert.NoError(t, err)
+	sequential, err := recipestats.Open(context.Background(), recipeStatsFixture, recipestats.WithParallelism(1))
+	assert.NoError(t, err)
+
+	parallelStats, err := parallel.Stats(context.Background(), recipeStatsRequest)
+	assert.NoError(t, err)
+	sequentialStats, err := sequential.Stats(context.Background(), recipeStatsRequest)
+	assert.NoError(t, err)
+	assert.Equal(t, parallelStats, sequentialStats)
+}
+
+func TestRecipeStatsParallelismOne(t *testing.T) {
+	output := &bytes.Buffer{}
+	logger := logging.New(logging.NewTextHandler(output, logging.LevelDebug))
+	_, err := recipestats.Open(context.Background(), recipeStatsFixture, recipestats.WithParallelism(1), recipestats.WithLogger(logger))
+	assert.NoError(t, err)
+
+	// the deliveries are mapped either before the recipes are loaded or after
+	// their names are built, never in the middle
+	log := output.String()
+	mappingStarted := strings.Index(log, "Mapping deliveries...")
+	mappingFinished := strings.Index(log, "Mapping deliveries finished")
+	recipesStarted := strings.Index(log, "Loading recipes...")
+	namesFinished := strings.Index(log, "Building recipes map finished")
+	for _, index := range []int{mappingStarted, mappingFinished, recipesStarted, namesFinished} {
+		assert.NotEqual(t, -1, index)
+	}
+	assert.True(t, mappingFinished < recipesStarted || namesFinished < mappingStarted, log)
+}
+
+func TestRecipeStatsParsePolicy(t *testing.T) {
+	strict, err := recipestats.Open(context.Background(), recipeStatsPolicyFixture)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, strict.RejectedRecords())
+	assert.Equal(t, 1, strict.UniqueRecipeCount())
+
+	lenient, err := recipestats.Open(context.Background(), recipeStatsPolicyFixture, recipestats.WithParsePolicy(adapters.LenientParsePolicy()))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, lenient.RejectedRecords())
+	assert.Equal(t, 3, lenient.UniqueRecipeCount())
+	assert.Equal(t, recipestats.PostcodeCount{Postcode: "10120", Count: 3}, lenient.BusiestPostcode())
+}
+
+func TestRecipeStatsRejects(t *testing.T) {
+	rejectPath := filepath.Join(t.TempDir(), "rejects.ndjson")
+	rejects, err := loaders.NewRejects(rejectPath)
+	assert.NoError(t, err)
+
+	dataset, err := recipestats.Open(context.Background(), recipeStatsPolicyFixture, recipestats.WithRejects(rejects))
+	assert.NoError(t, err)
+	assert.NoError(t, rejects.Close())
+	assert.Equal(t, 4, dataset.RejectedRecords())
+
+	content, err := ioutil.ReadFile(rejectPath)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, content)
+}
+
+func TestRecipeStatsAppend(t *testing.T) {
+	dataset, err := recipestats.Open(context.Background(), recipeStatsPolicyFixture)
+	assert.NoError(t, err)
+	postcodeCount := dataset.BusiestPostcode()
+
+	assert.NoError(t, dataset.Append(context.Background(), recipeStatsPolicyFixture))
+	assert.Equal(t, 8, dataset.RejectedRecords())
+	assert.Equal(t, 1, dataset.UniqueRecipeCount())
+	assert.Equal(t, recipestats.PostcodeCount{Postcode: postcodeCount.Postcode, Count: 2 * postcodeCount.Count}, dataset.BusiestPostcode())
+
+	assert.Error(t, dataset.Append(context.Background(), "./testdata/missing.json"))
+}
+
+func TestRecipeStatsCountDeliveries(t *testing.T) {
+	dataset, err := recipestats.Open(context.Background(), recipeStatsFixture)
+	assert.NoError(t, err)
+
+	count, err := dataset.CountDeliveries(context.Background(), "10120", "10AM", "3PM")
+	assert.NoError(t, err)
+	assert.Equal(t, LoadDeliveryKeeperHelper(recipeStatsFixture).CountByInterval("10120", "10AM", "3PM"), count)
+
+	_, err = dataset.CountDeliveries(context.Background(), "10120", "3PM", "10AM")
+	assert.EqualError(t, err, "the time window 3PM - 10AM ends before it starts")
+
+	_, err = dataset.CountDeliveries(context.Background(), "10120", "bad", "3PM")
+	assert.Error(t, err)
+
+	_, err = dataset.Stats(context.Background(), recipestats.Request{Postcode: "10120", From: "3PM", To: "10AM"})
+	assert.Error(t, err)
+
+	_, err = dataset.CountDeliveries(canceledContextHelper(), "10120", "10AM", "3PM")
+	assert.Equal(t, context.Canceled, err)
+}
+
+func TestRecipeStatsTopPostcodes(t *testing.T) {
+	dataset, err := recipestats.Open(context.Background(), recipeStatsFixture)
+	assert.NoError(t, err)
+
+	top := dataset.TopPostcodes(2)
+	assert.Len(t, top, 2)
+	assert.Equal(t, dataset.BusiestPostcode().Count, top[0].Count)
+	assert.Equal(t, LoadDeliveryKeeperHelper(recipeStatsFixture).Postcodes(), dataset.Postcodes())
+}
+
+func TestRecipeStatsCanceled(t *testing.T) {
+	dataset, err := recipestats.Open(canceledContextHelper(), recipeStatsFixture)
+	assert.Equal(t, context.Canceled, err)
+	assert.Nil(t, dataset)
+
+	file, err := os.Open(recipeStatsFixture)
+	assert.NoError(t, err)
+	defer file.Close()
+
+	dataset, err = recipestats.Load(canceledContextHelper(), file)
+	assert.Equal(t, context.Canceled, err)
+	assert.Nil(t, dataset)
+
+	_, err = recipestats.Open(context.Background(), "./testdata/missing.json")
+	assert.Error(t, err)
+}
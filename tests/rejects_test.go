@@ -0,0 +1,66 @@
+package tests
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"recipe-stats/adapters"
+	"recipe-stats/loaders"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadWithRejectFile(t *testing.T) {
+	filePath := "./testdata/test_validation_fixtures_invalid.json"
+	rejectFilePath := filepath.Join(t.TempDir(), "rejects.ndjson")
+
+	rejects, err := loaders.NewRejects(rejectFilePath)
+	assert.NoError(t, err)
+
+	rk, _, dk, err := loaders.LoadFromGeneralRecipeWithRejects(filePath, RecordsAdapterHelper(adapters.StrictParsePolicy()), rejects, false)
+	assert.NoError(t, err)
+	assert.NoError(t, rejects.Close())
+
+	assert.Equal(t, 4, rejects.Count())
+	assert.Equal(t, 3, rk.Count())
+	assert.Equal(t, 2, dk.CountByInterval("10145", "12AM", "11PM"))
+
+	file, err := os.Open(rejectFilePath)
+	assert.NoError(t, err)
+	defer file.Close()
+
+	type rejectedRecord struct {
+		File   string
+		Index  int
+		Offset int64
+		Class  string
+		Reason string
+		Record string
+	}
+	rejected := []rejectedRecord{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		record := rejectedRecord{}
+		assert.NoError(t, json.Unmarshal(scanner.Bytes(), &record))
+		rejected = append(rejected, record)
+	}
+
+	assert.Len(t, rejected, 4)
+	assert.Equal(t, filePath, rejected[0].File)
+	assert.Equal(t, 2, rejected[0].Index)
+	assert.Equal(t, int64(183), rejected[0].Offset)
+	assert.Equal(t, adapters.ErrorClassUnknownWeekday, rejected[0].Class)
+	assert.Equal(t, `{"postcode": "10145", "recipe": "X", "delivery": "Funday 9AM - 2PM"}`, rejected[0].Record)
+	assert.Equal(t, adapters.ErrorClassMalformedRecord, rejected[3].Class)
+	assert.Equal(t, `"nope"`, rejected[3].Record)
+}
+
+func TestLoadWithoutRejectFile(t *testing.T) {
+	filePath := "./testdata/test_validation_fixtures_invalid.json"
+
+	rk := LoadRecipeKeeperHelper(filePath)
+
+	assert.Equal(t, 3, rk.Count())
+}
@@ -0,0 +1,89 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"recipe-stats/metrics"
+	"recipe-stats/recipestats"
+	"recipe-stats/server"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// getHelper sends a request to the server, returning its status and body
+func getHelper(t *testing.T, handler http.Handler, method string, target string) (int, string) {
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(method, target, nil))
+
+	body, err := ioutil.ReadAll(recorder.Body)
+	assert.NoError(t, err)
+
+	return recorder.Code, string(body)
+}
+
+func TestServerStats(t *testing.T) {
+	prometheus := metrics.NewPrometheus()
+	loads := 0
+	statsServer := server.New(func(ctx context.Context) (*recipestats.Dataset, error) {
+		loads++
+		return recipestats.Open(ctx, flowFixture, recipestats.WithMetrics(prometheus))
+	}, prometheus)
+
+	status, _ := getHelper(t, statsServer, "GET", "/stats")
+	assert.Equal(t, http.StatusServiceUnavailable, status)
+
+	assert.NoError(t, statsServer.Reload(context.Background()))
+
+	status, body := getHelper(t, statsServer, "GET", "/stats?count=true&search=Cheese,%20Steak&postcode=10145&from=8AM&to=2PM")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Contains(t, body, `"unique_recipe_count": 26`)
+	assert.Contains(t, body, `"match_by_name": [`)
+	assert.Contains(t, body, `"count_per_postcode_and_time": {`)
+
+	status, body = getHelper(t, statsServer, "GET", "/stats?count=maybe")
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Contains(t, body, "invalid count")
+
+	status, _ = getHelper(t, statsServer, "GET", "/stats?postcode=10120&from=13PM&to=2PM")
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	status, _ = getHelper(t, statsServer, "GET", "/reload")
+	assert.Equal(t, http.StatusMethodNotAllowed, status)
+
+	status, _ = getHelper(t, statsServer, "POST", "/reload")
+	assert.Equal(t, http.StatusNoContent, status)
+	assert.Equal(t, 2, loads)
+
+	status, body = getHelper(t, statsServer, "GET", "/metrics")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Contains(t, body, `recipe_stats_load_phase_duration_seconds_count{phase="read_recipes"} 2`+"\n")
+	assert.Contains(t, body, "recipe_stats_recipes 26\n")
+	assert.Contains(t, body, `recipe_stats_query_duration_seconds_count{endpoint="/stats"} 4`+"\n")
+	assert.NotContains(t, body, "recipe_stats_last_reload_timestamp_seconds 0\n")
+}
+
+func TestServerKeepsDatasetWhenReloadFails(t *testing.T) {
+	prometheus := metrics.NewPrometheus()
+	broken := false
+	statsServer := server.New(func(ctx context.Context) (*recipestats.Dataset, error) {
+		if broken {
+			return nil, fmt.Errorf("broken file")
+		}
+		return recipestats.Open(ctx, flowFixture)
+	}, prometheus)
+	assert.NoError(t, statsServer.Reload(context.Background()))
+
+	broken = true
+	status, body := getHelper(t, statsServer, "POST", "/reload")
+	assert.Equal(t, http.StatusInternalServerError, status)
+	assert.True(t, strings.HasPrefix(body, "it was impossible to reload the dataset: broken file"))
+
+	status, body = getHelper(t, statsServer, "GET", "/stats?count=true")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Contains(t, body, `"unique_recipe_count": 26`)
+}
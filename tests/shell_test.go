@@ -0,0 +1,85 @@
+package tests
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"recipe-stats/shell"
+	"testing"
+
+	"github.com/AlecAivazis/survey/v2/terminal"
+	"github.com/stretchr/testify/assert"
+)
+
+func sessionHelper(out *bytes.Buffer) *shell.Session {
+	filePath := "./testdata/test_calculation_fixtures_busiest_postalcode.json"
+	return shell.NewSession(LoadRecipeKeeperHelper(filePath), LoadRecipeNameSliceKeeperHelper(filePath), LoadDeliveryKeeperHelper(filePath), out)
+}
+
+func TestSessionExecute(t *testing.T) {
+	expectations := map[string]string{
+		"count":                  "7 unique recipes\n",
+		"search Creamy Pasta":    "Creamy Shrimp Tagliatelle (6)\nSpinach Artichoke Pasta Bake (1)\n",
+		"search Nothing":         "No recipes found\n",
+		"postcode 10129 4AM 3PM": "6 deliveries to 10129 between 4AM and 3PM\n",
+		"postcode 10129 5AM 3PM": "0 deliveries to 10129 between 5AM and 3PM\n",
+		"top 2":                  "1. 10129 (6 deliveries)\n2. 10178 (3 deliveries)\n",
+		"  ":                     "",
+	}
+
+	for line, expected := range expectations {
+		out := &bytes.Buffer{}
+		assert.NoError(t, sessionHelper(out).Execute(line), line)
+		assert.Equal(t, expected, out.String(), line)
+	}
+}
+
+func TestSessionExecuteErrors(t *testing.T) {
+	session := sessionHelper(&bytes.Buffer{})
+
+	for _, line := range []string{"nope", "search", "postcode 10129", "postcode 10129 9 AM 2PM", "postcode 10129 25PM 2PM", "postcode 10129 2PM 9AM", "top zero", "top 0"} {
+		err := session.Execute(line)
+		assert.Error(t, err, line)
+		assert.NotEqual(t, shell.ErrQuit, err, line)
+	}
+
+	assert.Equal(t, shell.ErrQuit, session.Execute("exit"))
+	assert.Equal(t, shell.ErrQuit, session.Execute("quit"))
+}
+
+func TestSessionComplete(t *testing.T) {
+	session := sessionHelper(&bytes.Buffer{})
+
+	assert.Equal(t, []string{"search"}, session.Complete("se"))
+	assert.Equal(t, []string{"count", "exit", "help", "postcode", "search", "top"}, session.Complete(""))
+	assert.Equal(t, []string{"Cheese", "Cherry", "Chops"}, session.Complete("search Pasta ch"))
+	assert.Equal(t, []string{"10174", "10178"}, session.Complete("postcode 1017"))
+	assert.Equal(t, []string{"12AM", "1AM", "10AM", "11AM", "12PM", "1PM", "10PM", "11PM"}, session.Complete("postcode 10129 1"))
+	assert.Empty(t, session.Complete("top 1"))
+}
+
+func TestShellRun(t *testing.T) {
+	inputPath := filepath.Join(t.TempDir(), "commands")
+	assert.NoError(t, ioutil.WriteFile(inputPath, []byte("count\nnope\nexit\ncount\n"), 0644))
+	in, err := os.Open(inputPath)
+	assert.NoError(t, err)
+	defer in.Close()
+
+	promptOut, err := os.Create(filepath.Join(t.TempDir(), "prompts"))
+	assert.NoError(t, err)
+	defer promptOut.Close()
+	errOut, err := os.Create(filepath.Join(t.TempDir(), "errors"))
+	assert.NoError(t, err)
+	defer errOut.Close()
+
+	out := &bytes.Buffer{}
+	err = shell.Run(sessionHelper(out), terminal.Stdio{In: in, Out: promptOut, Err: errOut})
+	assert.NoError(t, err)
+
+	assert.Equal(t, "7 unique recipes\n", out.String())
+	prompts, _ := ioutil.ReadFile(promptOut.Name())
+	assert.Equal(t, shell.Prompt+shell.Prompt+shell.Prompt, string(prompts))
+	errorMessages, _ := ioutil.ReadFile(errOut.Name())
+	assert.Equal(t, "Error: unknown command \"nope\", type help to list the commands\n", string(errorMessages))
+}
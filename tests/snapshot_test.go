@@ -0,0 +1,110 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io/ioutil"
+	"path/filepath"
+	"recipe-stats/adapters"
+	"recipe-stats/loaders"
+	"recipe-stats/recipestats"
+	"recipe-stats/snapshots"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	filePath := "./testdata/test_calculation_fixtures_full.json"
+	snapshotPath := filepath.Join(t.TempDir(), "full.rsidx")
+
+	err := loaders.BuildSnapshot(filePath, snapshotPath, RecordsAdapterHelper(adapters.StrictParsePolicy()), false)
+	assert.NoError(t, err)
+	assert.True(t, snapshots.IsSnapshot(snapshotPath))
+	assert.False(t, snapshots.IsSnapshot(filePath))
+
+	rk, rnsk, dk, err := loaders.LoadFromGeneralRecipe(filePath, false)
+	assert.NoError(t, err)
+	snapshotRk, snapshotRnsk, snapshotDk, err := loaders.LoadFromSnapshot(snapshotPath, false)
+	assert.NoError(t, err)
+
+	assert.Equal(t, rk.GetMap(), snapshotRk.GetMap())
+	assert.Equal(t, rnsk.GetSome([]string{"Cheese", "Chicken"}), snapshotRnsk.GetSome([]string{"Cheese", "Chicken"}))
+	assert.Equal(t, dk.GetBusiestPostcode(), snapshotDk.GetBusiestPostcode())
+	assert.Equal(t, dk.CountByInterval("10120", "12AM", "11PM"), snapshotDk.CountByInterval("10120", "12AM", "11PM"))
+}
+
+func TestSnapshotIncompatibleVersion(t *testing.T) {
+	data := encodeSnapshotHelper(t)
+	binary.BigEndian.PutUint16(data[5:7], snapshots.Version+1)
+
+	_, _, _, _, err := snapshots.Decode(data)
+
+	assert.Equal(t, &snapshots.VersionError{Found: snapshots.Version + 1, Supported: snapshots.Version}, err)
+}
+
+func TestSnapshotChecksumMismatch(t *testing.T) {
+	data := encodeSnapshotHelper(t)
+	data[len(data)/2] ^= 0xff
+
+	_, _, _, _, err := snapshots.Decode(data)
+
+	assert.Equal(t, snapshots.ErrChecksumMismatch, err)
+}
+
+func TestSnapshotNotSnapshot(t *testing.T) {
+	_, _, _, _, err := snapshots.Decode([]byte(`[{"recipe": "Tex-Mex Tilapia"}]`))
+
+	assert.Equal(t, snapshots.ErrNotSnapshot, err)
+}
+
+func encodeSnapshotHelper(t *testing.T) []byte {
+	rk, rnsk, dk, err := loaders.LoadFromGeneralRecipe("./testdata/test_calculation_fixtures_double.json", false)
+	assert.NoError(t, err)
+
+	buf := new(bytes.Buffer)
+	assert.NoError(t, snapshots.Write(buf, rk, rnsk, dk, 0))
+
+	decodedRk, _, _, _, err := snapshots.Decode(buf.Bytes())
+	assert.NoError(t, err)
+	assert.Equal(t, 2, decodedRk.Count())
+
+	return buf.Bytes()
+}
+
+func TestSnapshotKeepsRejectedRecords(t *testing.T) {
+	filePath := "./testdata/test_parse_policy_fixtures.json"
+	snapshotPath := filepath.Join(t.TempDir(), "rejected.rsidx")
+	rejectFilePath := filepath.Join(t.TempDir(), "rejected.ndjson")
+
+	rejects, err := loaders.NewRejects(rejectFilePath)
+	assert.NoError(t, err)
+	assert.NoError(t, loaders.BuildSnapshotContext(context.Background(), filePath, snapshotPath, RecordsAdapterHelper(adapters.StrictParsePolicy()), rejects, loaders.Observer{}))
+	assert.NoError(t, rejects.Close())
+	assert.Equal(t, 4, rejects.Count())
+
+	rejected, err := ioutil.ReadFile(rejectFilePath)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, bytes.Count(rejected, []byte("\n")))
+
+	snapshotRejects, err := loaders.NewRejects("")
+	assert.NoError(t, err)
+	_, _, _, err = loaders.LoadFromSnapshotContext(context.Background(), snapshotPath, snapshotRejects, loaders.Observer{})
+	assert.NoError(t, err)
+	assert.Equal(t, 4, snapshotRejects.Count())
+
+	// the stats of the snapshot are the ones of its input
+	request := recipestats.Request{RecipeCount: true, Names: []string{"Chicken"}}
+	dataset, err := recipestats.Open(context.Background(), filePath)
+	assert.NoError(t, err)
+	expected, err := dataset.Stats(context.Background(), request)
+	assert.NoError(t, err)
+
+	snapshotDataset, err := recipestats.Open(context.Background(), snapshotPath)
+	assert.NoError(t, err)
+	stats, err := snapshotDataset.Stats(context.Background(), request)
+	assert.NoError(t, err)
+	assert.Equal(t, expected, stats)
+	assert.Equal(t, 4, stats.RejectedRecords)
+}
@@ -0,0 +1,67 @@
+package tests
+
+import (
+	"database/sql"
+	"path/filepath"
+	"recipe-stats/adapters"
+	"recipe-stats/loaders"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportToSQLite(t *testing.T) {
+	filePath := "./testdata/test_calculation_fixtures_busiest_postalcode.json"
+	outputPath := filepath.Join(t.TempDir(), "busiest.sqlite")
+
+	err := loaders.ExportToSQLite(filePath, outputPath, RecordsAdapterHelper(adapters.StrictParsePolicy()), false)
+	assert.NoError(t, err)
+
+	db, err := sql.Open("sqlite3", outputPath)
+	assert.NoError(t, err)
+	defer db.Close()
+
+	rk := LoadRecipeKeeperHelper(filePath)
+	var recipesCount int
+	assert.NoError(t, db.QueryRow("SELECT count(*) FROM recipes").Scan(&recipesCount))
+	assert.Equal(t, rk.Count(), recipesCount)
+
+	var postcode string
+	var deliveriesCount int
+	row := db.QueryRow("SELECT postcode, count(*) FROM delivery_details GROUP BY postcode ORDER BY 2 DESC LIMIT 1")
+	assert.NoError(t, row.Scan(&postcode, &deliveriesCount))
+	assert.Equal(t, "10129", postcode)
+	assert.Equal(t, 6, deliveriesCount)
+}
+
+func TestExportToSQLiteParsedDelivery(t *testing.T) {
+	filePath := "./testdata/test_calculation_fixtures_single.json"
+	outputPath := filepath.Join(t.TempDir(), "single.sqlite")
+
+	err := loaders.ExportToSQLite(filePath, outputPath, RecordsAdapterHelper(adapters.StrictParsePolicy()), false)
+	assert.NoError(t, err)
+
+	db, err := sql.Open("sqlite3", outputPath)
+	assert.NoError(t, err)
+	defer db.Close()
+
+	var recipe, postcode, weekday string
+	var from, to int
+	row := db.QueryRow("SELECT recipe, postcode, weekday, from_hour, to_hour FROM delivery_details")
+	assert.NoError(t, row.Scan(&recipe, &postcode, &weekday, &from, &to))
+	assert.Equal(t, "Parmesan-Crusted Pork Tenderloin", recipe)
+	assert.Equal(t, "10145", postcode)
+	assert.Equal(t, "Wednesday", weekday)
+	assert.Equal(t, 9, from)
+	assert.Equal(t, 14, to)
+}
+
+func TestExportToSQLiteSnapshot(t *testing.T) {
+	snapshotPath := filepath.Join(t.TempDir(), "single.rsidx")
+	outputPath := filepath.Join(t.TempDir(), "single.sqlite")
+	assert.NoError(t, loaders.BuildSnapshot("./testdata/test_calculation_fixtures_single.json", snapshotPath, RecordsAdapterHelper(adapters.StrictParsePolicy()), false))
+
+	err := loaders.ExportToSQLite(snapshotPath, outputPath, RecordsAdapterHelper(adapters.StrictParsePolicy()), false)
+	assert.Equal(t, loaders.ErrSnapshotNotExportable, err)
+	assert.NoFileExists(t, outputPath)
+}